@@ -0,0 +1,28 @@
+package entities
+
+import "time"
+
+// Tipos de alerta gerados pela análise de fraude
+const (
+	// AlertaTaxaOrigem indica uma origem votando acima da taxa permitida em um participante
+	AlertaTaxaOrigem = "taxa_origem"
+	// AlertaBaixaEntropia indica que os votos de um participante vêm de poucas origens
+	AlertaBaixaEntropia = "baixa_entropia"
+)
+
+type Alerta struct {
+	ID             int64  `json:"id"`
+	VotacaoID      int64  `json:"votacaoId"`
+	ParticipanteID int64  `json:"participanteId"`
+	Tipo           string `json:"tipo"`
+	// Origem é o hash do IP responsável ou, na baixa entropia, da origem mais frequente
+	Origem       string    `json:"origem"`
+	Valor        float64   `json:"valor"`
+	Limite       float64   `json:"limite"`
+	Votos        int       `json:"votos"`
+	JanelaInicio time.Time `json:"janelaInicio"`
+	JanelaFim    time.Time `json:"janelaFim"`
+	Ocorrencias  int       `json:"ocorrencias"`
+	CriadoEm     time.Time `json:"criadoEm"`
+	AtualizadoEm time.Time `json:"atualizadoEm"`
+}
@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// ArquivoVotos é o arquivo com os votos de uma votação encerrada, gravado pelo job de
+// retenção antes de os votos serem removidos do banco
+type ArquivoVotos struct {
+	VotacaoID   int64     `json:"votacaoId"`
+	TenantID    int64     `json:"tenantId"`
+	Arquivo     string    `json:"arquivo"`
+	Votos       int64     `json:"votos"`
+	ArquivadaEm time.Time `json:"arquivadaEm"`
+}
@@ -0,0 +1,22 @@
+package entities
+
+type CacheStatsResponse struct {
+	Hits      uint64          `json:"hits"`
+	StaleHits uint64          `json:"staleHits"`
+	Misses    uint64          `json:"misses"`
+	Loads     uint64          `json:"loads"`
+	Coalesced uint64          `json:"coalesced"`
+	LockWaits uint64          `json:"lockWaits"`
+	Local     LocalCacheStats `json:"local"`
+}
+
+type LocalCacheStats struct {
+	Size          int    `json:"size"`
+	MaxEntries    int    `json:"maxEntries"`
+	TTLMillis     int64  `json:"ttlMillis"`
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Expirations   uint64 `json:"expirations"`
+	Invalidations uint64 `json:"invalidations"`
+}
@@ -0,0 +1,23 @@
+package entities
+
+// RodadaResponse é o resultado de uma votação dentro de uma cadeia de rodadas
+type RodadaResponse struct {
+	Votacao       *Votacao                    `json:"votacao"`
+	Total         int                         `json:"total"`
+	Pontos        int                         `json:"pontos"`
+	Participantes []ParticipanteTotalResponse `json:"participantes"`
+	// Eliminado só é preenchido em rodadas encerradas sem empate
+	Eliminado *ParticipanteTotalResponse `json:"eliminado"`
+	Empate    bool                       `json:"empate"`
+}
+
+// CadeiaResponse resume uma votação e as rodadas derivadas dela (paredão falso,
+// desempates), da original à mais recente
+type CadeiaResponse struct {
+	VotacaoID int64            `json:"votacaoId"`
+	Rodadas   []RodadaResponse `json:"rodadas"`
+	// Eliminado é o eliminado da rodada mais recente que teve decisão
+	Eliminado *ParticipanteTotalResponse `json:"eliminado"`
+	// Concluida indica que todas as rodadas estão encerradas
+	Concluida bool `json:"concluida"`
+}
@@ -0,0 +1,13 @@
+package entities
+
+// ErroCampo descreve o problema de um campo enviado na requisição
+type ErroCampo struct {
+	Campo    string `json:"campo"`
+	Mensagem string `json:"mensagem"`
+}
+
+// ErroValidacaoResponse reúne todos os campos inválidos de uma requisição
+type ErroValidacaoResponse struct {
+	Mensagem string      `json:"mensagem"`
+	Erros    []ErroCampo `json:"erros"`
+}
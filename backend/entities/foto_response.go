@@ -0,0 +1,8 @@
+package entities
+
+// FotoResponse traz o endereço da foto gravada no participante e o de cada tamanho gerado
+type FotoResponse struct {
+	ParticipanteID int64             `json:"participanteId"`
+	URLFoto        string            `json:"urlFoto"`
+	Tamanhos       map[string]string `json:"tamanhos"`
+}
@@ -0,0 +1,8 @@
+package entities
+
+// HistoricoTemporadaResponse lista os paredões de uma temporada, com o resultado de cada um
+type HistoricoTemporadaResponse struct {
+	Temporada     *Temporada               `json:"temporada"`
+	Participantes []*TemporadaParticipante `json:"participantes"`
+	Paredoes      []RodadaResponse         `json:"paredoes"`
+}
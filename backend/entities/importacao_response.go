@@ -0,0 +1,52 @@
+package entities
+
+// Tipos de registro de uma importação
+const (
+	RegistroParticipante = "participante"
+	RegistroVotacao      = "votacao"
+)
+
+// Ações que a importação executa sobre cada registro
+const (
+	AcaoCriar      = "criar"
+	AcaoAtualizar  = "atualizar"
+	AcaoInalterado = "inalterado"
+)
+
+// CampoAlterado mostra o valor atual e o valor importado de um campo
+type CampoAlterado struct {
+	De   interface{} `json:"de"`
+	Para interface{} `json:"para"`
+}
+
+// AlteracaoImportacao descreve o que a importação faz com um registro do documento
+type AlteracaoImportacao struct {
+	Linha int    `json:"linha"`
+	Tipo  string `json:"tipo"`
+	Acao  string `json:"acao"`
+	// ID do registro existente ou, depois de gravado, do registro criado
+	ID int64 `json:"id,omitempty"`
+	// Chave é o nome do participante ou a descrição da votação
+	Chave  string                   `json:"chave"`
+	Campos map[string]CampoAlterado `json:"campos,omitempty"`
+	// Participantes incluídos na votação e, quando ainda não faziam parte dela, na temporada
+	ParticipantesAdicionados []string `json:"participantesAdicionados,omitempty"`
+	IncluidosNaTemporada     []string `json:"incluidosNaTemporada,omitempty"`
+}
+
+// ErroImportacao aponta o problema de um registro pela linha do documento
+type ErroImportacao struct {
+	Linha    int    `json:"linha"`
+	Campo    string `json:"campo,omitempty"`
+	Mensagem string `json:"mensagem"`
+}
+
+// ImportacaoResponse é o resultado de uma importação. Com erros nada é gravado.
+type ImportacaoResponse struct {
+	DryRun      bool                  `json:"dryRun"`
+	Criados     int                   `json:"criados"`
+	Atualizados int                   `json:"atualizados"`
+	Inalterados int                   `json:"inalterados"`
+	Alteracoes  []AlteracaoImportacao `json:"alteracoes"`
+	Erros       []ErroImportacao      `json:"erros,omitempty"`
+}
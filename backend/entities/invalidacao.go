@@ -0,0 +1,18 @@
+package entities
+
+import "time"
+
+// Invalidacao registra uma operação que invalidou votos de uma votação
+type Invalidacao struct {
+	ID        int64 `json:"id"`
+	VotacaoID int64 `json:"votacaoId"`
+	// Filtro aplicado; campos vazios não restringiram a seleção
+	ParticipanteID int64      `json:"participanteId,omitempty"`
+	IPHash         string     `json:"ipHash,omitempty"`
+	Inicio         *time.Time `json:"inicio,omitempty"`
+	Fim            *time.Time `json:"fim,omitempty"`
+	Motivo         string     `json:"motivo"`
+	Votos          int64      `json:"votos"`
+	CriadoEm       time.Time  `json:"criadoEm"`
+	RevertidaEm    *time.Time `json:"revertidaEm,omitempty"`
+}
@@ -0,0 +1,12 @@
+package entities
+
+import "time"
+
+// MinuteCount é o total de votos de um participante em um minuto (em UTC), com a soma
+// dos seus pesos
+type MinuteCount struct {
+	ParticipanteID int64     `json:"participanteId"`
+	Minuto         time.Time `json:"minuto"`
+	Total          int       `json:"total"`
+	Pontos         int       `json:"pontos"`
+}
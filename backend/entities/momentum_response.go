@@ -0,0 +1,41 @@
+package entities
+
+import "time"
+
+type ParticipanteMomentum struct {
+	ParticipanteID int64  `json:"participanteId"`
+	Nome           string `json:"nome"`
+	Total          int    `json:"total"`
+	Pontos         int    `json:"pontos"`
+	// Percentual é a fatia do participante nos pontos, como no ranking
+	Percentual   float64 `json:"percentual"`
+	TotalJanela  int     `json:"totalJanela"`
+	PontosJanela int     `json:"pontosJanela"`
+	// PercentualJanela é a fatia do participante nos pontos dos últimos minutos
+	PercentualJanela float64 `json:"percentualJanela"`
+	// Tendencia é a diferença, em pontos percentuais, entre a fatia recente e a geral
+	Tendencia float64           `json:"tendencia"`
+	Acumulado []TimeSeriesPoint `json:"acumulado"`
+}
+
+// LeadChange registra o minuto em que um participante assumiu a liderança
+type LeadChange struct {
+	Momento        time.Time `json:"momento"`
+	ParticipanteID int64     `json:"participanteId"`
+	Nome           string    `json:"nome"`
+	Total          int       `json:"total"`
+	Pontos         int       `json:"pontos"`
+}
+
+type MomentumResponse struct {
+	VotacaoID       int64                  `json:"votacaoId"`
+	JanelaMinutos   int                    `json:"janelaMinutos"`
+	JanelaInicio    time.Time              `json:"janelaInicio"`
+	JanelaFim       time.Time              `json:"janelaFim"`
+	Total           int                    `json:"total"`
+	Pontos          int                    `json:"pontos"`
+	TotalJanela     int                    `json:"totalJanela"`
+	PontosJanela    int                    `json:"pontosJanela"`
+	Participantes   []ParticipanteMomentum `json:"participantes"`
+	TrocasLideranca []LeadChange           `json:"trocasLideranca"`
+}
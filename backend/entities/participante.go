@@ -1,7 +1,20 @@
 package entities
 
+// Redes sociais aceitas no perfil do participante
+var RedesSociais = []string{"instagram", "tiktok", "x", "youtube"}
+
 type Participante struct {
 	ID      int64  `json:"id"`
 	Nome    string `json:"nome"`
 	URLFoto string `json:"urlFoto"`
+	// Campos opcionais do perfil
+	Apelido string `json:"apelido,omitempty"`
+	Bio     string `json:"bio,omitempty"`
+	Cidade  string `json:"cidade,omitempty"`
+	// DataNascimento no formato AAAA-MM-DD
+	DataNascimento string `json:"dataNascimento,omitempty"`
+	// RedesSociais associa cada rede ao nome de usuário do participante nela
+	RedesSociais map[string]string `json:"redesSociais,omitempty"`
+	// Cor usada nos gráficos, no formato #rrggbb
+	Cor string `json:"cor,omitempty"`
 }
@@ -3,5 +3,12 @@ package entities
 type ParticipanteTotalResponse struct {
 	ParticipanteID int64  `json:"participanteId"`
 	Nome           string `json:"nome"`
+	URLFoto        string `json:"urlFoto"`
 	Total          int    `json:"total"`
+	// Pontos é o total ponderado pelos pesos dos canais; igual a Total fora do modo ponderado
+	Pontos         int     `json:"pontos"`
+	Percentual     float64 `json:"percentual"`
+	Posicao        int     `json:"posicao"`
+	DiferencaLider int     `json:"diferencaLider"`
+	Empatado       bool    `json:"empatado"`
 }
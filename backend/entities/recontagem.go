@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+// Recontagem é o resultado de uma votação recalculado após uma invalidação ou
+// reversão, enviado aos clientes conectados
+type Recontagem struct {
+	VotacaoID     int64                       `json:"votacaoId"`
+	Total         int                         `json:"total"`
+	Pontos        int                         `json:"pontos"`
+	Participantes []ParticipanteTotalResponse `json:"participantes"`
+	Motivo        string                      `json:"motivo"`
+	AtualizadoEm  time.Time                   `json:"atualizadoEm"`
+}
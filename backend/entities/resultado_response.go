@@ -0,0 +1,15 @@
+package entities
+
+import "time"
+
+type ResultadoResponse struct {
+	VotacaoID   int64     `json:"votacaoId"`
+	EncerradaEm time.Time `json:"encerradaEm"`
+	Modo        string    `json:"modo"`
+	Total       int       `json:"total"`
+	Pontos      int       `json:"pontos"`
+	// Eliminado é nulo quando há empate na posição que define a eliminação
+	Eliminado     *ParticipanteTotalResponse  `json:"eliminado"`
+	Empate        bool                        `json:"empate"`
+	Participantes []ParticipanteTotalResponse `json:"participantes"`
+}
@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+// RollupDiscrepancy é uma diferença entre o total agregado e a contagem real de votos
+type RollupDiscrepancy struct {
+	VotacaoID      int64     `json:"votacaoId"`
+	ParticipanteID int64     `json:"participanteId"`
+	Minuto         time.Time `json:"minuto"`
+	Rollup         int       `json:"rollup"`
+	Votos          int       `json:"votos"`
+	RollupPontos   int       `json:"rollupPontos"`
+	Pontos         int       `json:"pontos"`
+}
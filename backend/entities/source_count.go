@@ -0,0 +1,8 @@
+package entities
+
+// SourceCount é o total de votos de uma origem em um participante
+type SourceCount struct {
+	ParticipanteID int64
+	Origem         string
+	Total          int
+}
@@ -0,0 +1,6 @@
+package entities
+
+type Temporada struct {
+	ID   int64  `json:"id"`
+	Nome string `json:"nome"`
+}
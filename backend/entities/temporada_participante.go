@@ -0,0 +1,17 @@
+package entities
+
+// Situações de um participante em uma temporada
+const (
+	StatusAtivo     = "ativo"
+	StatusEliminado = "eliminado"
+	StatusVencedor  = "vencedor"
+)
+
+type TemporadaParticipante struct {
+	ParticipanteID int64  `json:"participanteId"`
+	Nome           string `json:"nome"`
+	URLFoto        string `json:"urlFoto"`
+	Status         string `json:"status"`
+	// VotacaoEliminacaoID é a votação que eliminou o participante
+	VotacaoEliminacaoID *int64 `json:"votacaoEliminacaoId,omitempty"`
+}
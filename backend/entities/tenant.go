@@ -0,0 +1,54 @@
+package entities
+
+import "time"
+
+// DefaultTenantID é o tenant ao qual pertencem os dados anteriores ao suporte a vários programas
+const DefaultTenantID = 1
+
+// Tenant é um programa que compartilha a mesma instalação com os demais. Participantes,
+// votações e temporadas pertencem sempre a um único tenant.
+type Tenant struct {
+	ID   int64  `json:"id"`
+	Slug string `json:"slug"`
+	Nome string `json:"nome"`
+	// Host pelo qual as páginas públicas do programa são servidas
+	Host   string       `json:"host,omitempty"`
+	Config TenantConfig `json:"config"`
+}
+
+// TenantConfig sobrepõe, para um tenant, a configuração global da instalação. Campos
+// vazios mantêm o valor global.
+type TenantConfig struct {
+	// VoteCapLimit é o número de votos por cliente em cada janela; zero desativa o limite
+	VoteCapLimit *int `json:"voteCapLimit,omitempty"`
+	// VoteCapWindow é a duração da janela do limite, no formato de time.ParseDuration
+	VoteCapWindow string `json:"voteCapWindow,omitempty"`
+	// Modos restringe os modos de votação aceitos nas votações do tenant
+	Modos []string `json:"modos,omitempty"`
+}
+
+// ModoPermitido indica se o tenant aceita votações no modo informado
+func (t *Tenant) ModoPermitido(modo string) bool {
+	if len(t.Config.Modos) == 0 {
+		return true
+	}
+	for _, permitido := range t.Config.Modos {
+		if permitido == modo {
+			return true
+		}
+	}
+	return false
+}
+
+// VoteCapWindowDuration retorna a janela do limite de votos configurada, ou false se
+// o tenant usa a janela global
+func (t *Tenant) VoteCapWindowDuration() (time.Duration, bool) {
+	if t.Config.VoteCapWindow == "" {
+		return 0, false
+	}
+	window, err := time.ParseDuration(t.Config.VoteCapWindow)
+	if err != nil || window <= 0 {
+		return 0, false
+	}
+	return window, true
+}
@@ -0,0 +1,8 @@
+package entities
+
+// TenantAPIKeyResponse devolve a chave de API de um tenant. A chave só é exibida na
+// criação ou na troca, pois apenas o seu hash é gravado.
+type TenantAPIKeyResponse struct {
+	Tenant *Tenant `json:"tenant"`
+	APIKey string  `json:"apiKey"`
+}
@@ -0,0 +1,25 @@
+package entities
+
+import "time"
+
+type TimeSeriesPoint struct {
+	Inicio time.Time `json:"inicio"`
+	Total  int       `json:"total"`
+	Pontos int       `json:"pontos"`
+}
+
+type ParticipanteTimeSeries struct {
+	ParticipanteID int64             `json:"participanteId"`
+	Nome           string            `json:"nome"`
+	Serie          []TimeSeriesPoint `json:"serie"`
+}
+
+type TimeSeriesResponse struct {
+	VotacaoID     int64                    `json:"votacaoId"`
+	Granularity   string                   `json:"granularity"`
+	TZ            string                   `json:"tz"`
+	From          time.Time                `json:"from"`
+	To            time.Time                `json:"to"`
+	Serie         []TimeSeriesPoint        `json:"serie"`
+	Participantes []ParticipanteTimeSeries `json:"participantes"`
+}
@@ -1,6 +1,56 @@
 package entities
 
+import "time"
+
+// Critérios de escolha dos participantes de uma rodada derivada
+const (
+	// CriterioTop leva à nova rodada os participantes com mais pontos
+	CriterioTop = "top"
+	// CriterioEmpate leva à nova rodada os participantes empatados na posição de eliminação
+	CriterioEmpate = "empate"
+)
+
+// Modos de votação
+const (
+	// ModoUnica é a votação para eliminar: cada voto vale um ponto e o mais votado sai
+	ModoUnica = "unica"
+	// ModoSalvar é a votação para salvar: o menos votado sai
+	ModoSalvar = "salvar"
+	// ModoPonderada é a votação para eliminar em que cada canal tem um peso
+	ModoPonderada = "ponderada"
+)
+
+// CanalAnonimo é o canal dos votos enviados sem credencial de um votante autenticado
+const CanalAnonimo = "anonimo"
+
 type Votacao struct {
-	ID        int64  `json:"id"`
-	Descricao string `json:"descricao"`
+	ID          int64      `json:"id"`
+	Descricao   string     `json:"descricao"`
+	EncerradaEm *time.Time `json:"encerradaEm"`
+	Modo        string     `json:"modo"`
+	// Pesos associa cada canal aceito ao número de pontos de um voto, no modo ponderado
+	Pesos map[string]int `json:"pesos,omitempty"`
+	// VotacaoOrigemID é a votação da qual esta rodada foi derivada, com o critério usado
+	// para escolher os participantes
+	VotacaoOrigemID *int64 `json:"votacaoOrigemId,omitempty"`
+	Criterio        string `json:"criterio,omitempty"`
+	TemporadaID     *int64 `json:"temporadaId,omitempty"`
+	// ArquivadaEm é quando os votos da votação encerrada foram arquivados e removidos do
+	// banco; as estatísticas continuam disponíveis pelas agregações
+	ArquivadaEm *time.Time `json:"arquivadaEm,omitempty"`
+}
+
+// Encerrada indica se a votação não aceita mais votos
+func (v *Votacao) Encerrada() bool {
+	return v.EncerradaEm != nil
+}
+
+// Peso retorna quantos pontos vale um voto recebido pelo canal. Fora do modo ponderado
+// todo voto vale um ponto; no modo ponderado o canal precisa estar configurado.
+func (v *Votacao) Peso(canal string) (int, bool) {
+	if v.Modo != ModoPonderada {
+		return 1, true
+	}
+	peso, ok := v.Pesos[canal]
+	return peso, ok
 }
@@ -3,4 +3,5 @@ package entities
 type VotacaoTotalResponse struct {
 	VotacaoID int64 `json:"votacaoId"`
 	Total     int   `json:"total"`
+	Pontos    int   `json:"pontos"`
 }
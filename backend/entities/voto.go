@@ -6,4 +6,11 @@ type Voto struct {
 	Participante *Participante `json:"participante"`
 	Votacao      *Votacao      `json:"votacao"`
 	DataHora     time.Time     `json:"dataHora"`
+	// A origem do voto só é exposta à administração, por VotoOrigemResponse
+	IPHash        string `json:"-"`
+	UserAgentHash string `json:"-"`
+	DeviceID      string `json:"-"`
+	Invalidado    bool   `json:"invalidado"`
+	Canal         string `json:"canal,omitempty"`
+	Peso          int    `json:"peso"`
 }
@@ -0,0 +1,10 @@
+package entities
+
+// VotoOrigemResponse é o voto com a sua origem, listado apenas para a administração
+// investigar os alertas de fraude
+type VotoOrigemResponse struct {
+	*Voto
+	IPHash        string `json:"ipHash,omitempty"`
+	UserAgentHash string `json:"userAgentHash,omitempty"`
+	DeviceID      string `json:"deviceId,omitempty"`
+}
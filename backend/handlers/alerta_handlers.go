@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+func GetVotacaoAlertas(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	if _, err := getCachedVotacao(r, id); err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	alertas := repositories.GetAlertasByVotacaoID(tenantID(r), id)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(alertas); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// InvalidarAlerta invalida os votos da origem do alerta no participante sinalizado,
+// dentro da janela em que a anomalia foi observada
+func InvalidarAlerta(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	votacaoID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	alertaID, err := strconv.ParseInt(vars["alertaId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid alertaId format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	if _, err := repositories.Votacoes.GetByID(tenantID(r), votacaoID); err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	alerta, exists := repositories.GetAlertaByID(tenantID(r), alertaID)
+	if !exists || alerta.VotacaoID != votacaoID {
+		http.Error(w, "Alerta not found", http.StatusNotFound)
+		return
+	}
+
+	invalidacao, err := repositories.InvalidateVotos(tenantID(r), repositories.VotoFilter{
+		VotacaoID:      alerta.VotacaoID,
+		ParticipanteID: alerta.ParticipanteID,
+		IPHash:         alerta.Origem,
+		From:           alerta.JanelaInicio,
+		To:             alerta.JanelaFim,
+	}, fmt.Sprintf("Alerta %d (%s)", alerta.ID, alerta.Tipo))
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, "Votacao not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repositories.ErrVotacaoArquivada) {
+		http.Error(w, "Votos of an archived votacao cannot be changed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error invalidating votos for alerta %d: %v", alertaID, err)
+		http.Error(w, "Failed to invalidate votos", http.StatusInternalServerError)
+		return
+	}
+	scheduleRecount(r, votacaoID, invalidacao.Motivo)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(invalidacao); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// entityCacheOptions define o TTL longo das leituras de participantes e votações,
+// que são invalidadas explicitamente a cada alteração administrativa
+func entityCacheOptions(tags ...string) repositories.CacheOptions {
+	return repositories.CacheOptions{TTL: repositories.EntityCacheTTL, Tags: tags}
+}
+
+// getCachedParticipante retorna repositories.ErrNotFound se o participante não existir.
+// Erros não são armazenados em cache, então um participante criado depois aparece logo.
+func getCachedParticipante(r *http.Request, id int64) (*entities.Participante, error) {
+	return repositories.GetOrLoadCache(
+		r.Context(),
+		fmt.Sprintf(repositories.ParticipanteCacheKey, id),
+		entityCacheOptions(repositories.ParticipanteTag(id)),
+		func() (*entities.Participante, error) {
+			return repositories.Participantes.GetByID(tenantID(r), id)
+		},
+	)
+}
+
+// getCachedVotacao retorna repositories.ErrNotFound se a votação não existir
+func getCachedVotacao(r *http.Request, id int64) (*entities.Votacao, error) {
+	return repositories.GetOrLoadCache(
+		r.Context(),
+		fmt.Sprintf(repositories.VotacaoCacheKey, id),
+		entityCacheOptions(repositories.VotacaoTag(id)),
+		func() (*entities.Votacao, error) {
+			return repositories.Votacoes.GetByID(tenantID(r), id)
+		},
+	)
+}
+
+// writeLookupError responde 404 quando o registro buscado não existe e 500 quando a
+// leitura falhou, no banco ou no cache, para que uma falha não pareça um registro apagado
+func writeLookupError(w http.ResponseWriter, err error, entity string) {
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, entity+" not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("Error getting %s: %v", strings.ToLower(entity), err)
+	http.Error(w, "Error getting "+strings.ToLower(entity), http.StatusInternalServerError)
+}
+
+func getCachedVotacaoParticipantes(r *http.Request, votacaoID int64) ([]*entities.Participante, error) {
+	return repositories.GetOrLoadCache(
+		r.Context(),
+		fmt.Sprintf(repositories.VotacaoParticipantesCacheKey, votacaoID),
+		entityCacheOptions(repositories.VotacaoTag(votacaoID)),
+		func() ([]*entities.Participante, error) {
+			return repositories.GetParticipantesByVotacaoID(tenantID(r), votacaoID), nil
+		},
+	)
+}
+
+// getCachedTotals retorna os totais por participante de uma votação, compartilhando a
+// entrada de cache da rota de estatísticas por participante
+func getCachedTotals(r *http.Request, votacaoID int64) ([]entities.ParticipanteTotalResponse, error) {
+	return repositories.GetOrLoadCache(
+		r.Context(),
+		fmt.Sprintf(repositories.ParticipantCacheKey, votacaoID),
+		repositories.CacheOptions{Tags: []string{repositories.VotacaoTag(votacaoID)}},
+		func() ([]entities.ParticipanteTotalResponse, error) {
+			return repositories.GetTotalVotesByParticipante(tenantID(r), votacaoID)
+		},
+	)
+}
+
+// invalidateVotacaoCache descarta tudo o que foi armazenado em cache sobre uma votação alterada
+func invalidateVotacaoCache(r *http.Request, votacaoID int64) {
+	if err := repositories.InvalidateVotacaoCache(r.Context(), votacaoID); err != nil {
+		log.Printf("Cache invalidation error for votacao %d: %v", votacaoID, err)
+	}
+}
+
+// invalidateParticipanteCache descarta tudo o que foi armazenado em cache sobre um
+// participante alterado, inclusive as estatísticas das votações das quais ele participa
+func invalidateParticipanteCache(r *http.Request, participanteID int64, votacaoIDs []int64) {
+	if err := repositories.InvalidateParticipanteCache(r.Context(), participanteID, votacaoIDs); err != nil {
+		log.Printf("Cache invalidation error for participante %d: %v", participanteID, err)
+	}
+}
+
+// invalidateCacheTags descarta as listagens em cache afetadas por uma criação
+func invalidateCacheTags(r *http.Request, tags ...string) {
+	if err := repositories.InvalidateCacheTags(r.Context(), tags...); err != nil {
+		log.Printf("Cache invalidation error for tags %v: %v", tags, err)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Segredo compartilhado com o serviço que autentica os votantes, configurável por
+// CANAL_TOKEN_SECRET. Sem ele, todo voto é do canal anônimo.
+var canalTokenSecret = []byte(os.Getenv("CANAL_TOKEN_SECRET"))
+
+// errCanalToken indica uma credencial de canal malformada, com assinatura inválida ou vencida
+var errCanalToken = errors.New("invalid canal token")
+
+// canalFromRequest retorna o canal do voto, definido pelo servidor e nunca pelo corpo da
+// requisição. Um votante autenticado envia no cabeçalho X-Canal-Token a credencial emitida
+// pelo serviço de login, no formato "canal.expiraEm.assinatura": expiraEm em segundos Unix e
+// a assinatura em hexadecimal do HMAC-SHA256 de "canal.expiraEm" com CANAL_TOKEN_SECRET.
+// Sem credencial, o voto é do canal anônimo.
+func canalFromRequest(r *http.Request) (string, error) {
+	token := strings.TrimSpace(r.Header.Get("X-Canal-Token"))
+	if token == "" {
+		return entities.CanalAnonimo, nil
+	}
+	if len(canalTokenSecret) == 0 {
+		return "", errCanalToken
+	}
+
+	// O nome do canal pode ter pontos; a expiração e a assinatura são sempre as duas últimas partes
+	payload, signature, found := cutLast(token, ".")
+	if !found {
+		return "", errCanalToken
+	}
+	canal, expiresAt, found := cutLast(payload, ".")
+	if !found || canal == "" {
+		return "", errCanalToken
+	}
+
+	mac := hmac.New(sha256.New, canalTokenSecret)
+	mac.Write([]byte(payload))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", errCanalToken
+	}
+
+	expires, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return "", errCanalToken
+	}
+
+	return canal, nil
+}
+
+func cutLast(value, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(value, sep)
+	if i < 0 {
+		return value, "", false
+	}
+	return value[:i], value[i+len(sep):], true
+}
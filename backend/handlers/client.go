@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// Segredo dos hashes de identificadores, configurável por CLIENT_HASH_SALT. Sem ele,
+// um IP poderia ser descoberto testando todos os endereços possíveis.
+var clientHashSalt = []byte(os.Getenv("CLIENT_HASH_SALT"))
+
+// Redes dos proxies reversos à frente da API, configuráveis por TRUSTED_PROXIES (CIDRs ou
+// IPs separados por vírgula). Os cabeçalhos X-Forwarded-For e X-Real-IP só são considerados
+// em conexões vindas delas; sem a variável, o IP de origem é sempre o da conexão.
+var trustedProxies = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+
+func parseTrustedProxies(value string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				log.Printf("Ignoring invalid trusted proxy %q: %v", item, err)
+				continue
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			log.Printf("Ignoring invalid trusted proxy %q: %v", item, err)
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIDs identifica quem está votando para o limite de votos: sempre o IP de origem e,
+// se o aplicativo informar o cabeçalho X-Device-ID, também o dispositivo. O dispositivo não é
+// verificado, então ele nunca substitui o IP; o voto precisa estar dentro do limite dos dois.
+// Os valores são hashes, para que identificadores pessoais não sejam gravados no Redis.
+func clientIDs(r *http.Request) []string {
+	ids := []string{hashIdentifier("ip:" + clientIP(r))}
+	if device := deviceID(r); device != "" {
+		ids = append(ids, hashIdentifier("device:"+device))
+	}
+	return ids
+}
+
+// clientIP retorna o IP de origem. Só quando a conexão vem de um proxy confiável o IP é
+// lido de X-Forwarded-For, percorrido do fim para o início até o primeiro endereço que não
+// é de um proxy confiável, ou de X-Real-IP.
+func clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if _, err := netip.ParseAddr(hop); err != nil {
+				break
+			}
+			client = hop
+			if !isTrustedProxy(hop) {
+				break
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if _, err := netip.ParseAddr(realIP); err == nil {
+			return realIP
+		}
+	}
+	return remote
+}
+
+// hashIdentifier retorna um HMAC-SHA256 truncado em 32 caracteres hexadecimais
+func hashIdentifier(value string) string {
+	mac := hmac.New(sha256.New, clientHashSalt)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// deviceID retorna o identificador de dispositivo informado pelo aplicativo, se houver
+func deviceID(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get("X-Device-ID"))
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
+}
+
+// tenantID retorna o tenant da requisição, resolvido pelo middleware de tenants
+func tenantID(r *http.Request) int64 {
+	return repositories.TenantID(r.Context())
+}
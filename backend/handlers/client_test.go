@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	previous := trustedProxies
+	trustedProxies = parseTrustedProxies("10.0.0.0/8, 2001:db8::/32, 192.0.2.1, invalido")
+	t.Cleanup(func() { trustedProxies = previous })
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"untrusted source ignores the headers", "203.0.113.7:1000", []string{"198.51.100.1"}, "198.51.100.2",
+			"203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:1000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"single trusted address", "192.0.2.1:1000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"address next to a single trusted address", "192.0.2.2:1000", []string{"198.51.100.1"}, "", "192.0.2.2"},
+		{"spoofed hop before the client", "10.0.0.1:1000", []string{"1.2.3.4, 198.51.100.1, 10.0.0.2"}, "",
+			"198.51.100.1"},
+		{"hops in several headers", "10.0.0.1:1000", []string{"1.2.3.4", "198.51.100.1", "10.0.0.2"}, "",
+			"198.51.100.1"},
+		{"only trusted hops", "10.0.0.1:1000", []string{"10.0.0.3, 10.0.0.2"}, "", "10.0.0.3"},
+		{"invalid hop stops the walk", "10.0.0.1:1000", []string{"198.51.100.1, unknown, 10.0.0.2"}, "",
+			"10.0.0.2"},
+		{"real ip without forwarded", "10.0.0.1:1000", nil, "198.51.100.3", "198.51.100.3"},
+		{"invalid real ip", "10.0.0.1:1000", nil, "unknown", "10.0.0.1"},
+		{"ipv6 client behind an ipv6 proxy", "[2001:db8::1]:443", []string{"2606:4700::1111"}, "",
+			"2606:4700::1111"},
+		{"untrusted ipv6 source", "[2606:4700::1]:443", []string{"198.51.100.1"}, "", "2606:4700::1"},
+		{"ipv4-mapped trusted proxy", "[::ffff:10.0.0.1]:80", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"remote address without a port", "203.0.113.7", nil, "", "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,60 +4,78 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/danielfs/paredao/backend/entities"
 	"github.com/danielfs/paredao/backend/repositories"
+	"github.com/danielfs/paredao/backend/statistics"
 )
 
-func getVotacaoData(
+// Casas decimais padrão dos percentuais
+const defaultPercentDecimals = 2
+
+// Janela padrão, em minutos, usada para medir quem está ganhando votos
+const defaultMomentumWindow = 15
+
+func getVotacaoData[T any](
 	w http.ResponseWriter,
 	r *http.Request,
 	cacheKeyFormat string,
-	fetchData func(int64) (interface{}, error),
+	fetchData func(int64) (T, error),
 	errorMsg string,
 ) {
+	data, _, ok := loadVotacaoData(w, r, cacheKeyFormat, fetchData, errorMsg)
+	if !ok {
+		return
+	}
+
+	writeStatsJSON(w, data)
+}
+
+// loadVotacaoData valida a votação da rota e busca os dados no cache ou no banco.
+// Em caso de falha a resposta de erro já foi escrita e ok é false.
+func loadVotacaoData[T any](
+	w http.ResponseWriter,
+	r *http.Request,
+	cacheKeyFormat string,
+	fetchData func(int64) (T, error),
+	errorMsg string,
+) (data T, votacao *entities.Votacao, ok bool) {
 	vars := mux.Vars(r)
 	ctx := r.Context()
 
 	votacaoID, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
 		http.Error(w, "Invalid votacaoID format", http.StatusBadRequest)
-		return
+		return data, nil, false
 	}
 
-	_, exists := repositories.GetVotacaoByID(votacaoID)
-	if !exists {
-		http.Error(w, "Votacao not found", http.StatusNotFound)
-		return
+	votacao, err = getCachedVotacao(r, votacaoID)
+	if err != nil {
+		writeLookupError(w, err, "Votacao")
+		return data, nil, false
 	}
 
 	cacheKey := fmt.Sprintf(cacheKeyFormat, votacaoID)
 
-	var data interface{}
-	found, err := repositories.GetFromCache(ctx, cacheKey, &data)
+	// Requisições concorrentes pela mesma chave compartilham uma única consulta ao banco
+	opts := repositories.CacheOptions{Tags: []string{repositories.VotacaoTag(votacaoID)}}
+	data, err = repositories.GetOrLoadCache(ctx, cacheKey, opts, func() (T, error) {
+		return fetchData(votacaoID)
+	})
 	if err != nil {
-		// Registra o erro mas continua com a consulta ao banco de dados
-		fmt.Printf("Cache error: %v\n", err)
+		http.Error(w, errorMsg, http.StatusInternalServerError)
+		return data, nil, false
 	}
 
-	if !found {
-		// Cache não encontrado, busca no banco de dados
-		data, err = fetchData(votacaoID)
-		if err != nil {
-			http.Error(w, errorMsg, http.StatusInternalServerError)
-			return
-		}
-
-		// Armazena no cache para requisições futuras
-		if err := repositories.SetCache(ctx, cacheKey, data); err != nil {
-			// Registra o erro mas continua mesmo se o cache falhar
-			fmt.Printf("Cache set error: %v\n", err)
-		}
-	}
+	return data, votacao, true
+}
 
+func writeStatsJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		fmt.Printf("JSON encode error: %v\n", err)
@@ -66,19 +84,56 @@ func getVotacaoData(
 	}
 }
 
+// parseRanking lê a estratégia de arredondamento (parâmetro arredondamento ou
+// variável STATS_ROUNDING) e o número de casas decimais (parâmetro casas)
+func parseRanking(w http.ResponseWriter, r *http.Request) (statistics.RoundingStrategy, int, bool) {
+	strategy := defaultRoundingStrategy()
+	if name := r.URL.Query().Get("arredondamento"); name != "" {
+		var valid bool
+		strategy, valid = statistics.ParseRoundingStrategy(name)
+		if !valid {
+			http.Error(w, "Invalid arredondamento", http.StatusBadRequest)
+			return "", 0, false
+		}
+	}
+
+	decimals := defaultPercentDecimals
+	if value := r.URL.Query().Get("casas"); value != "" {
+		var err error
+		decimals, err = strconv.Atoi(value)
+		if err != nil || decimals < 0 || decimals > statistics.MaxDecimals {
+			http.Error(w, "Invalid casas", http.StatusBadRequest)
+			return "", 0, false
+		}
+	}
+
+	return strategy, decimals, true
+}
+
+// defaultRoundingStrategy é a estratégia definida por STATS_ROUNDING ou, na falta
+// de um valor válido, a dos maiores restos
+func defaultRoundingStrategy() statistics.RoundingStrategy {
+	strategy, valid := statistics.ParseRoundingStrategy(os.Getenv("STATS_ROUNDING"))
+	if !valid {
+		return statistics.LargestRemainder
+	}
+	return strategy
+}
+
 func GetVotacaoTotal(w http.ResponseWriter, r *http.Request) {
 	getVotacaoData(
 		w,
 		r,
 		repositories.TotalCacheKey,
-		func(votacaoID int64) (interface{}, error) {
-			total, err := repositories.GetTotalVotesForVotacao(votacaoID)
+		func(votacaoID int64) (entities.VotacaoTotalResponse, error) {
+			total, pontos, err := repositories.GetTotalVotesForVotacao(votacaoID)
 			if err != nil {
-				return nil, err
+				return entities.VotacaoTotalResponse{}, err
 			}
 			return entities.VotacaoTotalResponse{
 				VotacaoID: votacaoID,
 				Total:     total,
+				Pontos:    pontos,
 			}, nil
 		},
 		"Error getting total votes",
@@ -86,15 +141,86 @@ func GetVotacaoTotal(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetVotacaoTotalByParticipante(w http.ResponseWriter, r *http.Request) {
-	getVotacaoData(
+	strategy, decimals, ok := parseRanking(w, r)
+	if !ok {
+		return
+	}
+
+	// O cache guarda apenas os totais; o ranking depende dos parâmetros da requisição
+	totals, _, ok := loadVotacaoData(
 		w,
 		r,
 		repositories.ParticipantCacheKey,
-		func(votacaoID int64) (interface{}, error) {
-			return repositories.GetTotalVotesByParticipante(votacaoID)
+		func(votacaoID int64) ([]entities.ParticipanteTotalResponse, error) {
+			return repositories.GetTotalVotesByParticipante(tenantID(r), votacaoID)
 		},
 		"Error getting total votes by participante",
 	)
+	if !ok {
+		return
+	}
+
+	writeStatsJSON(w, statistics.Rank(totals, strategy, decimals))
+}
+
+func GetVotacaoResultado(w http.ResponseWriter, r *http.Request) {
+	strategy, decimals, ok := parseRanking(w, r)
+	if !ok {
+		return
+	}
+
+	votacaoID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid votacaoID format", http.StatusBadRequest)
+		return
+	}
+
+	votacao, err := getCachedVotacao(r, votacaoID)
+	if err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	if !votacao.Encerrada() {
+		http.Error(w, "Votacao is not closed", http.StatusConflict)
+		return
+	}
+
+	// As agregações das réplicas podem estar atrás do agregador; o resultado usa os mesmos
+	// totais finais que registraram o eliminado da temporada no encerramento
+	totals, err := repositories.GetOrLoadCache(
+		r.Context(),
+		fmt.Sprintf(repositories.ResultadoCacheKey, votacaoID),
+		repositories.CacheOptions{Tags: []string{repositories.VotacaoTag(votacaoID)}},
+		func() ([]entities.ParticipanteTotalResponse, error) {
+			return repositories.GetFinalTotalsByParticipante(tenantID(r), votacaoID)
+		},
+	)
+	if err != nil {
+		http.Error(w, "Error getting total votes by participante", http.StatusInternalServerError)
+		return
+	}
+
+	ranked := statistics.Rank(totals, strategy, decimals)
+	resultado := entities.ResultadoResponse{
+		VotacaoID:     votacao.ID,
+		EncerradaEm:   *votacao.EncerradaEm,
+		Modo:          votacao.Modo,
+		Participantes: ranked,
+	}
+
+	for _, t := range ranked {
+		resultado.Total += t.Total
+		resultado.Pontos += t.Pontos
+	}
+
+	// Na votação para eliminar sai quem tem mais pontos; na votação para salvar, quem tem
+	// menos. Não há eliminado se essa posição estiver empatada.
+	eliminado, empatados := statistics.Eliminate(ranked, votacao.Modo)
+	resultado.Eliminado = eliminado
+	resultado.Empate = len(empatados) > 0
+
+	writeStatsJSON(w, resultado)
 }
 
 func GetVotacaoTotalByHour(w http.ResponseWriter, r *http.Request) {
@@ -102,9 +228,181 @@ func GetVotacaoTotalByHour(w http.ResponseWriter, r *http.Request) {
 		w,
 		r,
 		repositories.HourlyCacheKey,
-		func(votacaoID int64) (interface{}, error) {
-			return repositories.GetTotalVotesByHour(votacaoID)
-		},
+		repositories.GetTotalVotesByHour,
 		"Error getting total votes by hour",
 	)
 }
+
+// parseSeriesParams lê a granularidade (parâmetro granularity) e o fuso horário
+// (parâmetro tz) usados para montar séries temporais
+func parseSeriesParams(w http.ResponseWriter, r *http.Request) (statistics.Granularity, *time.Location, bool) {
+	query := r.URL.Query()
+
+	granularity, valid := statistics.ParseGranularity(query.Get("granularity"))
+	if query.Get("granularity") == "" {
+		granularity, valid = statistics.Hour, true
+	}
+	if !valid {
+		http.Error(w, "Invalid granularity", http.StatusBadRequest)
+		return "", nil, false
+	}
+
+	tz := query.Get("tz")
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		http.Error(w, "Invalid tz", http.StatusBadRequest)
+		return "", nil, false
+	}
+
+	return granularity, loc, true
+}
+
+func GetVotacaoTimeSeries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	granularity, loc, ok := parseSeriesParams(w, r)
+	if !ok {
+		return
+	}
+
+	from, fromErr := parseOptionalTime(query.Get("from"))
+	to, toErr := parseOptionalTime(query.Get("to"))
+	if fromErr != nil || toErr != nil {
+		http.Error(w, "Invalid from or to, expected RFC 3339", http.StatusBadRequest)
+		return
+	}
+
+	// O cache guarda os totais por minuto, que servem a qualquer granularidade e período
+	counts, votacao, ok := loadVotacaoData(
+		w,
+		r,
+		repositories.MinuteCacheKey,
+		repositories.GetVoteCountsByMinute,
+		"Error getting votes time series",
+	)
+	if !ok {
+		return
+	}
+
+	participantes, err := getCachedVotacaoParticipantes(r, votacao.ID)
+	if err != nil {
+		http.Error(w, "Error getting participantes", http.StatusInternalServerError)
+		return
+	}
+
+	// Sem limites explícitos, a série vai do primeiro ao último voto
+	if from.IsZero() && len(counts) > 0 {
+		from = counts[0].Minuto
+	}
+	if to.IsZero() && len(counts) > 0 {
+		to = counts[len(counts)-1].Minuto.Add(time.Minute)
+	}
+	if to.IsZero() {
+		to = from
+	}
+	if to.Before(from) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	serie, porParticipante, err := statistics.BuildTimeSeries(counts, participantes, granularity, loc, from, to)
+	if err != nil {
+		http.Error(w, "Too many buckets, use a coarser granularity or a shorter period", http.StatusBadRequest)
+		return
+	}
+
+	writeStatsJSON(w, entities.TimeSeriesResponse{
+		VotacaoID:     votacao.ID,
+		Granularity:   string(granularity),
+		TZ:            loc.String(),
+		From:          from.In(loc),
+		To:            to.In(loc),
+		Serie:         serie,
+		Participantes: porParticipante,
+	})
+}
+
+func GetVotacaoMomentum(w http.ResponseWriter, r *http.Request) {
+	granularity, loc, ok := parseSeriesParams(w, r)
+	if !ok {
+		return
+	}
+
+	window := defaultMomentumWindow
+	if value := r.URL.Query().Get("janela"); value != "" {
+		var err error
+		window, err = strconv.Atoi(value)
+		if err != nil || window <= 0 {
+			http.Error(w, "Invalid janela", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Usa os mesmos totais por minuto da série temporal, sem consultar a tabela de votos
+	counts, votacao, ok := loadVotacaoData(
+		w,
+		r,
+		repositories.MinuteCacheKey,
+		repositories.GetVoteCountsByMinute,
+		"Error getting votes time series",
+	)
+	if !ok {
+		return
+	}
+
+	participantes, err := getCachedVotacaoParticipantes(r, votacao.ID)
+	if err != nil {
+		http.Error(w, "Error getting participantes", http.StatusInternalServerError)
+		return
+	}
+
+	// A janela termina agora ou, se a votação estiver encerrada, no encerramento
+	windowEnd := time.Now().UTC().Truncate(time.Minute).Add(time.Minute)
+	if votacao.Encerrada() {
+		windowEnd = votacao.EncerradaEm.UTC()
+	}
+	windowStart := windowEnd.Add(-time.Duration(window) * time.Minute)
+
+	response := statistics.Momentum(counts, participantes, windowStart, windowEnd)
+	response.VotacaoID = votacao.ID
+	response.JanelaMinutos = window
+	response.JanelaInicio = windowStart.In(loc)
+	response.JanelaFim = windowEnd.In(loc)
+	for i := range response.TrocasLideranca {
+		response.TrocasLideranca[i].Momento = response.TrocasLideranca[i].Momento.In(loc)
+	}
+
+	// Curva acumulada de cada participante, do primeiro ao último voto
+	if len(counts) > 0 {
+		from := counts[0].Minuto
+		to := counts[len(counts)-1].Minuto.Add(time.Minute)
+		_, series, err := statistics.BuildTimeSeries(counts, participantes, granularity, loc, from, to)
+		if err != nil {
+			http.Error(w, "Too many buckets, use a coarser granularity", http.StatusBadRequest)
+			return
+		}
+		for i := range response.Participantes {
+			response.Participantes[i].Acumulado = statistics.Cumulative(series[i].Serie)
+		}
+	}
+
+	writeStatsJSON(w, response)
+}
+
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func GetCacheMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(repositories.GetCacheStats()); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+func TestVotacaoResultadoUsesFinalTotals(t *testing.T) {
+	participantes := newTestParticipantes(t, 2)
+	votacao := createTestVotacao(t, &entities.Votacao{Modo: entities.ModoUnica}, participantes...)
+	router := mux.NewRouter()
+	router.HandleFunc("/estatisticas/votacoes/{id}/resultado", GetVotacaoResultado).Methods("GET")
+	path := fmt.Sprintf("/estatisticas/votacoes/%d/resultado", votacao.ID)
+
+	castTestVotos(t, votacao, participantes[0], 1)
+	if rec := serveJSON(router, "GET", path, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("open votacao: status = %d, want 409", rec.Code)
+	}
+
+	// Os votos ainda não passaram pelo agregador, então as agregações só têm o primeiro
+	if _, err := repositories.AggregateNewVotos(); err != nil {
+		t.Fatal(err)
+	}
+	castTestVotos(t, votacao, participantes[1], 2)
+	encerrarTestVotacao(t, votacao)
+
+	rec := serveJSON(router, "GET", path, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resultado entities.ResultadoResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resultado); err != nil {
+		t.Fatal(err)
+	}
+	if resultado.Total != 3 {
+		t.Errorf("total = %d, want 3 counted from votos", resultado.Total)
+	}
+	if resultado.Eliminado == nil || resultado.Eliminado.ParticipanteID != participantes[1].ID {
+		t.Errorf("eliminado = %+v, want participante %d", resultado.Eliminado, participantes[1].ID)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// Intervalo entre comentários enviados para manter a conexão aberta em proxies
+const eventsKeepAlive = 25 * time.Second
+
+// GetVotacaoEventos mantém uma conexão Server-Sent Events aberta e envia um evento
+// "recontagem" com o novo resultado sempre que os votos da votação são recontados
+func GetVotacaoEventos(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	if _, err := getCachedVotacao(r, id); err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	// A conexão dura mais que o WriteTimeout do servidor
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	updates, unsubscribe := repositories.SubscribeStatsUpdates(id)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	controller.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case recontagem := <-updates:
+			data, err := json.Marshal(recontagem)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: recontagem\ndata: %s\n\n", data)
+		}
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/images"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// Tamanho máximo do arquivo de uma foto
+const maxFotoSize = 5 << 20
+
+// Folga para os cabeçalhos e delimitadores do corpo multipart
+const multipartOverhead = 64 << 10
+
+// Campo do formulário multipart com a foto
+const fotoFormField = "foto"
+
+// Tamanho gravado no participante como a sua foto
+const fotoTamanhoPadrao = "media"
+
+// Tamanhos gerados para cada foto, pelo maior lado em pixels
+var fotoTamanhos = []struct {
+	nome string
+	lado int
+}{
+	{"pequena", 160},
+	{"media", 480},
+	{"grande", 1080},
+}
+
+// Endereço público padrão pelo qual as fotos são servidas
+const defaultFotoBaseURL = "http://localhost:8080/fotos"
+
+// fotoBaseURL é o endereço definido por FOTO_BASE_URL ou, na falta dele, o padrão
+func fotoBaseURL() string {
+	if baseURL := os.Getenv("FOTO_BASE_URL"); baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	return defaultFotoBaseURL
+}
+
+// UploadParticipanteFoto recebe uma foto em multipart/form-data (campo "foto"), grava
+// os tamanhos gerados e aponta a foto do participante para o tamanho médio
+func UploadParticipanteFoto(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se o participante existe
+	participante, err := repositories.Participantes.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Participante")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFotoSize+multipartOverhead)
+	file, _, err := r.FormFile(fotoFormField)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, fmt.Sprintf("Foto must be at most %d bytes", maxFotoSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Foto is required as multipart field foto", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxFotoSize+1))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxFotoSize {
+		http.Error(w, fmt.Sprintf("Foto must be at most %d bytes", maxFotoSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	img, err := images.Decode(data)
+	switch {
+	case errors.Is(err, images.ErrTooManyPixels):
+		http.Error(w, fmt.Sprintf("Foto must have at most %d pixels", images.MaxPixels), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, "Foto must be a JPEG, PNG or GIF image", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// O diretório leva o hash do conteúdo, então cada endereço serve sempre a mesma imagem
+	sum := sha256.Sum256(data)
+	dir := fotoDir(tenantID(r), id) + "/" + hex.EncodeToString(sum[:8])
+
+	// Cada tamanho é reduzido a partir do anterior, do maior para o menor, para que a imagem
+	// enviada seja percorrida uma única vez
+	response := entities.FotoResponse{ParticipanteID: id, Tamanhos: map[string]string{}}
+	for i := len(fotoTamanhos) - 1; i >= 0; i-- {
+		tamanho := fotoTamanhos[i]
+		img = images.Fit(img, tamanho.lado)
+		encoded, err := images.EncodeJPEG(img)
+		if err != nil {
+			log.Printf("Error encoding foto of participante %d: %v", id, err)
+			http.Error(w, "Error processing foto", http.StatusInternalServerError)
+			return
+		}
+
+		key := dir + "/" + tamanho.nome + ".jpg"
+		if err := repositories.Fotos.Put(ctx, key, repositories.Blob{Data: encoded, ContentType: "image/jpeg"}); err != nil {
+			log.Printf("Error storing foto %s: %v", key, err)
+			http.Error(w, "Error storing foto", http.StatusInternalServerError)
+			return
+		}
+		response.Tamanhos[tamanho.nome] = fotoBaseURL() + "/" + key
+	}
+	response.URLFoto = response.Tamanhos[fotoTamanhoPadrao]
+
+	if !repositories.Participantes.UpdateFoto(tenantID(r), id, response.URLFoto) {
+		http.Error(w, "Error saving participante", http.StatusInternalServerError)
+		return
+	}
+	invalidateParticipanteCache(r, id, repositories.GetVotacaoIDsByParticipanteID(id))
+
+	// A foto anterior só é removida depois que o participante aponta para a nova
+	if participante.URLFoto != response.URLFoto {
+		deleteFotos(r, id, participante.URLFoto)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetFoto serve uma foto gravada. Como o endereço muda sempre que a foto muda, a
+// resposta pode ficar em cache indefinidamente.
+func GetFoto(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/fotos/")
+
+	// Cada tenant só serve as fotos dos seus participantes
+	if !strings.HasPrefix(key, fmt.Sprintf("participantes/%d/", tenantID(r))) {
+		http.Error(w, "Foto not found", http.StatusNotFound)
+		return
+	}
+
+	// O hash do conteúdo e o tamanho identificam a imagem, sem precisar lê-la
+	etag := fmt.Sprintf(`"%s-%s"`, path.Base(path.Dir(key)), strings.TrimSuffix(path.Base(key), path.Ext(key)))
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	blob, err := repositories.Fotos.Get(r.Context(), key)
+	switch {
+	case errors.Is(err, repositories.ErrBlobNotFound), errors.Is(err, repositories.ErrInvalidBlobKey):
+		http.Error(w, "Foto not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Error reading foto %s: %v", key, err)
+		http.Error(w, "Error reading foto", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", blob.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(blob.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(blob.Data)
+}
+
+// fotoDir é o diretório das fotos enviadas para o participante, dentro do qual cada foto
+// fica no subdiretório do hash do seu conteúdo
+func fotoDir(tenantID, participanteID int64) string {
+	return fmt.Sprintf("participantes/%d/%d", tenantID, participanteID)
+}
+
+// deleteFotos remove os tamanhos gravados de uma foto enviada antes para o participante.
+// Como o endereço da foto pode ser alterado livremente pela administração, só são removidos
+// arquivos do diretório do próprio participante no tenant; endereços externos, como os do
+// cadastro inicial, e de outros participantes são ignorados. Falhas apenas deixam arquivos
+// órfãos.
+func deleteFotos(r *http.Request, participanteID int64, urlFoto string) {
+	key, found := strings.CutPrefix(urlFoto, fotoBaseURL()+"/")
+	if !found || path.Clean(key) != key {
+		return
+	}
+
+	dir := path.Dir(key)
+	if path.Dir(dir) != fotoDir(tenantID(r), participanteID) {
+		return
+	}
+	for _, tamanho := range fotoTamanhos {
+		if err := repositories.Fotos.Delete(r.Context(), dir+"/"+tamanho.nome+".jpg"); err != nil {
+			log.Printf("Error deleting foto %s: %v", dir, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+func TestDeleteFotosOnlyRemovesTheParticipantesOwnFotos(t *testing.T) {
+	ctx := context.Background()
+	previous := repositories.Fotos
+	repositories.Fotos = repositories.NewLocalBlobStore(t.TempDir())
+	defer func() { repositories.Fotos = previous }()
+
+	// Fotos do participante 2 no tenant 1, do participante 3 no tenant 1 e do participante 2
+	// no tenant 9
+	dirs := []string{"participantes/1/2/aaaa", "participantes/1/3/bbbb", "participantes/9/2/cccc"}
+	for _, dir := range dirs {
+		for _, tamanho := range fotoTamanhos {
+			err := repositories.Fotos.Put(ctx, dir+"/"+tamanho.nome+".jpg", repositories.Blob{Data: []byte("x")})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	r := httptest.NewRequest("DELETE", "/participantes/2/foto", nil)
+	r = r.WithContext(repositories.WithTenant(r.Context(), &entities.Tenant{ID: 1}))
+
+	// Endereços de outro participante, de outro tenant, externos ou com componentes relativos
+	// não removem nada
+	for _, urlFoto := range []string{
+		fotoBaseURL() + "/participantes/1/3/bbbb/media.jpg",
+		fotoBaseURL() + "/participantes/9/2/cccc/media.jpg",
+		fotoBaseURL() + "/participantes/1/2/../3/bbbb/media.jpg",
+		fotoBaseURL() + "/participantes/1/2/media.jpg",
+		"https://example.com/participantes/1/2/aaaa/media.jpg",
+	} {
+		deleteFotos(r, 2, urlFoto)
+	}
+	for _, dir := range dirs {
+		if _, err := repositories.Fotos.Get(ctx, dir+"/media.jpg"); err != nil {
+			t.Errorf("%s was deleted: %v", dir, err)
+		}
+	}
+
+	deleteFotos(r, 2, fotoBaseURL()+"/participantes/1/2/aaaa/media.jpg")
+	for _, tamanho := range fotoTamanhos {
+		_, err := repositories.Fotos.Get(ctx, "participantes/1/2/aaaa/"+tamanho.nome+".jpg")
+		if !errors.Is(err, repositories.ErrBlobNotFound) {
+			t.Errorf("%s was not deleted: %v", tamanho.nome, err)
+		}
+	}
+}
@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// Tamanho máximo do documento importado
+const maxImportSize = 5 << 20
+
+// planoImportacao é um documento já validado: o que será gravado e o resumo de cada registro
+type planoImportacao struct {
+	importacao repositories.Importacao
+	alteracoes []entities.AlteracaoImportacao
+	// ids aponta, para cada alteração, o ID do registro, preenchido ao criá-lo
+	ids []*int64
+	// Participantes e votações já existentes que serão alterados, para invalidar o cache
+	participantesAlterados []int64
+	votacoesAlteradas      []int64
+}
+
+// Importar cria e atualiza participantes e votações, com suas escalações, a partir de um
+// documento CSV ou JSON. O documento é validado por inteiro antes de qualquer gravação,
+// que acontece em uma única transação. Com dryRun=true apenas retorna o que seria feito.
+func Importar(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if value := r.URL.Query().Get("dryRun"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "Invalid dryRun value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Import document is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	var doc *documentoImportado
+	var erros []entities.ErroImportacao
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		doc, erros = parseImportCSV(data)
+	case "application/json":
+		doc, erros = parseImportJSON(data)
+	default:
+		http.Error(w, "Content-Type must be text/csv or application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Só planeja um documento lido sem erros, para não apontar referências a registros descartados
+	plano := &planoImportacao{}
+	if len(erros) == 0 {
+		plano, erros, err = planImportacao(r, doc)
+		if err != nil {
+			log.Printf("Error planning import: %v", err)
+			http.Error(w, "Error validating import", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response := entities.ImportacaoResponse{DryRun: dryRun, Alteracoes: []entities.AlteracaoImportacao{}}
+	if len(erros) > 0 {
+		response.Erros = erros
+		writeImportacaoResponse(w, http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	if !dryRun {
+		err := repositories.ApplyImportacao(tenantID(r), &plano.importacao)
+		if errors.Is(err, repositories.ErrVotacaoComVotos) {
+			// Uma votação recebeu o primeiro voto depois da validação
+			http.Error(w, "Modo and pesos cannot change after the votacao has votos", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Error applying import: %v", err)
+			http.Error(w, "Error saving import", http.StatusInternalServerError)
+			return
+		}
+		invalidateImportacaoCache(r, plano)
+
+		// Os registros criados só têm ID depois de gravados
+		for i, id := range plano.ids {
+			plano.alteracoes[i].ID = *id
+		}
+	}
+
+	response.Alteracoes = plano.alteracoes
+	for _, alteracao := range plano.alteracoes {
+		switch alteracao.Acao {
+		case entities.AcaoCriar:
+			response.Criados++
+		case entities.AcaoAtualizar:
+			response.Atualizados++
+		default:
+			response.Inalterados++
+		}
+	}
+	writeImportacaoResponse(w, http.StatusOK, response)
+}
+
+func writeImportacaoResponse(w http.ResponseWriter, status int, response entities.ImportacaoResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// invalidateImportacaoCache descarta as listagens e os registros alterados pela importação
+func invalidateImportacaoCache(r *http.Request, plano *planoImportacao) {
+	invalidateCacheTags(r, repositories.ParticipantesTag, repositories.VotacoesTag)
+	for _, id := range plano.participantesAlterados {
+		invalidateParticipanteCache(r, id, repositories.GetVotacaoIDsByParticipanteID(id))
+	}
+	for _, id := range plano.votacoesAlteradas {
+		invalidateVotacaoCache(r, id)
+	}
+}
+
+// importKey identifica participantes pelo nome e votações pela descrição, ignorando
+// maiúsculas e espaços nas pontas
+func importKey(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// planImportacao confronta o documento com os participantes e votações do tenant,
+// retornando o que será gravado ou todos os problemas encontrados
+func planImportacao(r *http.Request, doc *documentoImportado) (*planoImportacao, []entities.ErroImportacao, error) {
+	plano := &planoImportacao{}
+	erros := []entities.ErroImportacao{}
+	invalid := func(linha int, campo, format string, args ...interface{}) {
+		erros = append(erros, entities.ErroImportacao{Linha: linha, Campo: campo, Mensagem: fmt.Sprintf(format, args...)})
+	}
+
+	// Participantes pelo nome, com o estado final depois da importação
+	participantes := map[string]*entities.Participante{}
+	for _, p := range repositories.Participantes.GetAll(tenantID(r)) {
+		participantes[importKey(p.Nome)] = p
+	}
+
+	declarados := map[string]int{}
+	for _, rec := range doc.Participantes {
+		key := importKey(rec.Nome)
+		if linha, repeated := declarados[key]; repeated && key != "" {
+			invalid(rec.Linha, "nome", "Participante %s is already declared at line %d", rec.Nome, linha)
+			continue
+		}
+		declarados[key] = rec.Linha
+
+		existing := participantes[key]
+		current := &entities.Participante{}
+		if existing != nil {
+			current = existing
+		}
+		participante := mergeParticipanteImportado(current, &rec.Participante)
+		camposInvalidos := validateParticipante(participante)
+
+		alteracao := entities.AlteracaoImportacao{
+			Linha:  rec.Linha,
+			Tipo:   entities.RegistroParticipante,
+			Acao:   entities.AcaoCriar,
+			Chave:  participante.Nome,
+			Campos: diffParticipante(current, participante),
+		}
+		switch {
+		case existing == nil:
+			plano.importacao.Participantes = append(plano.importacao.Participantes, participante)
+		case len(alteracao.Campos) > 0:
+			alteracao.Acao = entities.AcaoAtualizar
+			plano.importacao.Participantes = append(plano.importacao.Participantes, participante)
+			plano.participantesAlterados = append(plano.participantesAlterados, existing.ID)
+		default:
+			// Um registro que não muda nada não é validado de novo
+			alteracao.Acao = entities.AcaoInalterado
+			participante = existing
+			camposInvalidos = nil
+		}
+		for _, erro := range camposInvalidos {
+			invalid(rec.Linha, erro.Campo, "%s", erro.Mensagem)
+		}
+		participantes[key] = participante
+		plano.alteracoes = append(plano.alteracoes, alteracao)
+		plano.ids = append(plano.ids, &participante.ID)
+	}
+
+	// Votações pela descrição; descrições repetidas no tenant não podem ser importadas
+	votacoes := map[string]*entities.Votacao{}
+	ambiguas := map[string]bool{}
+	for _, v := range repositories.Votacoes.GetAll(tenantID(r)) {
+		key := importKey(v.Descricao)
+		if votacoes[key] != nil {
+			ambiguas[key] = true
+		}
+		votacoes[key] = v
+	}
+
+	// Situação dos participantes em cada temporada usada, carregada uma vez por temporada
+	temporadas := map[int64]map[int64]string{}
+	incluidos := map[int64]map[*entities.Participante]bool{}
+	membros := func(temporadaID int64) (map[int64]string, bool) {
+		if status, ok := temporadas[temporadaID]; ok {
+			return status, status != nil
+		}
+		if _, err := repositories.Temporadas.GetByID(tenantID(r), temporadaID); err != nil {
+			temporadas[temporadaID] = nil
+			return nil, false
+		}
+		status := map[int64]string{}
+		for _, tp := range repositories.GetTemporadaParticipantes(temporadaID) {
+			status[tp.ParticipanteID] = tp.Status
+		}
+		temporadas[temporadaID] = status
+		incluidos[temporadaID] = map[*entities.Participante]bool{}
+		return status, true
+	}
+
+	declarados = map[string]int{}
+	for _, rec := range doc.Votacoes {
+		key := importKey(rec.Descricao)
+		if key == "" {
+			invalid(rec.Linha, "descricao", "Descricao is required")
+			continue
+		}
+		if linha, repeated := declarados[key]; repeated {
+			invalid(rec.Linha, "descricao", "Votacao %s is already declared at line %d", rec.Descricao, linha)
+			continue
+		}
+		declarados[key] = rec.Linha
+		if ambiguas[key] {
+			invalid(rec.Linha, "descricao", "More than one votacao is described as %s", rec.Descricao)
+			continue
+		}
+
+		// Como na atualização, sem modo mantém o modo e os pesos atuais e sem temporada mantém a atual
+		existing := votacoes[key]
+		current := &entities.Votacao{}
+		if existing != nil {
+			current = existing
+		}
+		votacao := *current
+		votacao.Descricao = strings.TrimSpace(rec.Descricao)
+		if rec.Modo != "" {
+			votacao.Modo = rec.Modo
+			votacao.Pesos = rec.Pesos
+		}
+		if rec.TemporadaID != nil {
+			votacao.TemporadaID = rec.TemporadaID
+		}
+		modoInvalido := validateModo(r, &votacao)
+
+		var status map[int64]string
+		if votacao.TemporadaID != nil {
+			var exists bool
+			if status, exists = membros(*votacao.TemporadaID); !exists {
+				invalid(rec.Linha, "temporadaId", "Temporada %d not found", *votacao.TemporadaID)
+			}
+		}
+
+		// A escalação só inclui participantes; quem já está na votação continua nela
+		escalados := map[int64]bool{}
+		if existing != nil {
+			for _, p := range repositories.GetParticipantesByVotacaoID(tenantID(r), existing.ID) {
+				escalados[p.ID] = true
+			}
+		}
+		escalacao := repositories.Escalacao{Votacao: &votacao}
+		var adicionados, novosNaTemporada []string
+		vistos := map[*entities.Participante]bool{}
+		for _, nome := range rec.Participantes {
+			p := participantes[importKey(nome)]
+			switch {
+			case p == nil:
+				invalid(rec.Linha, "participantes", "Participante %s not found", nome)
+				continue
+			case vistos[p] || (p.ID != 0 && escalados[p.ID]):
+				continue
+			}
+			vistos[p] = true
+			escalacao.Participantes = append(escalacao.Participantes, p)
+			adicionados = append(adicionados, p.Nome)
+
+			if status == nil {
+				continue
+			}
+			situacao, member := status[p.ID]
+			switch {
+			case p.ID != 0 && member && situacao != entities.StatusAtivo:
+				invalid(rec.Linha, "participantes", "Participante %s is not active in the temporada", p.Nome)
+			case (p.ID == 0 || !member) && !incluidos[*votacao.TemporadaID][p]:
+				// O nome precisa ser único na temporada em que o participante vai entrar
+				taken, err := repositories.IsNomeTakenInTemporada(*votacao.TemporadaID, p.ID, p.Nome)
+				if err != nil {
+					return nil, nil, err
+				}
+				if taken {
+					invalid(rec.Linha, "participantes", "%s", nomeTakenError.Mensagem+": "+p.Nome)
+				}
+				incluidos[*votacao.TemporadaID][p] = true
+				escalacao.NovosNaTemporada = append(escalacao.NovosNaTemporada, p)
+				novosNaTemporada = append(novosNaTemporada, p.Nome)
+			}
+		}
+
+		alteracao := entities.AlteracaoImportacao{
+			Linha:                    rec.Linha,
+			Tipo:                     entities.RegistroVotacao,
+			Acao:                     entities.AcaoCriar,
+			Chave:                    votacao.Descricao,
+			Campos:                   diffVotacao(current, &votacao),
+			ParticipantesAdicionados: adicionados,
+			IncluidosNaTemporada:     novosNaTemporada,
+		}
+		switch {
+		case existing == nil:
+			plano.importacao.Votacoes = append(plano.importacao.Votacoes, &votacao)
+		case len(alteracao.Campos) > 0 || len(adicionados) > 0:
+			alteracao.Acao = entities.AcaoAtualizar
+			if existing.Encerrada() {
+				invalid(rec.Linha, "descricao", "Votacao %s is closed and cannot be changed", existing.Descricao)
+			}
+			_, modoAlterado := alteracao.Campos["modo"]
+			_, pesosAlterados := alteracao.Campos["pesos"]
+			if (modoAlterado || pesosAlterados) && repositories.VotacaoHasVotos(existing.ID) {
+				modoInvalido = "Modo and pesos cannot change after the votacao has votos"
+			}
+			if len(alteracao.Campos) > 0 {
+				plano.importacao.Votacoes = append(plano.importacao.Votacoes, &votacao)
+			}
+			plano.votacoesAlteradas = append(plano.votacoesAlteradas, existing.ID)
+		default:
+			alteracao.Acao = entities.AcaoInalterado
+			modoInvalido = ""
+		}
+		if modoInvalido != "" {
+			invalid(rec.Linha, "modo", "%s", modoInvalido)
+		}
+		if len(escalacao.Participantes) > 0 {
+			plano.importacao.Escalacoes = append(plano.importacao.Escalacoes, escalacao)
+		}
+		plano.alteracoes = append(plano.alteracoes, alteracao)
+		plano.ids = append(plano.ids, &votacao.ID)
+	}
+
+	return plano, erros, nil
+}
+
+// mergeParticipanteImportado aplica sobre o participante atual os campos preenchidos no
+// documento; campos vazios mantêm o valor atual
+func mergeParticipanteImportado(current, rec *entities.Participante) *entities.Participante {
+	merged := *current
+	merged.RedesSociais = maps.Clone(current.RedesSociais)
+
+	overwrite := func(field *string, value string) {
+		if strings.TrimSpace(value) != "" {
+			*field = value
+		}
+	}
+	overwrite(&merged.Nome, rec.Nome)
+	overwrite(&merged.URLFoto, rec.URLFoto)
+	overwrite(&merged.Apelido, rec.Apelido)
+	overwrite(&merged.Bio, rec.Bio)
+	overwrite(&merged.Cidade, rec.Cidade)
+	overwrite(&merged.DataNascimento, rec.DataNascimento)
+	overwrite(&merged.Cor, rec.Cor)
+	for rede, handle := range rec.RedesSociais {
+		if merged.RedesSociais == nil {
+			merged.RedesSociais = map[string]string{}
+		}
+		merged.RedesSociais[rede] = handle
+	}
+
+	return &merged
+}
+
+// diffParticipante lista os campos que mudam de um participante para o outro
+func diffParticipante(de, para *entities.Participante) map[string]entities.CampoAlterado {
+	campos := map[string]entities.CampoAlterado{}
+	compare := func(campo, from, to string) {
+		if from != to {
+			campos[campo] = entities.CampoAlterado{De: from, Para: to}
+		}
+	}
+
+	compare("nome", de.Nome, para.Nome)
+	compare("urlFoto", de.URLFoto, para.URLFoto)
+	compare("apelido", de.Apelido, para.Apelido)
+	compare("bio", de.Bio, para.Bio)
+	compare("cidade", de.Cidade, para.Cidade)
+	compare("dataNascimento", de.DataNascimento, para.DataNascimento)
+	compare("cor", de.Cor, para.Cor)
+	for _, rede := range entities.RedesSociais {
+		compare("redesSociais."+rede, de.RedesSociais[rede], para.RedesSociais[rede])
+	}
+
+	return campos
+}
+
+// diffVotacao lista os campos que mudam de uma votação para a outra
+func diffVotacao(de, para *entities.Votacao) map[string]entities.CampoAlterado {
+	campos := map[string]entities.CampoAlterado{}
+	if de.Descricao != para.Descricao {
+		campos["descricao"] = entities.CampoAlterado{De: de.Descricao, Para: para.Descricao}
+	}
+	if de.Modo != para.Modo {
+		campos["modo"] = entities.CampoAlterado{De: de.Modo, Para: para.Modo}
+	}
+	if !maps.Equal(de.Pesos, para.Pesos) {
+		campos["pesos"] = entities.CampoAlterado{De: de.Pesos, Para: para.Pesos}
+	}
+	if (de.TemporadaID == nil) != (para.TemporadaID == nil) ||
+		(de.TemporadaID != nil && *de.TemporadaID != *para.TemporadaID) {
+		campos["temporadaId"] = entities.CampoAlterado{De: de.TemporadaID, Para: para.TemporadaID}
+	}
+	return campos
+}
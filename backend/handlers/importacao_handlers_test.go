@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+func postImport(t *testing.T, query, contentType, body string) (int, entities.ImportacaoResponse) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/import"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	Importar(rec, req)
+
+	var response entities.ImportacaoResponse
+	if rec.Header().Get("Content-Type") == "application/json" {
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, response
+}
+
+// importedCounts conta os participantes e votações do tenant com os nomes informados
+func importedCounts(nome, descricao string) (participantes, votacoes int) {
+	for _, p := range repositories.Participantes.GetAll(entities.DefaultTenantID) {
+		if p.Nome == nome {
+			participantes++
+		}
+	}
+	for _, v := range repositories.Votacoes.GetAll(entities.DefaultTenantID) {
+		if v.Descricao == descricao {
+			votacoes++
+		}
+	}
+	return participantes, votacoes
+}
+
+func TestImportarDryRunWritesNothing(t *testing.T) {
+	nome, descricao := uniqueNome("Importada"), uniqueNome("Paredão importado")
+	csv := fmt.Sprintf("tipo,nome,descricao,participantes\nparticipante,%s,,\nvotacao,,%s,%s\n", nome, descricao, nome)
+
+	status, response := postImport(t, "?dryRun=true", "text/csv", csv)
+	if status != http.StatusOK || !response.DryRun || response.Criados != 2 {
+		t.Fatalf("dry run: status = %d and response = %+v, want 200 with 2 criados", status, response)
+	}
+	for _, alteracao := range response.Alteracoes {
+		if alteracao.ID != 0 {
+			t.Errorf("dry run returned the id %d for %s", alteracao.ID, alteracao.Chave)
+		}
+	}
+	if participantes, votacoes := importedCounts(nome, descricao); participantes != 0 || votacoes != 0 {
+		t.Fatalf("dry run saved %d participantes and %d votacoes", participantes, votacoes)
+	}
+
+	// A mesma importação sem dryRun grava o que a simulação apontou
+	status, response = postImport(t, "", "text/csv; charset=utf-8", csv)
+	if status != http.StatusOK || response.DryRun || response.Criados != 2 {
+		t.Fatalf("import: status = %d and response = %+v, want 200 with 2 criados", status, response)
+	}
+	if participantes, votacoes := importedCounts(nome, descricao); participantes != 1 || votacoes != 1 {
+		t.Errorf("import saved %d participantes and %d votacoes, want 1 and 1", participantes, votacoes)
+	}
+
+	// Repetida, a importação não altera nada
+	status, response = postImport(t, "?dryRun=false", "text/csv", csv)
+	if status != http.StatusOK || response.Criados != 0 || response.Inalterados != 2 {
+		t.Errorf("repeated import: status = %d and response = %+v, want 2 inalterados", status, response)
+	}
+}
+
+func TestImportarRejectsTheWholeDocument(t *testing.T) {
+	nome, repetido := uniqueNome("Válida"), uniqueNome("Repetida")
+	body := fmt.Sprintf(`{"participantes": [
+  {"nome": %q},
+  {"nome": %q},
+  {"nome": %q}
+]}`, nome, repetido, strings.ToUpper(repetido))
+
+	// O nome repetido, mesmo com outra caixa, descarta também os registros válidos
+	status, response := postImport(t, "", "application/json", body)
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", status)
+	}
+	if len(response.Erros) != 1 || response.Erros[0].Linha != 4 || response.Erros[0].Campo != "nome" {
+		t.Errorf("erros = %+v, want the repeated nome at line 4", response.Erros)
+	}
+	if participantes, _ := importedCounts(nome, ""); participantes != 0 {
+		t.Errorf("a rejected import saved %d participantes", participantes)
+	}
+}
+
+func TestImportarValidatesTheRequest(t *testing.T) {
+	if status, _ := postImport(t, "?dryRun=talvez", "text/csv", "tipo\n"); status != http.StatusBadRequest {
+		t.Errorf("invalid dryRun: status = %d, want 400", status)
+	}
+	if status, _ := postImport(t, "", "text/plain", "tipo\n"); status != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain: status = %d, want 415", status)
+	}
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// participanteImportado é um participante descrito no documento, identificado pelo nome
+type participanteImportado struct {
+	Linha int `json:"-"`
+	entities.Participante
+}
+
+// votacaoImportada é uma votação descrita no documento, identificada pela descrição, com
+// os nomes dos participantes da sua escalação
+type votacaoImportada struct {
+	Linha         int            `json:"-"`
+	Descricao     string         `json:"descricao"`
+	Modo          string         `json:"modo"`
+	Pesos         map[string]int `json:"pesos"`
+	TemporadaID   *int64         `json:"temporadaId"`
+	Participantes []string       `json:"participantes"`
+}
+
+// documentoImportado reúne os registros lidos de um documento CSV ou JSON
+type documentoImportado struct {
+	Participantes []participanteImportado
+	Votacoes      []votacaoImportada
+}
+
+// Colunas do CSV de importação. Cada linha descreve um participante ou uma votação,
+// conforme a coluna tipo, e só pode preencher as colunas do seu tipo.
+var (
+	csvParticipanteColumns = []string{"nome", "urlFoto", "apelido", "bio", "cidade", "dataNascimento", "cor"}
+	csvVotacaoColumns      = []string{"descricao", "modo", "pesos", "temporadaId", "participantes"}
+)
+
+// Separadores das listas dentro de uma célula do CSV: "Ana;Bruno" e "app=1;web=2"
+const (
+	csvListSeparator = ";"
+	csvPesoSeparator = "="
+)
+
+// utf8BOM é a marca que alguns editores, como o Excel, gravam no início do arquivo
+var utf8BOM = []byte("\ufeff")
+
+// parseImportCSV lê um CSV com cabeçalho, apontando os erros pela linha do arquivo
+func parseImportCSV(data []byte) (*documentoImportado, []entities.ErroImportacao) {
+	erros := []entities.ErroImportacao{}
+	invalid := func(linha int, campo, format string, args ...interface{}) {
+		erros = append(erros, entities.ErroImportacao{Linha: linha, Campo: campo, Mensagem: fmt.Sprintf(format, args...)})
+	}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	header, err := reader.Read()
+	if err != nil {
+		invalid(1, "", "Could not read the CSV header: %v", err)
+		return nil, erros
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		switch {
+		case name != "tipo" && !slices.Contains(csvParticipanteColumns, name) &&
+			!slices.Contains(csvVotacaoColumns, name) && !slices.Contains(entities.RedesSociais, name):
+			invalid(1, name, "Unknown column %q", name)
+		default:
+			if _, repeated := columns[name]; repeated {
+				invalid(1, name, "Column %q is repeated", name)
+			}
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["tipo"]; !ok {
+		invalid(1, "tipo", "Column tipo is required")
+	}
+	if len(erros) > 0 {
+		return nil, erros
+	}
+
+	doc := &documentoImportado{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				invalid(parseErr.Line, "", "%v", parseErr.Err)
+				if errors.Is(err, csv.ErrFieldCount) {
+					continue
+				}
+			} else {
+				invalid(0, "", "Could not read the CSV: %v", err)
+			}
+			return nil, erros
+		}
+
+		linha, _ := reader.FieldPos(0)
+		get := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		// As colunas de outro tipo precisam ficar vazias
+		rejectFilled := func(tipo string, names []string) bool {
+			ok := true
+			for _, name := range names {
+				if get(name) != "" {
+					invalid(linha, name, "Column %s does not apply to tipo %s", name, tipo)
+					ok = false
+				}
+			}
+			return ok
+		}
+
+		switch tipo := get("tipo"); tipo {
+		case entities.RegistroParticipante:
+			if !rejectFilled(tipo, csvVotacaoColumns) {
+				continue
+			}
+			p := participanteImportado{Linha: linha}
+			p.Nome = get("nome")
+			p.URLFoto = get("urlFoto")
+			p.Apelido = get("apelido")
+			p.Bio = get("bio")
+			p.Cidade = get("cidade")
+			p.DataNascimento = get("dataNascimento")
+			p.Cor = get("cor")
+			for _, rede := range entities.RedesSociais {
+				if handle := get(rede); handle != "" {
+					if p.RedesSociais == nil {
+						p.RedesSociais = map[string]string{}
+					}
+					p.RedesSociais[rede] = handle
+				}
+			}
+			doc.Participantes = append(doc.Participantes, p)
+		case entities.RegistroVotacao:
+			if !rejectFilled(tipo, append(slices.Clone(csvParticipanteColumns), entities.RedesSociais...)) {
+				continue
+			}
+			v := votacaoImportada{Linha: linha, Descricao: get("descricao"), Modo: get("modo")}
+			if value := get("temporadaId"); value != "" {
+				id, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || id <= 0 {
+					invalid(linha, "temporadaId", "TemporadaId must be a positive integer")
+					continue
+				}
+				v.TemporadaID = &id
+			}
+			if value := get("pesos"); value != "" {
+				pesos, ok := parseCSVPesos(value)
+				if !ok {
+					invalid(linha, "pesos", "Pesos must be in the format canal=peso;canal=peso")
+					continue
+				}
+				v.Pesos = pesos
+			}
+			for _, nome := range strings.Split(get("participantes"), csvListSeparator) {
+				if nome = strings.TrimSpace(nome); nome != "" {
+					v.Participantes = append(v.Participantes, nome)
+				}
+			}
+			doc.Votacoes = append(doc.Votacoes, v)
+		case "":
+			invalid(linha, "tipo", "Tipo is required")
+		default:
+			invalid(linha, "tipo", "Invalid tipo, expected participante or votacao")
+		}
+	}
+
+	return doc, erros
+}
+
+// parseCSVPesos lê os pesos dos canais no formato "app=1;web=2"
+func parseCSVPesos(value string) (map[string]int, bool) {
+	pesos := map[string]int{}
+	for _, item := range strings.Split(value, csvListSeparator) {
+		canal, peso, found := strings.Cut(item, csvPesoSeparator)
+		if !found {
+			return nil, false
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(peso))
+		if err != nil {
+			return nil, false
+		}
+		pesos[strings.TrimSpace(canal)] = n
+	}
+	return pesos, true
+}
+
+// parseImportJSON lê um objeto com as listas "participantes" e "votacoes", apontando os
+// erros pela linha em que cada registro começa
+func parseImportJSON(data []byte) (*documentoImportado, []entities.ErroImportacao) {
+	erros := []entities.ErroImportacao{}
+	invalid := func(linha int, campo, format string, args ...interface{}) {
+		erros = append(erros, entities.ErroImportacao{Linha: linha, Campo: campo, Mensagem: fmt.Sprintf(format, args...)})
+	}
+
+	// Linha do próximo valor a partir da posição do decoder, que fica antes de espaços e vírgulas
+	lineAt := func(offset int64) int {
+		offset = min(max(offset, 0), int64(len(data)))
+		for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+			offset++
+		}
+		return 1 + bytes.Count(data[:offset], []byte("\n"))
+	}
+	syntaxError := func(err error) {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			invalid(lineAt(syntaxErr.Offset-1), "", "Invalid JSON: %v", syntaxErr)
+			return
+		}
+		invalid(lineAt(int64(len(data))), "", "Invalid JSON: %v", err)
+	}
+
+	data = bytes.TrimPrefix(data, utf8BOM)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		invalid(1, "", "The document must be a JSON object with participantes and votacoes")
+		return nil, erros
+	}
+
+	doc := &documentoImportado{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			syntaxError(err)
+			return nil, erros
+		}
+		key, _ := token.(string)
+		linha := lineAt(decoder.InputOffset())
+		if key != "participantes" && key != "votacoes" {
+			invalid(linha, key, "Unknown key %q, expected participantes or votacoes", key)
+			return nil, erros
+		}
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			invalid(linha, key, "%s must be an array", key)
+			return nil, erros
+		}
+
+		for decoder.More() {
+			linha := lineAt(decoder.InputOffset())
+			if key == "participantes" {
+				p := participanteImportado{Linha: linha}
+				err = decoder.Decode(&p)
+				if err == nil && p.ID != 0 {
+					invalid(linha, "id", "Id is not accepted, participantes are matched by nome")
+					continue
+				}
+				if err == nil {
+					doc.Participantes = append(doc.Participantes, p)
+				}
+			} else {
+				v := votacaoImportada{Linha: linha}
+				if err = decoder.Decode(&v); err == nil {
+					doc.Votacoes = append(doc.Votacoes, v)
+				}
+			}
+
+			// Erros de sintaxe interrompem a leitura; os demais descartam só o registro
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			switch {
+			case errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF):
+				syntaxError(err)
+				return nil, erros
+			case errors.As(err, &typeErr):
+				invalid(linha, typeErr.Field, "%s must be of type %s", typeErr.Field, typeErr.Type)
+			case err != nil:
+				invalid(linha, "", "%s", strings.TrimPrefix(err.Error(), "json: "))
+			}
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			syntaxError(err)
+			return nil, erros
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		syntaxError(err)
+		return nil, erros
+	}
+
+	return doc, erros
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// erroPositions resume os erros como "linha:campo" para comparar nos testes
+func erroPositions(erros []entities.ErroImportacao) []string {
+	positions := make([]string, len(erros))
+	for i, erro := range erros {
+		positions[i] = fmt.Sprintf("%d:%s", erro.Linha, erro.Campo)
+	}
+	return positions
+}
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// Nomes dos participantes e, por votação, a descrição seguida da escalação
+		participantes []string
+		votacoes      [][]string
+		erros         []string
+	}{
+		{
+			name: "participantes and votacoes",
+			data: "tipo,nome,descricao,participantes\n" +
+				"participante,Ana,,\n" +
+				"participante,Bruno,,\n" +
+				"votacao,,Paredão 1,Ana; Bruno ;\n",
+			participantes: []string{"Ana", "Bruno"},
+			votacoes:      [][]string{{"Paredão 1", "Ana", "Bruno"}},
+		},
+		{
+			name: "quoted fields",
+			data: "tipo,nome,bio,descricao,participantes\n" +
+				"participante,\"Silva, Ana\",\"Diz \"\"oi\"\"\nem duas linhas\",,\n" +
+				"votacao,,,\"Paredão, o primeiro\",\"Silva, Ana\"\n",
+			participantes: []string{"Silva, Ana"},
+			votacoes:      [][]string{{"Paredão, o primeiro", "Silva, Ana"}},
+		},
+		{
+			name:          "byte order mark",
+			data:          "\ufefftipo,nome\nparticipante,Ana\n",
+			participantes: []string{"Ana"},
+		},
+		{
+			name:          "byte order mark before a quoted header",
+			data:          "\ufeff\"tipo\",\"nome\"\nparticipante,Ana\n",
+			participantes: []string{"Ana"},
+		},
+		{
+			name:  "repeated column",
+			data:  "tipo,nome,nome\nparticipante,Ana,Bruno\n",
+			erros: []string{"1:nome"},
+		},
+		{
+			name:  "unknown column and missing tipo",
+			data:  "nome,idade\nAna,30\n",
+			erros: []string{"1:idade", "1:tipo"},
+		},
+		{
+			// A linha de um erro é a do arquivo, contando as quebras dentro das aspas
+			name: "errors point to the file line",
+			data: "tipo,nome,bio,descricao\n" +
+				"participante,Ana,\"uma\nduas\",\n" +
+				"participante,Bruno,,Paredão\n" +
+				"outro,,,\n",
+			participantes: []string{"Ana"},
+			erros:         []string{"4:descricao", "5:tipo"},
+		},
+		{
+			name:  "unterminated quote",
+			data:  "tipo,nome\nparticipante,\"Ana\n",
+			erros: []string{"2:"},
+		},
+		{
+			name:  "invalid pesos",
+			data:  "tipo,descricao,modo,pesos\nvotacao,Paredão,ponderada,app\n",
+			erros: []string{"2:pesos"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, erros := parseImportCSV([]byte(tt.data))
+			if got := erroPositions(erros); !slices.Equal(got, append([]string{}, tt.erros...)) {
+				t.Fatalf("erros = %+v, want %v", erros, tt.erros)
+			}
+			if doc == nil {
+				if tt.participantes != nil || tt.votacoes != nil {
+					t.Fatal("document was discarded")
+				}
+				return
+			}
+
+			nomes := []string{}
+			for _, p := range doc.Participantes {
+				nomes = append(nomes, p.Nome)
+			}
+			if !slices.Equal(nomes, append([]string{}, tt.participantes...)) {
+				t.Errorf("participantes = %q, want %q", nomes, tt.participantes)
+			}
+			votacoes := [][]string{}
+			for _, v := range doc.Votacoes {
+				votacoes = append(votacoes, append([]string{v.Descricao}, v.Participantes...))
+			}
+			if !slices.EqualFunc(votacoes, append([][]string{}, tt.votacoes...), slices.Equal) {
+				t.Errorf("votacoes = %q, want %q", votacoes, tt.votacoes)
+			}
+		})
+	}
+}
+
+func TestParseImportCSVFields(t *testing.T) {
+	data := "tipo,nome,instagram,descricao,modo,pesos,temporadaId\n" +
+		"participante,Ana,@ana,,,,\n" +
+		"votacao,,,Paredão,ponderada,app=3; web = 1,7\n"
+
+	doc, erros := parseImportCSV([]byte(data))
+	if len(erros) > 0 {
+		t.Fatalf("erros = %+v", erros)
+	}
+	if got := doc.Participantes[0].RedesSociais["instagram"]; got != "@ana" {
+		t.Errorf("instagram = %q, want @ana", got)
+	}
+	v := doc.Votacoes[0]
+	if v.Modo != entities.ModoPonderada || v.Pesos["app"] != 3 || v.Pesos["web"] != 1 || len(v.Pesos) != 2 {
+		t.Errorf("modo = %q and pesos = %v, want ponderada with app=3 and web=1", v.Modo, v.Pesos)
+	}
+	if v.TemporadaID == nil || *v.TemporadaID != 7 {
+		t.Errorf("temporadaId = %v, want 7", v.TemporadaID)
+	}
+	if v.Linha != 3 {
+		t.Errorf("votacao linha = %d, want 3", v.Linha)
+	}
+}
+
+func TestParseImportJSON(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		participantes []string
+		votacoes      []string
+		erros         []string
+	}{
+		{
+			name: "participantes and votacoes",
+			data: `{
+  "participantes": [{"nome": "Ana"}, {"nome": "Bruno"}],
+  "votacoes": [{"descricao": "Paredão", "participantes": ["Ana", "Bruno"]}]
+}`,
+			participantes: []string{"Ana", "Bruno"},
+			votacoes:      []string{"Paredão"},
+		},
+		{
+			name:          "byte order mark",
+			data:          "\ufeff{\"participantes\": [{\"nome\": \"Ana\"}]}",
+			participantes: []string{"Ana"},
+		},
+		{
+			name:          "escaped strings",
+			data:          `{"participantes": [{"nome": "Ana \"A\" Ávila"}]}`,
+			participantes: []string{`Ana "A" Ávila`},
+		},
+		{
+			// As listas repetidas são lidas uma depois da outra
+			name:          "repeated list",
+			data:          `{"participantes": [{"nome": "Ana"}], "participantes": [{"nome": "Bruno"}]}`,
+			participantes: []string{"Ana", "Bruno"},
+		},
+		{
+			// Como no encoding/json, vale a última ocorrência de um campo repetido
+			name:          "repeated field",
+			data:          `{"participantes": [{"nome": "Ana", "nome": "Bruno"}]}`,
+			participantes: []string{"Bruno"},
+		},
+		{
+			name: "invalid records are discarded",
+			data: `{"participantes": [
+  {"nome": "Ana"},
+  {"nome": 10},
+  {"nome": "Bruno", "idade": 30},
+  {"id": 3, "nome": "Carla"}
+]}`,
+			participantes: []string{"Ana"},
+			erros:         []string{"3:nome", "4:", "5:id"},
+		},
+		{
+			name:  "unknown key",
+			data:  "{\n\"participantes\": [],\n\"pessoas\": []\n}",
+			erros: []string{"3:pessoas"},
+		},
+		{
+			name:  "not an object",
+			data:  `[{"nome": "Ana"}]`,
+			erros: []string{"1:"},
+		},
+		{
+			name:  "list is not an array",
+			data:  `{"votacoes": {"descricao": "Paredão"}}`,
+			erros: []string{"1:votacoes"},
+		},
+		{
+			name:  "syntax error",
+			data:  "{\"participantes\": [\n{\"nome\": \"Ana\"},\n{\"nome\" \"Bruno\"}\n]}",
+			erros: []string{"3:"},
+		},
+		{
+			name:  "truncated document",
+			data:  "{\"participantes\": [\n{\"nome\": \"Ana\"",
+			erros: []string{"2:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, erros := parseImportJSON([]byte(tt.data))
+			if got := erroPositions(erros); !slices.Equal(got, append([]string{}, tt.erros...)) {
+				t.Fatalf("erros = %+v, want %v", erros, tt.erros)
+			}
+			if doc == nil {
+				if tt.participantes != nil || tt.votacoes != nil {
+					t.Fatal("document was discarded")
+				}
+				return
+			}
+
+			nomes := []string{}
+			for _, p := range doc.Participantes {
+				nomes = append(nomes, p.Nome)
+			}
+			if !slices.Equal(nomes, append([]string{}, tt.participantes...)) {
+				t.Errorf("participantes = %q, want %q", nomes, tt.participantes)
+			}
+			descricoes := []string{}
+			for _, v := range doc.Votacoes {
+				descricoes = append(descricoes, v.Descricao)
+			}
+			if !slices.Equal(descricoes, append([]string{}, tt.votacoes...)) {
+				t.Errorf("votacoes = %q, want %q", descricoes, tt.votacoes)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+	"github.com/danielfs/paredao/backend/statistics"
+	"github.com/danielfs/paredao/backend/workers"
+)
+
+// Tamanho máximo do motivo de uma invalidação
+const maxMotivoLength = 255
+
+func GetVotacaoInvalidacoes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	if _, err := getCachedVotacao(r, id); err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	invalidacoes := repositories.GetInvalidacoesByVotacaoID(tenantID(r), id)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(invalidacoes); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// CreateInvalidacao invalida os votos de uma votação que atendem ao filtro informado
+func CreateInvalidacao(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	votacaoID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		ParticipanteID int64     `json:"participanteId"`
+		IPHash         string    `json:"ipHash"`
+		From           time.Time `json:"from"`
+		To             time.Time `json:"to"`
+		Motivo         string    `json:"motivo"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Valida campos obrigatórios
+	request.Motivo = strings.TrimSpace(request.Motivo)
+	if request.Motivo == "" || len(request.Motivo) > maxMotivoLength {
+		http.Error(w, "Motivo is required and must have at most 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	// Invalidar todos os votos da votação de uma vez exige ao menos um filtro explícito
+	if request.ParticipanteID == 0 && request.IPHash == "" && request.From.IsZero() && request.To.IsZero() {
+		http.Error(w, "At least one of participanteId, ipHash, from or to is required", http.StatusBadRequest)
+		return
+	}
+
+	if !request.From.IsZero() && !request.To.IsZero() && !request.From.Before(request.To) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	if _, err := repositories.Votacoes.GetByID(tenantID(r), votacaoID); err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	invalidacao, err := repositories.InvalidateVotos(tenantID(r), repositories.VotoFilter{
+		VotacaoID:      votacaoID,
+		ParticipanteID: request.ParticipanteID,
+		IPHash:         request.IPHash,
+		From:           request.From.UTC(),
+		To:             request.To.UTC(),
+	}, request.Motivo)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, "Votacao not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repositories.ErrVotacaoArquivada) {
+		http.Error(w, "Votos of an archived votacao cannot be changed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error invalidating votos for votacao %d: %v", votacaoID, err)
+		http.Error(w, "Failed to invalidate votos", http.StatusInternalServerError)
+		return
+	}
+	scheduleRecount(r, votacaoID, invalidacao.Motivo)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(invalidacao); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// RevertInvalidacao volta a contar os votos invalidados por uma operação
+func RevertInvalidacao(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	votacaoID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	invalidacaoID, err := strconv.ParseInt(vars["invalidacaoId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid invalidacaoId format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	if _, err := repositories.Votacoes.GetByID(tenantID(r), votacaoID); err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	invalidacao, exists := repositories.GetInvalidacaoByID(tenantID(r), invalidacaoID)
+	if !exists || invalidacao.VotacaoID != votacaoID {
+		http.Error(w, "Invalidacao not found", http.StatusNotFound)
+		return
+	}
+
+	invalidacao, err = repositories.RevertInvalidacao(tenantID(r), invalidacaoID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, "Invalidacao not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repositories.ErrInvalidacaoRevertida) {
+		http.Error(w, "Invalidacao is already reverted", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, repositories.ErrVotacaoArquivada) {
+		http.Error(w, "Votos of an archived votacao cannot be changed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error reverting invalidacao %d: %v", invalidacaoID, err)
+		http.Error(w, "Failed to revert invalidacao", http.StatusInternalServerError)
+		return
+	}
+	scheduleRecount(r, votacaoID, "Reversão: "+invalidacao.Motivo)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(invalidacao); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// scheduleRecount descarta as estatísticas em cache da votação, cujas agregações a
+// invalidação já ajustou, e agenda a recontagem no agregador
+func scheduleRecount(r *http.Request, votacaoID int64, motivo string) {
+	invalidateVotacaoCache(r, votacaoID)
+	workers.EnqueueRecount(tenantID(r), votacaoID, motivo)
+}
+
+// RecountVotacao confere as agregações da votação com os votos válidos, descarta as
+// estatísticas em cache, atualiza o eliminado da temporada e envia o novo resultado aos
+// clientes conectados. Executada pelo agregador, com o tenant em ctx.
+func RecountVotacao(ctx context.Context, tenantID, votacaoID int64, motivo string) {
+	discrepancies, err := repositories.RebuildRollups(votacaoID, true)
+	if err != nil {
+		log.Printf("Error recounting votacao %d: %v", votacaoID, err)
+	} else if len(discrepancies) > 0 {
+		log.Printf("Recount of votacao %d fixed %d discrepancies", votacaoID, len(discrepancies))
+		if err := repositories.InvalidateVotacaoCache(ctx, votacaoID); err != nil {
+			log.Printf("Cache invalidation error for votacao %d: %v", votacaoID, err)
+		}
+	}
+
+	// Em uma votação já encerrada, a recontagem pode mudar o eliminado da temporada
+	if votacao, err := repositories.Votacoes.GetByID(tenantID, votacaoID); err == nil {
+		markEliminado(tenantID, votacao)
+	}
+
+	totals, err := repositories.GetTotalVotesByParticipante(tenantID, votacaoID)
+	if err != nil {
+		log.Printf("Error getting totals after recount of votacao %d: %v", votacaoID, err)
+		return
+	}
+
+	recontagem := entities.Recontagem{
+		VotacaoID:     votacaoID,
+		Participantes: statistics.Rank(totals, defaultRoundingStrategy(), defaultPercentDecimals),
+		Motivo:        motivo,
+		AtualizadoEm:  time.Now().UTC(),
+	}
+	for _, t := range totals {
+		recontagem.Total += t.Total
+		recontagem.Pontos += t.Pontos
+	}
+
+	if err := repositories.PublishStatsUpdate(ctx, recontagem); err != nil {
+		log.Printf("Error publishing recount of votacao %d: %v", votacaoID, err)
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+func invalidacaoRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/votacoes/{id}/invalidacoes", CreateInvalidacao).Methods("POST")
+	router.HandleFunc("/votacoes/{id}/invalidacoes/{invalidacaoId}/reverter", RevertInvalidacao).Methods("POST")
+	router.HandleFunc("/votacoes/{id}/alertas/{alertaId}/invalidar", InvalidarAlerta).Methods("POST")
+	return router
+}
+
+// castTestVotosFrom grava n votos no participante vindos da origem informada, já agregados
+func castTestVotosFrom(t *testing.T, v *entities.Votacao, p *entities.Participante, ipHash string, n int) {
+	t.Helper()
+	for range n {
+		voto := &entities.Voto{Participante: p, Votacao: v, IPHash: ipHash}
+		if _, err := repositories.SaveVoto(entities.DefaultTenantID, voto); err != nil {
+			t.Fatalf("saving voto: %v", err)
+		}
+	}
+	for {
+		processed, err := repositories.AggregateNewVotos()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if processed == 0 {
+			return
+		}
+	}
+}
+
+// testTotals retorna os votos de cada participante nas agregações e na tabela de votos
+func testTotals(t *testing.T, v *entities.Votacao) (rollups, final map[int64]int) {
+	t.Helper()
+	index := func(totals []entities.ParticipanteTotalResponse, err error) map[int64]int {
+		if err != nil {
+			t.Fatal(err)
+		}
+		byID := map[int64]int{}
+		for _, total := range totals {
+			byID[total.ParticipanteID] = total.Total
+		}
+		return byID
+	}
+	return index(repositories.GetTotalVotesByParticipante(entities.DefaultTenantID, v.ID)),
+		index(repositories.GetFinalTotalsByParticipante(entities.DefaultTenantID, v.ID))
+}
+
+func assertTestTotals(t *testing.T, v *entities.Votacao, want map[int64]int) {
+	t.Helper()
+	rollups, final := testTotals(t, v)
+	for id, total := range want {
+		if rollups[id] != total || final[id] != total {
+			t.Errorf("participante %d has %d votos in the rollups and %d in votos, want %d",
+				id, rollups[id], final[id], total)
+		}
+	}
+}
+
+func TestInvalidateAndRevertVotos(t *testing.T) {
+	participantes := newTestParticipantes(t, 2)
+	votacao := createTestVotacao(t, &entities.Votacao{}, participantes...)
+	a, b := participantes[0], participantes[1]
+	castTestVotosFrom(t, votacao, a, "origem-suspeita", 4)
+	castTestVotosFrom(t, votacao, a, "origem-comum", 1)
+	castTestVotosFrom(t, votacao, b, "origem-comum", 3)
+	router := invalidacaoRouter()
+	path := fmt.Sprintf("/votacoes/%d/invalidacoes", votacao.ID)
+
+	// Sem filtro, a invalidação de todos os votos é recusada
+	if rec := serveJSON(router, "POST", path, `{"motivo": "tudo"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalidation without a filter: status = %d, want 400", rec.Code)
+	}
+
+	rec := serveJSON(router, "POST", path, `{"ipHash": "origem-suspeita", "motivo": "robô"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("invalidation: status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	var invalidacao entities.Invalidacao
+	if err := json.Unmarshal(rec.Body.Bytes(), &invalidacao); err != nil {
+		t.Fatal(err)
+	}
+	if invalidacao.Votos != 4 {
+		t.Errorf("invalidated %d votos, want 4", invalidacao.Votos)
+	}
+
+	// Os votos saem dos totais e a liderança muda
+	assertTestTotals(t, votacao, map[int64]int{a.ID: 1, b.ID: 3})
+
+	revert := fmt.Sprintf("%s/%d/reverter", path, invalidacao.ID)
+	if rec := serveJSON(router, "POST", revert, ""); rec.Code != http.StatusOK {
+		t.Fatalf("revert: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	assertTestTotals(t, votacao, map[int64]int{a.ID: 5, b.ID: 3})
+
+	if rec := serveJSON(router, "POST", revert, ""); rec.Code != http.StatusConflict {
+		t.Errorf("second revert: status = %d, want 409", rec.Code)
+	}
+	assertTestTotals(t, votacao, map[int64]int{a.ID: 5, b.ID: 3})
+}
+
+func TestInvalidarAlerta(t *testing.T) {
+	participantes := newTestParticipantes(t, 2)
+	votacao := createTestVotacao(t, &entities.Votacao{}, participantes...)
+	a, b := participantes[0], participantes[1]
+	castTestVotosFrom(t, votacao, a, "origem-suspeita", 3)
+	castTestVotosFrom(t, votacao, b, "origem-suspeita", 2)
+	castTestVotosFrom(t, votacao, a, "origem-comum", 1)
+
+	now := time.Now().UTC()
+	err := repositories.SaveAlerta(&entities.Alerta{
+		VotacaoID: votacao.ID, ParticipanteID: a.ID, Tipo: entities.AlertaTaxaOrigem, Origem: "origem-suspeita",
+		Valor: 3, Limite: 1, Votos: 3, JanelaInicio: now.Add(-time.Hour), JanelaFim: now.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alertas := repositories.GetAlertasByVotacaoID(entities.DefaultTenantID, votacao.ID)
+	if len(alertas) != 1 {
+		t.Fatalf("votacao has %d alertas, want 1", len(alertas))
+	}
+
+	// Só os votos da origem do alerta no participante do alerta, dentro da janela, são invalidados
+	path := fmt.Sprintf("/votacoes/%d/alertas/%d/invalidar", votacao.ID, alertas[0].ID)
+	rec := serveJSON(invalidacaoRouter(), "POST", path, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	assertTestTotals(t, votacao, map[int64]int{a.ID: 1, b.ID: 2})
+
+	// O alerta de outra votação não é encontrado por esta
+	other := createTestVotacao(t, &entities.Votacao{}, a)
+	path = fmt.Sprintf("/votacoes/%d/alertas/%d/invalidar", other.ID, alertas[0].ID)
+	if rec := serveJSON(invalidacaoRouter(), "POST", path, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("alerta of another votacao: status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// Os testes dos handlers usam o banco SQLite do modo de desenvolvimento, num arquivo
+// temporário, porque parte das consultas ainda não passa pelos armazenamentos
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "paredao-handlers-test")
+	if err != nil {
+		log.Fatalf("Failed to create test database directory: %v", err)
+	}
+
+	os.Setenv("DB_NAME", filepath.Join(dir, "paredao.db"))
+	repositories.InitDevDB()
+
+	code := m.Run()
+
+	repositories.CloseDB()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// createTestVotacao grava a votação no tenant padrão com os participantes informados
+func createTestVotacao(t *testing.T, v *entities.Votacao, participantes ...*entities.Participante) *entities.Votacao {
+	t.Helper()
+	if v.Descricao == "" {
+		v.Descricao = uniqueNome("Votacao")
+	}
+	if err := repositories.Votacoes.Save(entities.DefaultTenantID, v); err != nil {
+		t.Fatalf("saving votacao: %v", err)
+	}
+	t.Cleanup(func() { repositories.Votacoes.Delete(entities.DefaultTenantID, v.ID) })
+
+	for _, p := range participantes {
+		if err := repositories.AddParticipanteToVotacaoInDB(entities.DefaultTenantID, p.ID, v.ID); err != nil {
+			t.Fatalf("adding participante %d to votacao: %v", p.ID, err)
+		}
+	}
+	return v
+}
+
+// castTestVotos grava n votos no participante, sem passar pelo agregador
+func castTestVotos(t *testing.T, v *entities.Votacao, p *entities.Participante, n int) {
+	t.Helper()
+	for range n {
+		voto := &entities.Voto{Participante: p, Votacao: v, IPHash: "ip-teste"}
+		if _, err := repositories.SaveVoto(entities.DefaultTenantID, voto); err != nil {
+			t.Fatalf("saving voto: %v", err)
+		}
+	}
+}
+
+// newTestParticipantes cria n participantes com nomes únicos
+func newTestParticipantes(t *testing.T, n int) []*entities.Participante {
+	t.Helper()
+	participantes := []*entities.Participante{}
+	for i := range n {
+		participantes = append(participantes, createTestParticipante(t, &entities.Participante{
+			Nome: uniqueNome(fmt.Sprintf("Participante %d", i)), URLFoto: "https://example.com/p.jpg",
+		}))
+	}
+	return participantes
+}
+
+// encerrarTestVotacao encerra a votação pela rota, que também registra o eliminado
+func encerrarTestVotacao(t *testing.T, v *entities.Votacao) {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/votacoes/{id}/encerrar", EncerrarVotacao).Methods("POST")
+	if rec := serveJSON(router, "POST", fmt.Sprintf("/votacoes/%d/encerrar", v.ID), ""); rec.Code != http.StatusOK {
+		t.Fatalf("closing votacao: status = %d: %s", rec.Code, rec.Body)
+	}
+}
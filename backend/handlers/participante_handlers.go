@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -12,7 +15,18 @@ import (
 )
 
 func GetParticipantes(w http.ResponseWriter, r *http.Request) {
-	participantes := repositories.GetAllParticipantes()
+	participantes, err := repositories.GetOrLoadCache(
+		r.Context(),
+		repositories.ParticipantesCacheKey,
+		entityCacheOptions(repositories.ParticipantesTag),
+		func() ([]*entities.Participante, error) {
+			return repositories.Participantes.GetAll(tenantID(r)), nil
+		},
+	)
+	if err != nil {
+		http.Error(w, "Error getting participantes", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(participantes); err != nil {
@@ -29,9 +43,9 @@ func GetParticipante(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	participante, exists := repositories.GetParticipanteByID(id)
-	if !exists {
-		http.Error(w, "Participante not found", http.StatusNotFound)
+	participante, err := getCachedParticipante(r, id)
+	if err != nil {
+		writeLookupError(w, err, "Participante")
 		return
 	}
 
@@ -49,15 +63,21 @@ func CreateParticipante(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	participante.ID = 0
 
-	// Valida campos obrigatórios
-	if participante.Nome == "" {
-		http.Error(w, "Nome is required", http.StatusBadRequest)
+	// Valida todos os campos, informando cada um que estiver inválido
+	if erros := validateParticipante(&participante); len(erros) > 0 {
+		writeValidationErrors(w, erros)
 		return
 	}
 
 	// Salva participante
-	savedParticipante := repositories.SaveParticipante(&participante)
+	savedParticipante := repositories.Participantes.Save(tenantID(r), &participante)
+	if savedParticipante == nil {
+		http.Error(w, "Error saving participante", http.StatusInternalServerError)
+		return
+	}
+	invalidateCacheTags(r, repositories.ParticipantesTag)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -67,6 +87,7 @@ func CreateParticipante(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// UpdateParticipante substitui todos os campos do participante; os omitidos ficam vazios
 func UpdateParticipante(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
@@ -76,9 +97,9 @@ func UpdateParticipante(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verifica se o participante existe
-	_, exists := repositories.GetParticipanteByID(id)
-	if !exists {
-		http.Error(w, "Participante not found", http.StatusNotFound)
+	existing, err := repositories.Participantes.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Participante")
 		return
 	}
 
@@ -90,20 +111,95 @@ func UpdateParticipante(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	saveExistingParticipante(w, r, existing, &participante)
+}
+
+// PatchParticipante altera apenas os campos enviados, como um JSON Merge Patch: campos
+// nulos são apagados e as redes sociais são combinadas com as atuais
+func PatchParticipante(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se o participante existe
+	existing, err := repositories.Participantes.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Participante")
+		return
+	}
+
+	var patch map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Aplica a alteração sobre a representação JSON do participante atual
+	current, err := json.Marshal(existing)
+	if err != nil {
+		http.Error(w, "Error encoding participante", http.StatusInternalServerError)
+		return
+	}
+	var document interface{}
+	if err := json.Unmarshal(current, &document); err != nil {
+		http.Error(w, "Error encoding participante", http.StatusInternalServerError)
+		return
+	}
+	merged, err := json.Marshal(mergePatch(document, patch))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var participante entities.Participante
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&participante); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saveExistingParticipante(w, r, existing, &participante)
+}
+
+// saveExistingParticipante valida e grava a nova versão de um participante já existente,
+// respondendo com o participante gravado
+func saveExistingParticipante(w http.ResponseWriter, r *http.Request, existing, participante *entities.Participante) {
 	// Garante que o ID corresponde ao parâmetro do caminho
-	participante.ID = id
+	participante.ID = existing.ID
+
+	// Valida todos os campos, informando cada um que estiver inválido
+	if erros := validateParticipante(participante); len(erros) > 0 {
+		writeValidationErrors(w, erros)
+		return
+	}
 
-	// Valida campos obrigatórios
-	if participante.Nome == "" {
-		http.Error(w, "Nome is required", http.StatusBadRequest)
+	// Grava o participante; o nome precisa ser único em cada temporada da qual ele faz parte
+	err := repositories.Participantes.Update(tenantID(r), participante)
+	switch {
+	case errors.Is(err, repositories.ErrNomeEmUso):
+		writeValidationErrors(w, []entities.ErroCampo{nomeTakenError})
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "Participante not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Error saving participante %d: %v", existing.ID, err)
+		http.Error(w, "Error saving participante", http.StatusInternalServerError)
 		return
 	}
+	invalidateParticipanteCache(r, existing.ID, repositories.GetVotacaoIDsByParticipanteID(existing.ID))
 
-	// Salva o participante atualizado
-	updatedParticipante := repositories.SaveParticipante(&participante)
+	// Uma foto enviada que deixou de ser usada é removida
+	if existing.URLFoto != participante.URLFoto {
+		deleteFotos(r, existing.ID, existing.URLFoto)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(updatedParticipante); err != nil {
+	if err := json.NewEncoder(w).Encode(participante); err != nil {
 		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 		return
 	}
@@ -117,11 +213,23 @@ func DeleteParticipante(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	success := repositories.DeleteParticipanteByID(id)
+	// O participante é lido antes da exclusão para que a foto enviada seja removida
+	participante, err := repositories.Participantes.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Participante")
+		return
+	}
+
+	// As votações são lidas antes da exclusão, que remove os vínculos em cascata
+	votacaoIDs := repositories.GetVotacaoIDsByParticipanteID(id)
+
+	success := repositories.Participantes.Delete(tenantID(r), id)
 	if !success {
 		http.Error(w, "Participante not found", http.StatusNotFound)
 		return
 	}
+	invalidateParticipanteCache(r, id, votacaoIDs)
+	deleteFotos(r, id, participante.URLFoto)
 
 	w.WriteHeader(http.StatusNoContent)
 }
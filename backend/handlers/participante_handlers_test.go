@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+func participanteRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/participantes", CreateParticipante).Methods("POST")
+	r.HandleFunc("/participantes/{id}", UpdateParticipante).Methods("PUT")
+	r.HandleFunc("/participantes/{id}", PatchParticipante).Methods("PATCH")
+	r.HandleFunc("/temporadas/{id}/participantes", AddParticipanteToTemporada).Methods("POST")
+	return r
+}
+
+func serveJSON(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// uniqueNome gera um nome que não se repete entre os testes
+func uniqueNome(prefix string) string {
+	return fmt.Sprintf("%s %d", prefix, time.Now().UnixNano())
+}
+
+func createTestParticipante(t *testing.T, p *entities.Participante) *entities.Participante {
+	t.Helper()
+	saved := repositories.Participantes.Save(entities.DefaultTenantID, p)
+	if saved == nil {
+		t.Fatal("saving participante failed")
+	}
+	t.Cleanup(func() { repositories.Participantes.Delete(entities.DefaultTenantID, saved.ID) })
+	return saved
+}
+
+func createTestTemporada(t *testing.T, participantes ...*entities.Participante) *entities.Temporada {
+	t.Helper()
+	temporada := repositories.Temporadas.Save(entities.DefaultTenantID, &entities.Temporada{Nome: uniqueNome("Temporada")})
+	if temporada == nil {
+		t.Fatal("saving temporada failed")
+	}
+	t.Cleanup(func() { repositories.Temporadas.Delete(entities.DefaultTenantID, temporada.ID) })
+
+	for _, p := range participantes {
+		if err := repositories.AddParticipanteToTemporadaInDB(p.ID, temporada.ID); err != nil {
+			t.Fatalf("adding participante %d to temporada: %v", p.ID, err)
+		}
+	}
+	return temporada
+}
+
+// decodeErros retorna os campos da resposta 422, em ordem
+func decodeErros(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422: %s", rec.Code, rec.Body)
+	}
+	var response entities.ErroValidacaoResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	campos := []string{}
+	for _, erro := range response.Erros {
+		campos = append(campos, erro.Campo)
+	}
+	return campos
+}
+
+func TestCreateParticipanteReportsEveryInvalidField(t *testing.T) {
+	body := `{
+		"nome": "A",
+		"urlFoto": "ftp://example.com/a.jpg",
+		"dataNascimento": "31/12/1990",
+		"redesSociais": {"orkut": "ana"},
+		"cor": "vermelho"
+	}`
+	rec := serveJSON(participanteRouter(), "POST", "/participantes", body)
+
+	campos := decodeErros(t, rec)
+	for _, campo := range []string{"nome", "urlFoto", "dataNascimento", "cor"} {
+		if !slices.Contains(campos, campo) {
+			t.Errorf("erros %v do not include %s", campos, campo)
+		}
+	}
+	if !slices.ContainsFunc(campos, func(campo string) bool { return strings.HasPrefix(campo, "redesSociais") }) {
+		t.Errorf("erros %v do not include the unknown rede social", campos)
+	}
+}
+
+func TestPatchParticipanteMergesFields(t *testing.T) {
+	p := createTestParticipante(t, &entities.Participante{
+		Nome:         uniqueNome("Ana"),
+		URLFoto:      "https://example.com/ana.jpg",
+		Apelido:      "Aninha",
+		Cidade:       "Recife",
+		RedesSociais: map[string]string{"instagram": "ana", "tiktok": "ana.t"},
+	})
+
+	// Campos ausentes ficam como estão, null apaga e as redes sociais são combinadas
+	body := `{"cidade": null, "bio": "Nova bio", "redesSociais": {"tiktok": null, "x": "@ana_x"}}`
+	rec := serveJSON(participanteRouter(), "PATCH", fmt.Sprintf("/participantes/%d", p.ID), body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	got, err := repositories.Participantes.GetByID(entities.DefaultTenantID, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Nome != p.Nome || got.Apelido != "Aninha" || got.URLFoto != p.URLFoto {
+		t.Errorf("untouched fields changed: %+v", got)
+	}
+	if got.Cidade != "" || got.Bio != "Nova bio" {
+		t.Errorf("cidade = %q, bio = %q; want empty and Nova bio", got.Cidade, got.Bio)
+	}
+	if want := map[string]string{"instagram": "ana", "x": "ana_x"}; !maps.Equal(got.RedesSociais, want) {
+		t.Errorf("redesSociais = %v, want %v", got.RedesSociais, want)
+	}
+}
+
+func TestPatchParticipanteRejectsInvalidChanges(t *testing.T) {
+	p := createTestParticipante(t, &entities.Participante{Nome: uniqueNome("Bruno"), URLFoto: "https://example.com/b.jpg"})
+	path := fmt.Sprintf("/participantes/%d", p.ID)
+
+	// Campos desconhecidos são recusados em vez de ignorados
+	if rec := serveJSON(participanteRouter(), "PATCH", path, `{"nomee": "Bruno"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown field: status = %d, want 400", rec.Code)
+	}
+
+	// Apagar um campo obrigatório falha na validação, e nada é gravado
+	rec := serveJSON(participanteRouter(), "PATCH", path, `{"nome": null, "cor": "#12345g"}`)
+	if campos := decodeErros(t, rec); !slices.Equal(campos, []string{"nome", "cor"}) {
+		t.Errorf("erros = %v, want [nome cor]", campos)
+	}
+	got, err := repositories.Participantes.GetByID(entities.DefaultTenantID, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Nome != p.Nome || got.Cor != "" {
+		t.Errorf("a rejected patch was saved: %+v", got)
+	}
+
+	rec = serveJSON(participanteRouter(), "PATCH", "/participantes/999999", `{"bio": "x"}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing participante: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestParticipanteNomeIsUniquePerTemporada(t *testing.T) {
+	newParticipante := func(nome string) *entities.Participante {
+		return createTestParticipante(t, &entities.Participante{Nome: uniqueNome(nome), URLFoto: "https://example.com/p.jpg"})
+	}
+	ana, bruno, carla := newParticipante("Ana"), newParticipante("Bruno"), newParticipante("Carla")
+	temporada := createTestTemporada(t, ana, bruno)
+	router := participanteRouter()
+
+	// Renomear para o nome de outro participante da temporada é recusado
+	body := fmt.Sprintf(`{"nome": %q}`, ana.Nome)
+	rec := serveJSON(router, "PATCH", fmt.Sprintf("/participantes/%d", bruno.ID), body)
+	if campos := decodeErros(t, rec); !slices.Equal(campos, []string{"nome"}) {
+		t.Errorf("rename: erros = %v, want [nome]", campos)
+	}
+
+	// Quem não está na temporada pode usar o nome
+	rec = serveJSON(router, "PATCH", fmt.Sprintf("/participantes/%d", carla.ID), body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rename outside the temporada: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	// Mas não pode entrar na temporada com ele
+	path := fmt.Sprintf("/temporadas/%d/participantes", temporada.ID)
+	rec = serveJSON(router, "POST", path, fmt.Sprintf(`{"participanteId": %d}`, carla.ID))
+	if campos := decodeErros(t, rec); !slices.Equal(campos, []string{"nome"}) {
+		t.Errorf("add to temporada: erros = %v, want [nome]", campos)
+	}
+}
+
+func TestConcurrentRenamesKeepNomeUnique(t *testing.T) {
+	const participantes = 4
+	members := []*entities.Participante{}
+	for i := range participantes {
+		members = append(members, createTestParticipante(t, &entities.Participante{
+			Nome: uniqueNome(fmt.Sprintf("Participante %d", i)), URLFoto: "https://example.com/p.jpg",
+		}))
+	}
+	createTestTemporada(t, members...)
+	router := participanteRouter()
+
+	// Todos tentam ao mesmo tempo o mesmo nome; apenas um pode consegui-lo
+	body := fmt.Sprintf(`{"nome": %q}`, uniqueNome("Disputado"))
+	statuses := make([]int, participantes)
+	var wg sync.WaitGroup
+	for i, p := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = serveJSON(router, "PATCH", fmt.Sprintf("/participantes/%d", p.ID), body).Code
+		}()
+	}
+	wg.Wait()
+
+	counts := map[int]int{}
+	for _, status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusUnprocessableEntity] != participantes-1 {
+		t.Errorf("statuses = %v, want one 200 and %d 422", statuses, participantes-1)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Limites dos campos do perfil, em caracteres
+const (
+	minNomeLength    = 2
+	maxNomeLength    = 100
+	maxApelidoLength = 50
+	maxBioLength     = 500
+	maxCidadeLength  = 100
+	maxURLLength     = 255
+)
+
+var (
+	corPattern    = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+	handlePattern = regexp.MustCompile(`^[A-Za-z0-9._]{1,30}$`)
+)
+
+// nomeTakenError é o erro de um nome já usado por outro participante da mesma temporada
+var nomeTakenError = entities.ErroCampo{
+	Campo:    "nome",
+	Mensagem: "Nome is already used by another participante in the same temporada",
+}
+
+// Datas de nascimento aceitas a partir desta
+var minDataNascimento = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// validateParticipante normaliza os campos do participante (espaços, "@" dos nomes de
+// usuário, cor em minúsculas) e retorna todos os campos inválidos
+func validateParticipante(p *entities.Participante) []entities.ErroCampo {
+	erros := []entities.ErroCampo{}
+	invalid := func(campo, format string, args ...interface{}) {
+		erros = append(erros, entities.ErroCampo{Campo: campo, Mensagem: fmt.Sprintf(format, args...)})
+	}
+
+	p.Nome = strings.TrimSpace(p.Nome)
+	p.Apelido = strings.TrimSpace(p.Apelido)
+	p.Cidade = strings.TrimSpace(p.Cidade)
+	p.Bio = strings.TrimSpace(p.Bio)
+	p.URLFoto = strings.TrimSpace(p.URLFoto)
+	p.Cor = strings.ToLower(strings.TrimSpace(p.Cor))
+
+	if length := utf8.RuneCountInString(p.Nome); length < minNomeLength || length > maxNomeLength {
+		invalid("nome", "Nome must have between %d and %d characters", minNomeLength, maxNomeLength)
+	}
+	if utf8.RuneCountInString(p.Apelido) > maxApelidoLength {
+		invalid("apelido", "Apelido must have at most %d characters", maxApelidoLength)
+	}
+	if utf8.RuneCountInString(p.Bio) > maxBioLength {
+		invalid("bio", "Bio must have at most %d characters", maxBioLength)
+	}
+	if utf8.RuneCountInString(p.Cidade) > maxCidadeLength {
+		invalid("cidade", "Cidade must have at most %d characters", maxCidadeLength)
+	}
+
+	if p.URLFoto != "" {
+		u, err := url.Parse(p.URLFoto)
+		switch {
+		case len(p.URLFoto) > maxURLLength:
+			invalid("urlFoto", "UrlFoto must have at most %d characters", maxURLLength)
+		case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+			invalid("urlFoto", "UrlFoto must be an absolute http or https URL")
+		}
+	}
+
+	if p.DataNascimento != "" {
+		date, err := time.Parse(time.DateOnly, p.DataNascimento)
+		switch {
+		case err != nil:
+			invalid("dataNascimento", "DataNascimento must be a date in the format YYYY-MM-DD")
+		case date.Before(minDataNascimento) || date.After(time.Now()):
+			invalid("dataNascimento", "DataNascimento must be between 1900-01-01 and today")
+		}
+	}
+
+	for rede, handle := range p.RedesSociais {
+		campo := "redesSociais." + rede
+		handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+		switch {
+		case !slices.Contains(entities.RedesSociais, rede):
+			invalid(campo, "Unknown rede social, expected one of %s", strings.Join(entities.RedesSociais, ", "))
+		case !handlePattern.MatchString(handle):
+			invalid(campo, "Handle must have up to 30 letters, numbers, dots or underscores")
+		default:
+			p.RedesSociais[rede] = handle
+		}
+	}
+
+	if p.Cor != "" && !corPattern.MatchString(p.Cor) {
+		invalid("cor", "Cor must be a hex color in the format #rrggbb")
+	}
+
+	return erros
+}
+
+// writeValidationErrors responde 422 com a lista de campos inválidos
+func writeValidationErrors(w http.ResponseWriter, erros []entities.ErroCampo) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	response := entities.ErroValidacaoResponse{Mensagem: "Validation failed", Erros: erros}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// mergePatch aplica um JSON Merge Patch (RFC 7396): campos nulos são removidos, objetos
+// são combinados campo a campo e os demais valores substituem os atuais
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+	"github.com/danielfs/paredao/backend/statistics"
+)
+
+// Número padrão de participantes levados a uma rodada pelo critério top
+const defaultTopN = 2
+
+// DeriveVotacao cria uma nova rodada a partir de uma votação encerrada, com os
+// participantes mais votados (critério top) ou os empatados na posição de eliminação
+// (critério empate)
+func DeriveVotacao(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Criterio  string         `json:"criterio"`
+		N         int            `json:"n"`
+		Descricao string         `json:"descricao"`
+		Modo      string         `json:"modo"`
+		Pesos     map[string]int `json:"pesos"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	origem, err := repositories.Votacoes.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	if !origem.Encerrada() {
+		http.Error(w, "Votacao must be closed before deriving a new round", http.StatusConflict)
+		return
+	}
+
+	totals, err := repositories.GetTotalVotesByParticipante(tenantID(r), id)
+	if err != nil {
+		http.Error(w, "Error getting total votes by participante", http.StatusInternalServerError)
+		return
+	}
+	ranked := statistics.Rank(totals, defaultRoundingStrategy(), defaultPercentDecimals)
+
+	var escolhidos []entities.ParticipanteTotalResponse
+	switch request.Criterio {
+	case entities.CriterioTop:
+		if request.N == 0 {
+			request.N = defaultTopN
+		}
+		if request.N < 2 {
+			http.Error(w, "N must be at least 2", http.StatusBadRequest)
+			return
+		}
+		escolhidos = statistics.Top(ranked, request.N)
+	case entities.CriterioEmpate:
+		_, escolhidos = statistics.Eliminate(ranked, origem.Modo)
+		if len(escolhidos) == 0 {
+			http.Error(w, "Votacao has no tie at the elimination position", http.StatusConflict)
+			return
+		}
+	default:
+		http.Error(w, "Invalid criterio, expected top or empate", http.StatusBadRequest)
+		return
+	}
+
+	if len(escolhidos) < 2 {
+		http.Error(w, "A new round needs at least two participantes", http.StatusConflict)
+		return
+	}
+
+	votacao := &entities.Votacao{
+		Descricao:       request.Descricao,
+		Modo:            request.Modo,
+		Pesos:           request.Pesos,
+		VotacaoOrigemID: &origem.ID,
+		Criterio:        request.Criterio,
+		TemporadaID:     origem.TemporadaID,
+	}
+
+	if votacao.Descricao == "" {
+		votacao.Descricao = origem.Descricao + " - Rodada final"
+		if request.Criterio == entities.CriterioEmpate {
+			votacao.Descricao = origem.Descricao + " - Desempate"
+		}
+	}
+
+	// Sem modo na requisição, a rodada segue o modo e os pesos da votação de origem
+	if votacao.Modo == "" {
+		votacao.Modo = origem.Modo
+		votacao.Pesos = origem.Pesos
+	}
+
+	if msg := validateModo(r, votacao); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	participanteIDs := make([]int64, len(escolhidos))
+	for i, p := range escolhidos {
+		participanteIDs[i] = p.ParticipanteID
+	}
+
+	votacao, err = repositories.CreateDerivedVotacao(tenantID(r), votacao, participanteIDs)
+	switch {
+	case errors.Is(err, repositories.ErrRodadaExistente):
+		http.Error(w, "Votacao already has a derived round with this criterio", http.StatusConflict)
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "Votacao not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Error deriving votacao from %d: %v", id, err)
+		http.Error(w, "Failed to create votacao", http.StatusInternalServerError)
+		return
+	}
+	invalidateCacheTags(r, repositories.VotacoesTag)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(votacao); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetVotacaoCadeia resume a votação original e todas as rodadas derivadas dela
+func GetVotacaoCadeia(w http.ResponseWriter, r *http.Request) {
+	strategy, decimals, ok := parseRanking(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid votacaoID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	if _, err := getCachedVotacao(r, id); err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	votacoes, err := repositories.GetVotacaoChain(tenantID(r), id)
+	if err != nil || len(votacoes) == 0 {
+		log.Printf("Error getting chain of votacao %d: %v", id, err)
+		http.Error(w, "Error getting votacao rounds", http.StatusInternalServerError)
+		return
+	}
+
+	// A votação original tem o menor ID da cadeia
+	cadeia := entities.CadeiaResponse{
+		VotacaoID: votacoes[0].ID,
+		Rodadas:   make([]entities.RodadaResponse, 0, len(votacoes)),
+		Concluida: true,
+	}
+
+	for _, votacao := range votacoes {
+		rodada, err := buildRodada(r, votacao, strategy, decimals)
+		if err != nil {
+			http.Error(w, "Error getting total votes by participante", http.StatusInternalServerError)
+			return
+		}
+
+		if rodada.Eliminado != nil {
+			cadeia.Eliminado = rodada.Eliminado
+		}
+		if !votacao.Encerrada() {
+			cadeia.Concluida = false
+		}
+
+		cadeia.Rodadas = append(cadeia.Rodadas, rodada)
+	}
+
+	writeStatsJSON(w, cadeia)
+}
+
+// buildRodada monta o ranking de uma votação e, se ela estiver encerrada, o eliminado
+func buildRodada(
+	r *http.Request,
+	votacao *entities.Votacao,
+	strategy statistics.RoundingStrategy,
+	decimals int,
+) (entities.RodadaResponse, error) {
+	totals, err := getCachedTotals(r, votacao.ID)
+	if err != nil {
+		return entities.RodadaResponse{}, err
+	}
+
+	rodada := entities.RodadaResponse{
+		Votacao:       votacao,
+		Participantes: statistics.Rank(totals, strategy, decimals),
+	}
+	for _, t := range totals {
+		rodada.Total += t.Total
+		rodada.Pontos += t.Pontos
+	}
+
+	if votacao.Encerrada() {
+		eliminado, empatados := statistics.Eliminate(rodada.Participantes, votacao.Modo)
+		rodada.Eliminado = eliminado
+		rodada.Empate = len(empatados) > 0
+	}
+
+	return rodada, nil
+}
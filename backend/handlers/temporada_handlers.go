@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+	"github.com/danielfs/paredao/backend/statistics"
+)
+
+func GetTemporadas(w http.ResponseWriter, r *http.Request) {
+	temporadas := repositories.Temporadas.GetAll(tenantID(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(temporadas); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func GetTemporada(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	temporada, err := repositories.Temporadas.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Temporada")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(temporada); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func CreateTemporada(w http.ResponseWriter, r *http.Request) {
+	var temporada entities.Temporada
+	err := json.NewDecoder(r.Body).Decode(&temporada)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Valida campos obrigatórios
+	if temporada.Nome == "" {
+		http.Error(w, "Nome is required", http.StatusBadRequest)
+		return
+	}
+
+	// Salva temporada
+	savedTemporada := repositories.Temporadas.Save(tenantID(r), &temporada)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(savedTemporada); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func UpdateTemporada(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a temporada existe
+	if _, err := repositories.Temporadas.GetByID(tenantID(r), id); err != nil {
+		writeLookupError(w, err, "Temporada")
+		return
+	}
+
+	// Decodifica o corpo da requisição
+	var temporada entities.Temporada
+	err = json.NewDecoder(r.Body).Decode(&temporada)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Garante que o ID corresponde ao parâmetro do caminho
+	temporada.ID = id
+
+	// Valida campos obrigatórios
+	if temporada.Nome == "" {
+		http.Error(w, "Nome is required", http.StatusBadRequest)
+		return
+	}
+
+	// Salva a temporada atualizada
+	updatedTemporada := repositories.Temporadas.Save(tenantID(r), &temporada)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updatedTemporada); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func DeleteTemporada(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// As votações são lidas antes da exclusão, que as desvincula da temporada
+	votacoes := repositories.GetVotacoesByTemporadaID(id)
+
+	success := repositories.Temporadas.Delete(tenantID(r), id)
+	if !success {
+		http.Error(w, "Temporada not found", http.StatusNotFound)
+		return
+	}
+	for _, votacao := range votacoes {
+		invalidateVotacaoCache(r, votacao.ID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetTemporadaParticipantes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a temporada existe
+	if _, err := repositories.Temporadas.GetByID(tenantID(r), id); err != nil {
+		writeLookupError(w, err, "Temporada")
+		return
+	}
+
+	participantes := repositories.GetTemporadaParticipantes(id)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(participantes); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func AddParticipanteToTemporada(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	temporadaID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid temporada ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a temporada existe
+	if _, err := repositories.Temporadas.GetByID(tenantID(r), temporadaID); err != nil {
+		writeLookupError(w, err, "Temporada")
+		return
+	}
+
+	// Analisa o corpo da requisição
+	var request struct {
+		ParticipanteID int64 `json:"participanteId"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Valida campos obrigatórios
+	if request.ParticipanteID == 0 {
+		http.Error(w, "ParticipanteId is required", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se o participante existe
+	participante, err := repositories.Participantes.GetByID(tenantID(r), request.ParticipanteID)
+	if err != nil {
+		writeLookupError(w, err, "Participante")
+		return
+	}
+
+	// Adiciona participante à temporada; o nome precisa ser único nela
+	err = repositories.AddParticipanteToTemporadaInDB(participante.ID, temporadaID)
+	switch {
+	case errors.Is(err, repositories.ErrNomeEmUso):
+		writeValidationErrors(w, []entities.ErroCampo{nomeTakenError})
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "Participante not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Error adding participante %d to temporada %d: %v", participante.ID, temporadaID, err)
+		http.Error(w, "Failed to add participante to temporada", http.StatusInternalServerError)
+		return
+	}
+
+	// Retorna o participante que foi adicionado
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(participante); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// UpdateTemporadaParticipante altera manualmente a situação de um participante na
+// temporada, por exemplo para apontar o vencedor ou corrigir uma eliminação
+func UpdateTemporadaParticipante(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	temporadaID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid temporada ID format", http.StatusBadRequest)
+		return
+	}
+
+	participanteID, err := strconv.ParseInt(vars["participanteId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid participanteId format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a temporada existe
+	if _, err := repositories.Temporadas.GetByID(tenantID(r), temporadaID); err != nil {
+		writeLookupError(w, err, "Temporada")
+		return
+	}
+
+	var request struct {
+		Status string `json:"status"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch request.Status {
+	case entities.StatusAtivo, entities.StatusEliminado, entities.StatusVencedor:
+	default:
+		http.Error(w, "Invalid status, expected ativo, eliminado or vencedor", http.StatusBadRequest)
+		return
+	}
+
+	success := repositories.UpdateParticipanteStatus(temporadaID, participanteID, request.Status)
+	if !success {
+		http.Error(w, "Participante not found in temporada", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTemporadaParedoes retorna o histórico da temporada: a situação de cada participante
+// e o resultado de cada votação, na ordem em que foram criadas
+func GetTemporadaParedoes(w http.ResponseWriter, r *http.Request) {
+	strategy, decimals, ok := parseRanking(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	temporada, err := repositories.Temporadas.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Temporada")
+		return
+	}
+
+	historico := entities.HistoricoTemporadaResponse{
+		Temporada:     temporada,
+		Participantes: repositories.GetTemporadaParticipantes(id),
+		Paredoes:      []entities.RodadaResponse{},
+	}
+
+	for _, votacao := range repositories.GetVotacoesByTemporadaID(id) {
+		rodada, err := buildRodada(r, votacao, strategy, decimals)
+		if err != nil {
+			http.Error(w, "Error getting total votes by participante", http.StatusInternalServerError)
+			return
+		}
+		historico.Paredoes = append(historico.Paredoes, rodada)
+	}
+
+	writeStatsJSON(w, historico)
+}
+
+// markEliminado registra na temporada o eliminado de uma votação encerrada, contando os
+// votos direto da tabela de votos. Sem decisão (empate ou nenhum voto), desfaz qualquer
+// eliminação registrada antes pela mesma votação.
+func markEliminado(tenantID int64, votacao *entities.Votacao) {
+	if votacao.TemporadaID == nil || !votacao.Encerrada() {
+		return
+	}
+
+	totals, err := repositories.GetFinalTotalsByParticipante(tenantID, votacao.ID)
+	if err != nil {
+		log.Printf("Error getting final totals of votacao %d: %v", votacao.ID, err)
+		return
+	}
+
+	var participanteID int64
+	ranked := statistics.Rank(totals, defaultRoundingStrategy(), defaultPercentDecimals)
+	if eliminado, _ := statistics.Eliminate(ranked, votacao.Modo); eliminado != nil {
+		participanteID = eliminado.ParticipanteID
+	}
+
+	if err := repositories.SetEliminadoByVotacao(*votacao.TemporadaID, votacao.ID, participanteID); err != nil {
+		log.Printf("Error marking eliminado of votacao %d: %v", votacao.ID, err)
+	}
+}
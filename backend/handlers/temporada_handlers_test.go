@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// testStatus retorna a situação de cada participante na temporada e a votação que o eliminou
+func testStatus(t *testing.T, temporada *entities.Temporada) map[int64]entities.TemporadaParticipante {
+	t.Helper()
+	status := map[int64]entities.TemporadaParticipante{}
+	for _, tp := range repositories.GetTemporadaParticipantes(temporada.ID) {
+		status[tp.ParticipanteID] = *tp
+	}
+	return status
+}
+
+// assertEliminado confere que só o participante informado saiu da temporada, pela votação
+func assertEliminado(t *testing.T, temporada *entities.Temporada, votacao *entities.Votacao, eliminado int64) {
+	t.Helper()
+	status := testStatus(t, temporada)
+	if len(status) == 0 {
+		t.Fatalf("temporada %d has no participantes", temporada.ID)
+	}
+	for id, tp := range status {
+		switch {
+		case id == eliminado && (tp.Status != entities.StatusEliminado || tp.VotacaoEliminacaoID == nil ||
+			*tp.VotacaoEliminacaoID != votacao.ID):
+			t.Errorf("participante %d = %+v, want eliminado by votacao %d", id, tp, votacao.ID)
+		case id != eliminado && (tp.Status != entities.StatusAtivo || tp.VotacaoEliminacaoID != nil):
+			t.Errorf("participante %d = %+v, want ativo", id, tp)
+		}
+	}
+}
+
+func TestMarkEliminado(t *testing.T) {
+	tests := []struct {
+		name  string
+		modo  string
+		votos []int
+		// Índice do participante eliminado, ou -1 para nenhum
+		eliminado int
+	}{
+		{"most voted leaves", entities.ModoUnica, []int{3, 1, 2}, 0},
+		{"least voted leaves when saving", entities.ModoSalvar, []int{3, 1, 2}, 1},
+		{"tie eliminates nobody", entities.ModoUnica, []int{2, 2, 1}, -1},
+		{"tie among the least voted eliminates nobody", entities.ModoSalvar, []int{3, 1, 1}, -1},
+		{"no votos", entities.ModoUnica, []int{0, 0, 0}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			participantes := newTestParticipantes(t, len(tt.votos))
+			temporada := createTestTemporada(t, participantes...)
+			votacao := createTestVotacao(t, &entities.Votacao{Modo: tt.modo, TemporadaID: &temporada.ID}, participantes...)
+			for i, n := range tt.votos {
+				castTestVotos(t, votacao, participantes[i], n)
+			}
+			encerrarTestVotacao(t, votacao)
+
+			var eliminado int64
+			if tt.eliminado >= 0 {
+				eliminado = participantes[tt.eliminado].ID
+			}
+			assertEliminado(t, temporada, votacao, eliminado)
+		})
+	}
+}
+
+func TestMarkEliminadoOutsideTemporada(t *testing.T) {
+	participantes := newTestParticipantes(t, 2)
+	temporada := createTestTemporada(t, participantes...)
+	votacao := createTestVotacao(t, &entities.Votacao{Modo: entities.ModoUnica}, participantes...)
+	castTestVotos(t, votacao, participantes[0], 3)
+	castTestVotos(t, votacao, participantes[1], 1)
+	encerrarTestVotacao(t, votacao)
+
+	// Sem temporada, a votação tem resultado mas não elimina ninguém das temporadas dos participantes
+	if resultado := getTestResultado(t, votacao); resultado.Eliminado == nil {
+		t.Fatal("votacao without temporada has no eliminado in the resultado")
+	}
+	assertEliminado(t, temporada, votacao, 0)
+}
+
+func TestMarkEliminadoAfterRecount(t *testing.T) {
+	participantes := newTestParticipantes(t, 2)
+	a, b := participantes[0], participantes[1]
+	temporada := createTestTemporada(t, participantes...)
+	votacao := createTestVotacao(t, &entities.Votacao{Modo: entities.ModoUnica, TemporadaID: &temporada.ID}, a, b)
+	castTestVotosFrom(t, votacao, a, "origem-suspeita", 3)
+	castTestVotosFrom(t, votacao, b, "origem-comum", 2)
+	encerrarTestVotacao(t, votacao)
+	assertEliminado(t, temporada, votacao, a.ID)
+
+	// Invalidados os votos suspeitos, a recontagem devolve A à temporada e elimina B
+	filter := repositories.VotoFilter{VotacaoID: votacao.ID, IPHash: "origem-suspeita"}
+	if _, err := repositories.InvalidateVotos(entities.DefaultTenantID, filter, "robô"); err != nil {
+		t.Fatal(err)
+	}
+	RecountVotacao(context.Background(), entities.DefaultTenantID, votacao.ID, "teste")
+	assertEliminado(t, temporada, votacao, b.ID)
+}
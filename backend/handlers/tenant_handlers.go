@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// Tamanho, em bytes, das chaves de API geradas para os tenants
+const apiKeySize = 32
+
+// Slugs aceitos: letras minúsculas, números e hífens
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// Chave que autoriza a administração de tenants, configurável por TENANT_ADMIN_KEY.
+// Sem ela, as rotas de administração de tenants ficam desativadas.
+var tenantAdminKey = os.Getenv("TENANT_ADMIN_KEY")
+
+// authorizeTenantAdmin confere o cabeçalho Authorization: Bearer <TENANT_ADMIN_KEY>.
+// Em caso de falha a resposta de erro já foi escrita.
+func authorizeTenantAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if tenantAdminKey == "" {
+		http.Error(w, "Tenant administration is disabled", http.StatusForbidden)
+		return false
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(token), []byte(tenantAdminKey)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func GetTenants(w http.ResponseWriter, r *http.Request) {
+	if !authorizeTenantAdmin(w, r) {
+		return
+	}
+
+	tenants := repositories.Tenants.GetAll()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tenants); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func GetTenant(w http.ResponseWriter, r *http.Request) {
+	if !authorizeTenantAdmin(w, r) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	tenant, exists := repositories.Tenants.GetByID(id)
+	if !exists {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tenant); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// CreateTenant cria um tenant e devolve a chave de API gerada para ele
+func CreateTenant(w http.ResponseWriter, r *http.Request) {
+	if !authorizeTenantAdmin(w, r) {
+		return
+	}
+
+	var tenant entities.Tenant
+	err := json.NewDecoder(r.Body).Decode(&tenant)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	tenant.ID = 0
+
+	if msg := validateTenant(&tenant); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	apiKey, err := newAPIKey()
+	if err != nil {
+		log.Printf("Error generating API key: %v", err)
+		http.Error(w, "Error generating API key", http.StatusInternalServerError)
+		return
+	}
+
+	savedTenant, err := repositories.Tenants.Save(&tenant, apiKey)
+	if !writeTenantError(w, err) {
+		return
+	}
+	invalidateCacheTags(r, repositories.TenantsTag)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := entities.TenantAPIKeyResponse{Tenant: savedTenant, APIKey: apiKey}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	if !authorizeTenantAdmin(w, r) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se o tenant existe
+	_, exists := repositories.Tenants.GetByID(id)
+	if !exists {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	var tenant entities.Tenant
+	err = json.NewDecoder(r.Body).Decode(&tenant)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Garante que o ID corresponde ao parâmetro do caminho
+	tenant.ID = id
+
+	if msg := validateTenant(&tenant); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	updatedTenant, err := repositories.Tenants.Save(&tenant, "")
+	if !writeTenantError(w, err) {
+		return
+	}
+	invalidateCacheTags(r, repositories.TenantsTag)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updatedTenant); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// RotateTenantAPIKey gera uma nova chave de API para o tenant; a anterior deixa de valer
+func RotateTenantAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !authorizeTenantAdmin(w, r) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	apiKey, err := newAPIKey()
+	if err != nil {
+		log.Printf("Error generating API key: %v", err)
+		http.Error(w, "Error generating API key", http.StatusInternalServerError)
+		return
+	}
+
+	if !repositories.Tenants.RotateAPIKey(id, apiKey) {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+	invalidateCacheTags(r, repositories.TenantsTag)
+
+	tenant, _ := repositories.Tenants.GetByID(id)
+
+	w.Header().Set("Content-Type", "application/json")
+	response := entities.TenantAPIKeyResponse{Tenant: tenant, APIKey: apiKey}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// validateTenant confere os campos e a configuração do tenant, retornando a mensagem de
+// erro ou vazio se forem válidos
+func validateTenant(tenant *entities.Tenant) string {
+	tenant.Host = strings.ToLower(strings.TrimSpace(tenant.Host))
+
+	if !tenantSlugPattern.MatchString(tenant.Slug) {
+		return "Slug is required and must contain only lowercase letters, numbers and hyphens"
+	}
+	if tenant.Nome == "" {
+		return "Nome is required"
+	}
+
+	config := tenant.Config
+	if config.VoteCapLimit != nil && *config.VoteCapLimit < 0 {
+		return "voteCapLimit must not be negative"
+	}
+	if _, ok := tenant.VoteCapWindowDuration(); config.VoteCapWindow != "" && !ok {
+		return "voteCapWindow must be a positive duration, such as 1m"
+	}
+	for _, modo := range config.Modos {
+		switch modo {
+		case entities.ModoUnica, entities.ModoSalvar, entities.ModoPonderada:
+		default:
+			return "Invalid modo in config, expected unica, salvar or ponderada"
+		}
+	}
+
+	return ""
+}
+
+// writeTenantError escreve a resposta de erro da gravação de um tenant, retornando
+// false se houve erro
+func writeTenantError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repositories.ErrTenantDuplicado):
+		http.Error(w, "Slug or host is already used by another tenant", http.StatusConflict)
+	default:
+		log.Printf("Error saving tenant: %v", err)
+		http.Error(w, "Failed to save tenant", http.StatusInternalServerError)
+	}
+	return false
+}
+
+func newAPIKey() (string, error) {
+	key := make([]byte, apiKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
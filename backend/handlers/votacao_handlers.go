@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -11,8 +14,25 @@ import (
 	"github.com/danielfs/paredao/backend/repositories"
 )
 
+// Tamanho máximo do nome de um canal de votação
+const maxCanalLength = 32
+
+// Peso máximo de um voto em uma votação ponderada
+const maxPeso = 100
+
 func GetVotacoes(w http.ResponseWriter, r *http.Request) {
-	votacoes := repositories.GetAllVotacoes()
+	votacoes, err := repositories.GetOrLoadCache(
+		r.Context(),
+		repositories.VotacoesCacheKey,
+		entityCacheOptions(repositories.VotacoesTag),
+		func() ([]*entities.Votacao, error) {
+			return repositories.Votacoes.GetAll(tenantID(r)), nil
+		},
+	)
+	if err != nil {
+		http.Error(w, "Error getting votacoes", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(votacoes); err != nil {
@@ -29,9 +49,9 @@ func GetVotacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	votacao, exists := repositories.GetVotacaoByID(id)
-	if !exists {
-		http.Error(w, "Votacao not found", http.StatusNotFound)
+	votacao, err := getCachedVotacao(r, id)
+	if err != nil {
+		writeLookupError(w, err, "Votacao")
 		return
 	}
 
@@ -56,12 +76,29 @@ func CreateVotacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if msg := validateModo(r, &votacao); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if votacao.TemporadaID != nil {
+		if _, err := repositories.Temporadas.GetByID(tenantID(r), *votacao.TemporadaID); err != nil {
+			writeLookupError(w, err, "Temporada")
+			return
+		}
+	}
+
 	// Salva votação
-	savedVotacao := repositories.SaveVotacao(&votacao)
+	if err := repositories.Votacoes.Save(tenantID(r), &votacao); err != nil {
+		log.Printf("Error saving votacao: %v", err)
+		http.Error(w, "Failed to create votacao", http.StatusInternalServerError)
+		return
+	}
+	invalidateCacheTags(r, repositories.VotacoesTag)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(savedVotacao); err != nil {
+	if err := json.NewEncoder(w).Encode(&votacao); err != nil {
 		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 		return
 	}
@@ -76,9 +113,9 @@ func UpdateVotacao(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verifica se a votação existe
-	_, exists := repositories.GetVotacaoByID(id)
-	if !exists {
-		http.Error(w, "Votacao not found", http.StatusNotFound)
+	existing, err := repositories.Votacoes.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Votacao")
 		return
 	}
 
@@ -93,17 +130,102 @@ func UpdateVotacao(w http.ResponseWriter, r *http.Request) {
 	// Garante que o ID corresponde ao parâmetro do caminho
 	votacao.ID = id
 
+	// O encerramento só é alterado pela rota própria, e o arquivamento, pelo job de retenção
+	votacao.EncerradaEm = existing.EncerradaEm
+	votacao.ArquivadaEm = existing.ArquivadaEm
+
+	// Sem modo na requisição, mantém o modo e os pesos atuais
+	if votacao.Modo == "" {
+		votacao.Modo = existing.Modo
+		votacao.Pesos = existing.Pesos
+	}
+
+	// A origem de uma rodada derivada não muda; sem temporada na requisição, mantém a atual
+	votacao.VotacaoOrigemID = existing.VotacaoOrigemID
+	votacao.Criterio = existing.Criterio
+	if votacao.TemporadaID == nil {
+		votacao.TemporadaID = existing.TemporadaID
+	}
+
 	// Valida campos obrigatórios
 	if votacao.Descricao == "" {
 		http.Error(w, "Descricao is required", http.StatusBadRequest)
 		return
 	}
 
-	// Salva a votação atualizada
-	updatedVotacao := repositories.SaveVotacao(&votacao)
+	if msg := validateModo(r, &votacao); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if votacao.TemporadaID != nil {
+		if _, err := repositories.Temporadas.GetByID(tenantID(r), *votacao.TemporadaID); err != nil {
+			writeLookupError(w, err, "Temporada")
+			return
+		}
+	}
+
+	// Salva a votação atualizada; o modo e os pesos não mudam depois do primeiro voto
+	err = repositories.Votacoes.Save(tenantID(r), &votacao)
+	switch {
+	case errors.Is(err, repositories.ErrVotacaoComVotos):
+		http.Error(w, "Modo and pesos cannot change after the votacao has votos", http.StatusConflict)
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "Votacao not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Error updating votacao: %v", err)
+		http.Error(w, "Error updating votacao", http.StatusInternalServerError)
+		return
+	}
+	invalidateVotacaoCache(r, id)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&votacao); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func EncerrarVotacao(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	votacao, err := repositories.Votacoes.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	if votacao.Encerrada() {
+		http.Error(w, "Votacao is already closed", http.StatusConflict)
+		return
+	}
+
+	if !repositories.Votacoes.Encerrar(tenantID(r), id) {
+		http.Error(w, "Failed to close votacao", http.StatusInternalServerError)
+		return
+	}
+	invalidateVotacaoCache(r, id)
+
+	// Retorna a votação com a data de encerramento gravada
+	votacao, err = repositories.Votacoes.GetByID(tenantID(r), id)
+	if err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	// Registra o eliminado na temporada da votação
+	markEliminado(tenantID(r), votacao)
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(updatedVotacao); err != nil {
+	if err := json.NewEncoder(w).Encode(votacao); err != nil {
 		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 		return
 	}
@@ -117,11 +239,12 @@ func DeleteVotacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	success := repositories.DeleteVotacaoByID(id)
+	success := repositories.Votacoes.Delete(tenantID(r), id)
 	if !success {
 		http.Error(w, "Votacao not found", http.StatusNotFound)
 		return
 	}
+	invalidateVotacaoCache(r, id)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -135,13 +258,16 @@ func GetVotacaoParticipantes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verifica se a votação existe
-	_, exists := repositories.GetVotacaoByID(id)
-	if !exists {
-		http.Error(w, "Votacao not found", http.StatusNotFound)
+	if _, err := getCachedVotacao(r, id); err != nil {
+		writeLookupError(w, err, "Votacao")
 		return
 	}
 
-	participantes := repositories.GetParticipantesByVotacaoID(id)
+	participantes, err := getCachedVotacaoParticipantes(r, id)
+	if err != nil {
+		http.Error(w, "Error getting participantes", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(participantes); err != nil {
@@ -159,9 +285,8 @@ func AddParticipanteToVotacao(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verifica se a votação existe
-	_, exists := repositories.GetVotacaoByID(votacaoID)
-	if !exists {
-		http.Error(w, "Votacao not found", http.StatusNotFound)
+	if _, err := repositories.Votacoes.GetByID(tenantID(r), votacaoID); err != nil {
+		writeLookupError(w, err, "Votacao")
 		return
 	}
 
@@ -183,18 +308,28 @@ func AddParticipanteToVotacao(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verifica se o participante existe
-	participante, exists := repositories.GetParticipanteByID(request.ParticipanteID)
-	if !exists {
-		http.Error(w, "Participante not found", http.StatusNotFound)
+	participante, err := repositories.Participantes.GetByID(tenantID(r), request.ParticipanteID)
+	if err != nil {
+		writeLookupError(w, err, "Participante")
 		return
 	}
 
-	// Adiciona participante à votação
-	success := repositories.AddParticipanteToVotacaoInDB(request.ParticipanteID, votacaoID)
-	if !success {
+	// Adiciona participante à votação; em uma votação da temporada, só participantes ativos
+	// nela podem ir ao paredão
+	err = repositories.AddParticipanteToVotacaoInDB(tenantID(r), request.ParticipanteID, votacaoID)
+	switch {
+	case errors.Is(err, repositories.ErrParticipanteInativo):
+		http.Error(w, "Participante is not active in the temporada", http.StatusConflict)
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "Participante or votacao not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Error adding participante %d to votacao %d: %v", request.ParticipanteID, votacaoID, err)
 		http.Error(w, "Failed to add participante to votacao", http.StatusInternalServerError)
 		return
 	}
+	invalidateVotacaoCache(r, votacaoID)
 
 	// Retorna o participante que foi adicionado
 	w.Header().Set("Content-Type", "application/json")
@@ -204,3 +339,39 @@ func AddParticipanteToVotacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// validateModo confere o modo da votação e os pesos dos canais, retornando a mensagem
+// de erro ou vazio se forem válidos. Sem modo, a votação é de escolha única.
+func validateModo(r *http.Request, votacao *entities.Votacao) string {
+	if votacao.Modo == "" {
+		votacao.Modo = entities.ModoUnica
+	}
+
+	switch votacao.Modo {
+	case entities.ModoUnica, entities.ModoSalvar:
+		if len(votacao.Pesos) > 0 {
+			return "Pesos are only accepted in modo ponderada"
+		}
+	case entities.ModoPonderada:
+		if len(votacao.Pesos) == 0 {
+			return "Pesos are required in modo ponderada"
+		}
+		for canal, peso := range votacao.Pesos {
+			if canal == "" || len(canal) > maxCanalLength {
+				return "Canal names must have between 1 and 32 characters"
+			}
+			if peso < 1 || peso > maxPeso {
+				return fmt.Sprintf("Peso of canal %s must be between 1 and %d", canal, maxPeso)
+			}
+		}
+	default:
+		return "Invalid modo, expected unica, salvar or ponderada"
+	}
+
+	// O tenant pode restringir os modos aceitos nas suas votações
+	if tenant, ok := repositories.TenantFromContext(r.Context()); ok && !tenant.ModoPermitido(votacao.Modo) {
+		return fmt.Sprintf("Modo %s is not enabled for this tenant", votacao.Modo)
+	}
+
+	return ""
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// getTestResultado lê o resultado da votação encerrada pela rota de estatísticas
+func getTestResultado(t *testing.T, v *entities.Votacao) entities.ResultadoResponse {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/estatisticas/votacoes/{id}/resultado", GetVotacaoResultado).Methods("GET")
+
+	rec := serveJSON(router, "GET", fmt.Sprintf("/estatisticas/votacoes/%d/resultado", v.ID), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("resultado: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resultado entities.ResultadoResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resultado); err != nil {
+		t.Fatal(err)
+	}
+	return resultado
+}
+
+// pontosByParticipante indexa os pontos do resultado pelo participante
+func pontosByParticipante(resultado entities.ResultadoResponse) map[int64]int {
+	pontos := map[int64]int{}
+	for _, p := range resultado.Participantes {
+		pontos[p.ParticipanteID] = p.Pontos
+	}
+	return pontos
+}
+
+func TestUpdateVotacaoModoAfterFirstVoto(t *testing.T) {
+	participantes := newTestParticipantes(t, 1)
+	votacao := createTestVotacao(t, &entities.Votacao{Modo: entities.ModoUnica}, participantes...)
+	router := mux.NewRouter()
+	router.HandleFunc("/votacoes/{id}", UpdateVotacao).Methods("PUT")
+	path := fmt.Sprintf("/votacoes/%d", votacao.ID)
+	update := func(modo string) int {
+		body := fmt.Sprintf(`{"descricao": %q, "modo": %q}`, votacao.Descricao, modo)
+		return serveJSON(router, "PUT", path, body).Code
+	}
+
+	// Antes do primeiro voto, o novo modo substitui o anterior
+	if status := update(entities.ModoSalvar); status != http.StatusOK {
+		t.Fatalf("changing modo before any voto: status = %d, want 200", status)
+	}
+
+	castTestVotos(t, votacao, participantes[0], 1)
+	if status := update(entities.ModoUnica); status != http.StatusConflict {
+		t.Errorf("changing modo after the first voto: status = %d, want 409", status)
+	}
+	if status := update(entities.ModoSalvar); status != http.StatusOK {
+		t.Errorf("keeping the modo after the first voto: status = %d, want 200", status)
+	}
+}
+
+func TestVotacaoSalvarEliminatesLeastVoted(t *testing.T) {
+	participantes := newTestParticipantes(t, 3)
+	votacao := createTestVotacao(t, &entities.Votacao{Modo: entities.ModoSalvar}, participantes...)
+	castTestVotos(t, votacao, participantes[0], 3)
+	castTestVotos(t, votacao, participantes[1], 1)
+	castTestVotos(t, votacao, participantes[2], 2)
+	encerrarTestVotacao(t, votacao)
+
+	// Cada voto salva o participante: sai o que recebeu menos votos
+	resultado := getTestResultado(t, votacao)
+	if resultado.Eliminado == nil || resultado.Eliminado.ParticipanteID != participantes[1].ID {
+		t.Errorf("eliminado = %+v, want participante %d", resultado.Eliminado, participantes[1].ID)
+	}
+}
+
+func TestVotacaoPonderadaSumsPesos(t *testing.T) {
+	previous := canalTokenSecret
+	canalTokenSecret = []byte("segredo-de-teste")
+	t.Cleanup(func() { canalTokenSecret = previous })
+
+	participantes := newTestParticipantes(t, 2)
+	votacao := createTestVotacao(t, &entities.Votacao{
+		Modo:  entities.ModoPonderada,
+		Pesos: map[string]int{entities.CanalAnonimo: 1, "app": 3},
+	}, participantes...)
+	app := signCanalToken("app", time.Now().Add(time.Hour))
+
+	// Dois votos anônimos valem menos que um voto do aplicativo
+	votos := []struct {
+		participante *entities.Participante
+		token        string
+	}{
+		{participantes[0], ""},
+		{participantes[0], ""},
+		{participantes[1], app},
+	}
+	for i, v := range votos {
+		rec := postVoto(votoRequest{
+			remoteAddr: fmt.Sprintf("198.51.100.%d:1000", 40+i),
+			headers:    map[string]string{"X-Canal-Token": v.token},
+			body:       votoBody(votacao, v.participante),
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("voto %d: status = %d, want 201: %s", i, rec.Code, rec.Body)
+		}
+	}
+	encerrarTestVotacao(t, votacao)
+
+	resultado := getTestResultado(t, votacao)
+	if resultado.Total != 3 || resultado.Pontos != 5 {
+		t.Errorf("total = %d and pontos = %d, want 3 and 5", resultado.Total, resultado.Pontos)
+	}
+	pontos := pontosByParticipante(resultado)
+	if pontos[participantes[0].ID] != 2 || pontos[participantes[1].ID] != 3 {
+		t.Errorf("pontos = %v, want 2 and 3", pontos)
+	}
+	if resultado.Eliminado == nil || resultado.Eliminado.ParticipanteID != participantes[1].ID {
+		t.Errorf("eliminado = %+v, want participante %d with more pontos", resultado.Eliminado, participantes[1].ID)
+	}
+}
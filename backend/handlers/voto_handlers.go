@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -11,8 +16,17 @@ import (
 	"github.com/danielfs/paredao/backend/repositories"
 )
 
+// Tamanho máximo do corpo de uma requisição de voto
+const maxVotoBodySize = 1 << 20
+
+// Tamanho máximo do identificador de dispositivo gravado no voto
+const maxDeviceIDLength = 128
+
+// Escopo das chaves de idempotência da criação de votos
+const idempotencyScopeVotos = "votos"
+
 func GetVotos(w http.ResponseWriter, r *http.Request) {
-	votos := repositories.GetAllVotos()
+	votos := repositories.GetAllVotos(tenantID(r))
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(votos); err != nil {
@@ -21,6 +35,39 @@ func GetVotos(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetVotacaoVotos lista os votos da votação com a sua origem (hashes do IP e do user-agent e
+// identificador do dispositivo), que as rotas públicas não expõem. Rota administrativa.
+func GetVotacaoVotos(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Verifica se a votação existe
+	if _, err := getCachedVotacao(r, id); err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	votos := repositories.GetVotosByVotacaoID(tenantID(r), id)
+	response := make([]entities.VotoOrigemResponse, len(votos))
+	for i, v := range votos {
+		response[i] = entities.VotoOrigemResponse{
+			Voto:          v,
+			IPHash:        v.IPHash,
+			UserAgentHash: v.UserAgentHash,
+			DeviceID:      v.DeviceID,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
 func GetVoto(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
@@ -36,7 +83,7 @@ func GetVoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	voto, exists := repositories.GetVotoByIDs(participanteID, votacaoID)
+	voto, exists := repositories.GetVotoByIDs(tenantID(r), participanteID, votacaoID)
 	if !exists {
 		http.Error(w, "Voto not found", http.StatusNotFound)
 		return
@@ -50,12 +97,22 @@ func GetVoto(w http.ResponseWriter, r *http.Request) {
 }
 
 func CreateVoto(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	var votoRequest struct {
-		ParticipanteID int64 `json:"participanteId"`
-		VotacaoID      int64 `json:"votacaoId"`
+		ParticipanteID int64  `json:"participanteId"`
+		VotacaoID      int64  `json:"votacaoId"`
+		Canal          string `json:"canal"`
 	}
 
-	err := json.NewDecoder(r.Body).Decode(&votoRequest)
+	// O corpo é lido inteiro para servir de impressão digital da requisição idempotente
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxVotoBodySize))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = json.Unmarshal(body, &votoRequest)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
@@ -67,33 +124,146 @@ func CreateVoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Uma repetição com a mesma Idempotency-Key devolve a resposta original sem votar de novo.
+	// A chave vale só para o cliente que a enviou: o dispositivo, se informado, que não muda
+	// quando o celular troca de rede entre as tentativas, ou o IP.
+	clients := clientIDs(r)
+	idempotencyClient := clients[len(clients)-1]
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	fingerprint := hashIdentifier(string(body))
+	if idempotencyKey != "" {
+		record, err := repositories.ReserveIdempotencyKey(
+			ctx, idempotencyScopeVotos, idempotencyClient, idempotencyKey, fingerprint,
+		)
+		switch {
+		case errors.Is(err, repositories.ErrIdempotencyInProgress):
+			http.Error(w, "A request with this Idempotency-Key is in progress", http.StatusConflict)
+			return
+		case errors.Is(err, repositories.ErrIdempotencyMismatch):
+			http.Error(w, "Idempotency-Key was used with a different request", http.StatusUnprocessableEntity)
+			return
+		case err != nil:
+			// Sem o Redis a requisição segue sem garantia de idempotência
+			log.Printf("Idempotency unavailable: %v", err)
+			idempotencyKey = ""
+		case record != nil:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.Status)
+			w.Write(record.Body)
+			return
+		}
+	}
+
+	// Libera a chave se a requisição não terminar com sucesso, para que a repetição seja processada
+	completed := false
+	defer func() {
+		if idempotencyKey != "" && !completed {
+			repositories.ReleaseIdempotencyKey(ctx, idempotencyScopeVotos, idempotencyClient, idempotencyKey)
+		}
+	}()
+
 	// Verifica se o participante existe
-	participante, exists := repositories.GetParticipanteByID(votoRequest.ParticipanteID)
-	if !exists {
-		http.Error(w, "Participante not found", http.StatusNotFound)
+	participante, err := repositories.Participantes.GetByID(tenantID(r), votoRequest.ParticipanteID)
+	if err != nil {
+		writeLookupError(w, err, "Participante")
 		return
 	}
 
 	// Verifica se a votação existe
-	votacao, exists := repositories.GetVotacaoByID(votoRequest.VotacaoID)
-	if !exists {
-		http.Error(w, "Votacao not found", http.StatusNotFound)
+	votacao, err := repositories.Votacoes.GetByID(tenantID(r), votoRequest.VotacaoID)
+	if err != nil {
+		writeLookupError(w, err, "Votacao")
+		return
+	}
+
+	if votacao.Encerrada() {
+		http.Error(w, "Votacao is closed", http.StatusConflict)
+		return
+	}
+
+	// O canal vem da credencial do votante; o campo canal do corpo só é aceito se for o mesmo
+	canal, err := canalFromRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid or expired canal token", http.StatusUnauthorized)
 		return
 	}
+	if votoRequest.Canal != "" && votoRequest.Canal != canal {
+		http.Error(w, "Canal does not match the authenticated client", http.StatusForbidden)
+		return
+	}
+
+	// Na votação ponderada o canal define quantos pontos o voto vale
+	peso, valid := votacao.Peso(canal)
+	if !valid {
+		http.Error(w, "Canal is not accepted by this votacao", http.StatusBadRequest)
+		return
+	}
+	if votacao.Modo != entities.ModoPonderada {
+		canal = ""
+	}
+
+	// Aplica o limite de votos por cliente nesta votação
+	allowed, retryAfter := repositories.AllowVote(ctx, votacao.ID, clients)
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		http.Error(w, "Vote limit reached, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	// Um voto que não chega a ser gravado não conta para o limite, mesmo que o cliente desconecte
+	saved := false
+	defer func() {
+		if !saved {
+			repositories.RefundVote(context.WithoutCancel(ctx), votacao.ID, clients)
+		}
+	}()
 
 	// Cria voto
 	voto := &entities.Voto{
 		Participante: participante,
 		Votacao:      votacao,
+		// Origem do voto, usada pela análise de fraude
+		IPHash:        hashIdentifier("ip:" + clientIP(r)),
+		UserAgentHash: hashIdentifier("ua:" + r.UserAgent()),
+		DeviceID:      truncate(deviceID(r), maxDeviceIDLength),
+		Canal:         canal,
+		Peso:          peso,
 	}
 
 	// Salva voto
-	savedVoto := repositories.SaveVoto(voto)
+	// A gravação confere de novo a votação, que pode ter sido encerrada desde a leitura
+	savedVoto, err := repositories.SaveVoto(tenantID(r), voto)
+	switch {
+	case errors.Is(err, repositories.ErrVotacaoEncerrada):
+		http.Error(w, "Votacao is closed", http.StatusConflict)
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "Participante or votacao not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Error saving voto: %v", err)
+		http.Error(w, "Error saving voto", http.StatusInternalServerError)
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(savedVoto); err != nil {
+	saved = true
+
+	response, err := json.Marshal(savedVoto)
+	if err != nil {
 		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 		return
 	}
+	response = append(response, '\n')
+
+	if idempotencyKey != "" {
+		repositories.CompleteIdempotencyKey(
+			ctx, idempotencyScopeVotos, idempotencyClient, idempotencyKey, fingerprint, http.StatusCreated, response,
+		)
+		completed = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(response)
 }
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// votoRequest descreve um POST /votos de um cliente
+type votoRequest struct {
+	remoteAddr string
+	headers    map[string]string
+	body       string
+}
+
+func postVoto(req votoRequest) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc("/votos", CreateVoto).Methods("POST")
+
+	r := httptest.NewRequest("POST", "/votos", strings.NewReader(req.body))
+	r.Header.Set("Content-Type", "application/json")
+	if req.remoteAddr != "" {
+		r.RemoteAddr = req.remoteAddr
+	}
+	for name, value := range req.headers {
+		r.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	return rec
+}
+
+func votoBody(v *entities.Votacao, p *entities.Participante) string {
+	return fmt.Sprintf(`{"participanteId": %d, "votacaoId": %d}`, p.ID, v.ID)
+}
+
+func countVotos(t *testing.T, v *entities.Votacao) int {
+	t.Helper()
+	return len(repositories.GetVotosByVotacaoID(entities.DefaultTenantID, v.ID))
+}
+
+func TestCreateVotoIdempotency(t *testing.T) {
+	participantes := newTestParticipantes(t, 2)
+	votacao := createTestVotacao(t, &entities.Votacao{}, participantes...)
+	key := fmt.Sprintf("chave-%d", time.Now().UnixNano())
+	body := votoBody(votacao, participantes[0])
+	first := votoRequest{remoteAddr: "198.51.100.1:1000", headers: map[string]string{"Idempotency-Key": key}, body: body}
+
+	if rec := postVoto(first); rec.Code != http.StatusCreated {
+		t.Fatalf("first voto: status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+
+	// A repetição do mesmo cliente devolve a resposta gravada sem votar de novo
+	rec := postVoto(first)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay: status = %d, Idempotent-Replayed = %q; want 201 and true",
+			rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+	if got := countVotos(t, votacao); got != 1 {
+		t.Errorf("after the replay the votacao has %d votos, want 1", got)
+	}
+
+	// Outro corpo com a mesma chave é recusado
+	changed := first
+	changed.body = votoBody(votacao, participantes[1])
+	if rec := postVoto(changed); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body: status = %d, want 422", rec.Code)
+	}
+
+	// Outro cliente com a mesma chave e o mesmo corpo tem o próprio voto contado
+	other := first
+	other.remoteAddr = "198.51.100.2:1000"
+	rec = postVoto(other)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("other client: status = %d, Idempotent-Replayed = %q; want 201 and a new voto",
+			rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+	device := first
+	device.headers = map[string]string{"Idempotency-Key": key, "X-Device-ID": "aparelho-1"}
+	if rec := postVoto(device); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("other device on the same IP: status = %d, want 201 and a new voto", rec.Code)
+	}
+	if got := countVotos(t, votacao); got != 3 {
+		t.Errorf("the votacao has %d votos, want 3", got)
+	}
+}
+
+func TestCreateVotoIdempotencyKeyInProgress(t *testing.T) {
+	participantes := newTestParticipantes(t, 1)
+	votacao := createTestVotacao(t, &entities.Votacao{}, participantes...)
+	key := fmt.Sprintf("chave-%d", time.Now().UnixNano())
+	body := votoBody(votacao, participantes[0])
+
+	// Uma requisição do mesmo cliente ainda em andamento reservou a chave
+	client := hashIdentifier("ip:198.51.100.3")
+	_, err := repositories.ReserveIdempotencyKey(
+		context.Background(), idempotencyScopeVotos, client, key, hashIdentifier(body),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := postVoto(votoRequest{
+		remoteAddr: "198.51.100.3:1000", headers: map[string]string{"Idempotency-Key": key}, body: body,
+	})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", rec.Code)
+	}
+	if got := countVotos(t, votacao); got != 0 {
+		t.Errorf("the votacao has %d votos, want none", got)
+	}
+}
+
+// signCanalToken emite a credencial de canal como o serviço de login, com o segredo dos testes
+func signCanalToken(canal string, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%s.%d", canal, expiresAt.Unix())
+	mac := hmac.New(sha256.New, canalTokenSecret)
+	mac.Write([]byte(payload))
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestCreateVotoCanalToken(t *testing.T) {
+	previous := canalTokenSecret
+	canalTokenSecret = []byte("segredo-de-teste")
+	t.Cleanup(func() { canalTokenSecret = previous })
+
+	participantes := newTestParticipantes(t, 1)
+	votacao := createTestVotacao(t, &entities.Votacao{
+		Modo:  entities.ModoPonderada,
+		Pesos: map[string]int{entities.CanalAnonimo: 1, "app": 3, "app.premium": 5},
+	}, participantes...)
+	valid := signCanalToken("app", time.Now().Add(time.Hour))
+	tampered := []byte(valid)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name   string
+		token  string
+		canal  string
+		status int
+		peso   int
+	}{
+		{"without a token", "", "", http.StatusCreated, 1},
+		{"valid token", valid, "", http.StatusCreated, 3},
+		{"canal with dots", signCanalToken("app.premium", time.Now().Add(time.Hour)), "", http.StatusCreated, 5},
+		{"body canal matching the token", valid, "app", http.StatusCreated, 3},
+		{"body canal without a token", "", "app", http.StatusForbidden, 0},
+		{"tampered canal", "premium" + strings.TrimPrefix(valid, "app"), "", http.StatusUnauthorized, 0},
+		{"tampered signature", string(tampered), "", http.StatusUnauthorized, 0},
+		{"expired", signCanalToken("app", time.Now().Add(-time.Minute)), "", http.StatusUnauthorized, 0},
+		{"malformed", "app", "", http.StatusUnauthorized, 0},
+		{"canal without peso", signCanalToken("tv", time.Now().Add(time.Hour)), "", http.StatusBadRequest, 0},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"participanteId": %d, "votacaoId": %d, "canal": %q}`,
+				participantes[0].ID, votacao.ID, tt.canal)
+			rec := postVoto(votoRequest{
+				remoteAddr: fmt.Sprintf("198.51.100.%d:1000", 10+i),
+				headers:    map[string]string{"X-Canal-Token": tt.token},
+				body:       body,
+			})
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusCreated {
+				return
+			}
+			var voto entities.Voto
+			if err := json.Unmarshal(rec.Body.Bytes(), &voto); err != nil {
+				t.Fatal(err)
+			}
+			if voto.Peso != tt.peso {
+				t.Errorf("peso = %d, want %d", voto.Peso, tt.peso)
+			}
+		})
+	}
+}
+
+func TestCreateVotoWithoutCanalSecret(t *testing.T) {
+	previous := canalTokenSecret
+	canalTokenSecret = []byte("segredo-de-teste")
+	token := signCanalToken("app", time.Now().Add(time.Hour))
+	canalTokenSecret = nil
+	t.Cleanup(func() { canalTokenSecret = previous })
+
+	participantes := newTestParticipantes(t, 1)
+	votacao := createTestVotacao(t, &entities.Votacao{}, participantes...)
+
+	// Sem o segredo nenhuma credencial pode ser conferida
+	rec := postVoto(votoRequest{
+		remoteAddr: "198.51.100.30:1000",
+		headers:    map[string]string{"X-Canal-Token": token},
+		body:       votoBody(votacao, participantes[0]),
+	})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", rec.Code)
+	}
+}
+
+// votacaoEncerradaAposLeitura simula a votação encerrada entre a leitura do handler e a
+// gravação do voto: a primeira leitura ainda a encontra aberta
+type votacaoEncerradaAposLeitura struct {
+	repositories.VotacaoStore
+	lida bool
+}
+
+func (s *votacaoEncerradaAposLeitura) GetByID(tenantID, id int64) (*entities.Votacao, error) {
+	votacao, err := s.VotacaoStore.GetByID(tenantID, id)
+	if err == nil && !s.lida {
+		s.lida = true
+		votacao.EncerradaEm = nil
+	}
+	return votacao, err
+}
+
+func TestCreateVotoRefundsTheCapWhenNotSaved(t *testing.T) {
+	previous := repositories.VoteCapConfig
+	repositories.VoteCapConfig = repositories.VoteCap{Limit: 1, Window: time.Minute}
+	t.Cleanup(func() { repositories.VoteCapConfig = previous })
+
+	participantes := newTestParticipantes(t, 1)
+	votacao := createTestVotacao(t, &entities.Votacao{}, participantes...)
+	repositories.Votacoes.Encerrar(entities.DefaultTenantID, votacao.ID)
+
+	// O voto passa pelo limite, mas a gravação encontra a votação encerrada
+	store := repositories.Votacoes
+	repositories.Votacoes = &votacaoEncerradaAposLeitura{VotacaoStore: store}
+	rec := postVoto(votoRequest{remoteAddr: "198.51.100.60:1000", body: votoBody(votacao, participantes[0])})
+	repositories.Votacoes = store
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("voto in a votacao closed before saving: status = %d, want 409: %s", rec.Code, rec.Body)
+	}
+	if got := countVotos(t, votacao); got != 0 {
+		t.Fatalf("the closed votacao has %d votos, want none", got)
+	}
+
+	// O voto não gravado foi devolvido: o único voto da janela continua disponível
+	clients := []string{hashIdentifier("ip:198.51.100.60")}
+	if allowed, _ := repositories.AllowVote(context.Background(), votacao.ID, clients); !allowed {
+		t.Fatal("the cap slot of the unsaved voto was not refunded")
+	}
+	if allowed, _ := repositories.AllowVote(context.Background(), votacao.ID, clients); allowed {
+		t.Error("a second voto in the window was allowed, want the cap to apply")
+	}
+}
@@ -0,0 +1,129 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+
+	// Registra os decodificadores aceitos
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Número máximo de pixels de uma imagem enviada, para que um arquivo pequeno não
+// consuma memória e processamento demais ao ser decodificado e reduzido. Cobre as fotos
+// de câmeras de celular, de até 16 megapixels.
+const MaxPixels = 16_800_000
+
+// Qualidade das imagens JPEG geradas
+const JPEGQuality = 85
+
+var (
+	// ErrUnsupportedFormat indica que o conteúdo não é uma imagem JPEG, PNG ou GIF
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	// ErrTooManyPixels indica que as dimensões da imagem passam de MaxPixels
+	ErrTooManyPixels = errors.New("image dimensions too large")
+)
+
+// Tipos aceitos, identificados pelo conteúdo e não pelo nome ou cabeçalho do arquivo
+var supportedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Decode confere o tipo e as dimensões da imagem antes de decodificá-la. De um GIF
+// animado, apenas o primeiro quadro é usado.
+func Decode(data []byte) (image.Image, error) {
+	if !supportedTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedFormat
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	return img, nil
+}
+
+// Fit reduz a imagem para que o maior lado tenha no máximo size pixels, mantendo a
+// proporção. Imagens menores são mantidas no tamanho original.
+func Fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+	return resize(img, width, height)
+}
+
+// resize calcula cada pixel de destino como a média da área correspondente na origem,
+// o que evita o serrilhado de uma amostragem simples ao reduzir fotos grandes
+func resize(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcHeight/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcWidth/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcWidth/width)
+
+			var r, g, b, a uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// Os valores já vêm pré-multiplicados pelo alfa, em 16 bits
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+				}
+			}
+
+			n := uint64((y1 - y0) * (x1 - x0))
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
+
+// EncodeJPEG grava a imagem em JPEG sobre um fundo branco, já que o formato não tem
+// transparência. Metadados da imagem original, como EXIF, não são copiados.
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
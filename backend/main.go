@@ -2,29 +2,67 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	// Embute a base de fusos horários, ausente na imagem alpine
+	_ "time/tzdata"
 
 	"github.com/gorilla/mux"
 
 	"github.com/danielfs/paredao/backend/handlers"
+	"github.com/danielfs/paredao/backend/middlewares"
 	"github.com/danielfs/paredao/backend/repositories"
+	"github.com/danielfs/paredao/backend/workers"
 )
 
 func main() {
+	rebuildRollups := flag.Bool("rebuild-rollups", false, "Recalcula as tabelas de agregação a partir dos votos e sai")
+	votacaoID := flag.Int64("votacao", 0, "Restringe -rebuild-rollups a uma votação")
+	dryRun := flag.Bool("dry-run", false, "Com -rebuild-rollups, apenas relata as divergências")
+	dev := flag.Bool("dev", false,
+		"Usa o SQLite embutido e o cache em memória, sem MySQL nem Redis, com dados de demonstração")
+	flag.Parse()
+
 	// Inicializa conexão com o banco de dados
-	repositories.InitDB()
+	if *dev {
+		repositories.InitDevDB()
+	} else {
+		repositories.InitDB()
+	}
 	defer repositories.CloseDB()
 
-	// Inicializa cliente Redis
-	redisHost := os.Getenv("REDIS_HOST")
-	redisPort := os.Getenv("REDIS_PORT")
-	repositories.InitRedis(redisHost, redisPort)
-	defer repositories.CloseRedis()
+	if *rebuildRollups {
+		runRebuildRollups(*votacaoID, *dryRun)
+		return
+	}
+
+	// Inicializa o cache em memória que fica na frente do Redis
+	repositories.InitLocalCache()
+	repositories.InitCacheCodec()
+	repositories.InitIdempotency()
+	repositories.InitVoteCap()
+	repositories.InitBlobStore()
+
+	// Inicializa cliente Redis; no modo de desenvolvimento, o cache fica só na memória
+	if !*dev {
+		redisHost := os.Getenv("REDIS_HOST")
+		redisPort := os.Getenv("REDIS_PORT")
+		repositories.InitRedis(redisHost, redisPort)
+		defer repositories.CloseRedis()
+	}
+
+	// Inicia a agregação dos votos nas tabelas de estatísticas, com as recontagens depois
+	// das invalidações, a análise de fraude e a retenção dos votos
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	workers.StartRollupAggregator(workersCtx, handlers.RecountVotacao)
+	workers.StartFraudAnalyzer(workersCtx)
+	workers.StartRetentionJob(workersCtx)
 
 	r := mux.NewRouter()
 
@@ -33,7 +71,12 @@ func main() {
 	r.HandleFunc("/participantes/{id}", handlers.GetParticipante).Methods("GET")
 	r.HandleFunc("/participantes", handlers.CreateParticipante).Methods("POST")
 	r.HandleFunc("/participantes/{id}", handlers.UpdateParticipante).Methods("PUT")
+	r.HandleFunc("/participantes/{id}", handlers.PatchParticipante).Methods("PATCH")
 	r.HandleFunc("/participantes/{id}", handlers.DeleteParticipante).Methods("DELETE")
+	r.HandleFunc("/participantes/{id}/foto", handlers.UploadParticipanteFoto).Methods("POST")
+
+	// Fotos enviadas, servidas a partir do armazenamento configurado
+	r.PathPrefix("/fotos/").HandlerFunc(handlers.GetFoto).Methods("GET")
 
 	// Rotas de Votação
 	r.HandleFunc("/votacoes", handlers.GetVotacoes).Methods("GET")
@@ -41,8 +84,30 @@ func main() {
 	r.HandleFunc("/votacoes", handlers.CreateVotacao).Methods("POST")
 	r.HandleFunc("/votacoes/{id}", handlers.UpdateVotacao).Methods("PUT")
 	r.HandleFunc("/votacoes/{id}", handlers.DeleteVotacao).Methods("DELETE")
+	r.HandleFunc("/votacoes/{id}/encerrar", handlers.EncerrarVotacao).Methods("POST")
+	r.HandleFunc("/votacoes/{id}/rodadas", handlers.DeriveVotacao).Methods("POST")
 	r.HandleFunc("/votacoes/{id}/participantes", handlers.GetVotacaoParticipantes).Methods("GET")
 	r.HandleFunc("/votacoes/{id}/participantes", handlers.AddParticipanteToVotacao).Methods("POST")
+	r.HandleFunc("/votacoes/{id}/votos", handlers.GetVotacaoVotos).Methods("GET")
+	r.HandleFunc("/votacoes/{id}/alertas", handlers.GetVotacaoAlertas).Methods("GET")
+	r.HandleFunc("/votacoes/{id}/alertas/{alertaId}/invalidar", handlers.InvalidarAlerta).Methods("POST")
+	r.HandleFunc("/votacoes/{id}/invalidacoes", handlers.GetVotacaoInvalidacoes).Methods("GET")
+	r.HandleFunc("/votacoes/{id}/invalidacoes", handlers.CreateInvalidacao).Methods("POST")
+	r.HandleFunc("/votacoes/{id}/invalidacoes/{invalidacaoId}/reverter", handlers.RevertInvalidacao).Methods("POST")
+
+	// Rotas de Temporada
+	r.HandleFunc("/temporadas", handlers.GetTemporadas).Methods("GET")
+	r.HandleFunc("/temporadas/{id}", handlers.GetTemporada).Methods("GET")
+	r.HandleFunc("/temporadas", handlers.CreateTemporada).Methods("POST")
+	r.HandleFunc("/temporadas/{id}", handlers.UpdateTemporada).Methods("PUT")
+	r.HandleFunc("/temporadas/{id}", handlers.DeleteTemporada).Methods("DELETE")
+	r.HandleFunc("/temporadas/{id}/participantes", handlers.GetTemporadaParticipantes).Methods("GET")
+	r.HandleFunc("/temporadas/{id}/participantes", handlers.AddParticipanteToTemporada).Methods("POST")
+	r.HandleFunc("/temporadas/{id}/participantes/{participanteId}", handlers.UpdateTemporadaParticipante).Methods("PUT")
+	r.HandleFunc("/temporadas/{id}/paredoes", handlers.GetTemporadaParedoes).Methods("GET")
+
+	// Rota de importação de participantes e votações
+	r.HandleFunc("/import", handlers.Importar).Methods("POST")
 
 	// Rotas de Voto
 	r.HandleFunc("/votos", handlers.GetVotos).Methods("GET")
@@ -53,33 +118,33 @@ func main() {
 	r.HandleFunc("/estatisticas/votacoes/{id}/total", handlers.GetVotacaoTotal).Methods("GET")
 	r.HandleFunc("/estatisticas/votacoes/{id}/participantes", handlers.GetVotacaoTotalByParticipante).Methods("GET")
 	r.HandleFunc("/estatisticas/votacoes/{id}/hourly", handlers.GetVotacaoTotalByHour).Methods("GET")
+	r.HandleFunc("/estatisticas/votacoes/{id}/serie", handlers.GetVotacaoTimeSeries).Methods("GET")
+	r.HandleFunc("/estatisticas/votacoes/{id}/momentum", handlers.GetVotacaoMomentum).Methods("GET")
+	r.HandleFunc("/estatisticas/votacoes/{id}/resultado", handlers.GetVotacaoResultado).Methods("GET")
+	r.HandleFunc("/estatisticas/votacoes/{id}/cadeia", handlers.GetVotacaoCadeia).Methods("GET")
+	r.HandleFunc("/estatisticas/votacoes/{id}/eventos", handlers.GetVotacaoEventos).Methods("GET")
+	r.HandleFunc("/estatisticas/cache", handlers.GetCacheMetrics).Methods("GET")
+
+	// Rotas de administração de tenants, fora do escopo de qualquer tenant
+	r.HandleFunc("/admin/tenants", handlers.GetTenants).Methods("GET")
+	r.HandleFunc("/admin/tenants/{id}", handlers.GetTenant).Methods("GET")
+	r.HandleFunc("/admin/tenants", handlers.CreateTenant).Methods("POST")
+	r.HandleFunc("/admin/tenants/{id}", handlers.UpdateTenant).Methods("PUT")
+	r.HandleFunc("/admin/tenants/{id}/chave", handlers.RotateTenantAPIKey).Methods("POST")
 
 	// Configura encerramento gracioso
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
-	// Adiciona middleware CORS
-	corsMiddleware := func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Define cabeçalhos CORS
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, Authorization")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-			// Trata requisições preflight
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-
-			// Chama o próximo handler
-			next.ServeHTTP(w, r)
-		})
-	}
-
-	// Aplica middleware CORS
-	handler := corsMiddleware(r)
+	// Aplica middleware CORS: leituras e votos são públicos, o restante é administrativo
+	cors := middlewares.CORS(
+		middlewares.CORSRoute{Match: middlewares.IsPublicRoute, Policy: middlewares.PublicCORSPolicy()},
+		middlewares.CORSRoute{Match: middlewares.IsAdminRoute, Policy: middlewares.AdminCORSPolicy()},
+	)
+	// Resolve o tenant de cada requisição, exceto na administração de tenants. As rotas
+	// administrativas exigem a chave de API do tenant.
+	tenant := middlewares.Tenant(middlewares.IsTenantAdminRoute, middlewares.IsPublicRoute)
+	handler := cors(tenant(r))
 
 	// Cria um servidor com timeouts
 	server := &http.Server{
@@ -114,3 +179,26 @@ func main() {
 
 	log.Println("Server exited properly")
 }
+
+// runRebuildRollups recalcula votos_por_minuto e relata as divergências encontradas
+func runRebuildRollups(votacaoID int64, dryRun bool) {
+	discrepancies, err := repositories.RebuildRollups(votacaoID, !dryRun)
+	if err != nil {
+		log.Printf("Error rebuilding rollups: %v", err)
+		return
+	}
+
+	for _, d := range discrepancies {
+		log.Printf("Discrepancy: votacao %d, participante %d, minuto %s: rollup %d (%d pontos), votos %d (%d pontos)",
+			d.VotacaoID, d.ParticipanteID, d.Minuto.Format(time.DateTime), d.Rollup, d.RollupPontos, d.Votos, d.Pontos)
+	}
+
+	switch {
+	case len(discrepancies) == 0:
+		log.Println("Rollups are consistent with votos")
+	case dryRun:
+		log.Printf("Found %d discrepancies (dry run, nothing changed)", len(discrepancies))
+	default:
+		log.Printf("Fixed %d discrepancies", len(discrepancies))
+	}
+}
@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSPolicy define quais origens, métodos e cabeçalhos são aceitos para um grupo de rotas
+type CORSPolicy struct {
+	// AllowedOrigins aceita origens exatas ("https://paredao.com") e
+	// subdomínios curinga ("https://*.paredao.com")
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders lista os cabeçalhos de resposta que o navegador pode ler
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge é o tempo, em segundos, que o navegador pode manter o preflight em cache
+	MaxAge int
+}
+
+// CORSRoute associa uma política a um grupo de rotas
+type CORSRoute struct {
+	// Match recebe o caminho e o método efetivo (no preflight, o valor de
+	// Access-Control-Request-Method) e indica se a política se aplica
+	Match  func(path, method string) bool
+	Policy *CORSPolicy
+}
+
+// CORS aplica a primeira política cujo grupo de rotas corresponde à requisição.
+// Requisições que não correspondem a nenhum grupo seguem sem cabeçalhos CORS.
+func CORS(routes ...CORSRoute) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			method := r.Method
+			if preflight {
+				method = r.Header.Get("Access-Control-Request-Method")
+			}
+
+			var policy *CORSPolicy
+			for _, route := range routes {
+				if route.Match(r.URL.Path, method) {
+					policy = route.Policy
+					break
+				}
+			}
+
+			// A resposta depende da origem, então caches intermediários precisam diferenciá-la
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			origin := r.Header.Get("Origin")
+			allowed := policy != nil && origin != "" && policy.originAllowed(origin)
+
+			if preflight {
+				if allowed && policy.methodAllowed(method) {
+					policy.writePreflightHeaders(w, origin)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if allowed {
+				policy.writeOriginHeaders(w, origin)
+				if len(policy.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (p *CORSPolicy) writeOriginHeaders(w http.ResponseWriter, origin string) {
+	// Nunca usa "*" para que a política seja compatível com credenciais
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if p.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (p *CORSPolicy) writePreflightHeaders(w http.ResponseWriter, origin string) {
+	p.writeOriginHeaders(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(p.AllowedHeaders, ", "))
+	if p.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(p.MaxAge))
+	}
+}
+
+func (p *CORSPolicy) originAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == origin {
+			return true
+		}
+
+		// Curinga de subdomínio: "https://*.paredao.com" aceita "https://a.paredao.com",
+		// mas não "https://paredao.com" nem "https://a.paredao.com.evil.com"
+		scheme, host, found := strings.Cut(allowed, "*.")
+		if !found || !strings.HasPrefix(origin, scheme) {
+			continue
+		}
+		sub, ok := strings.CutSuffix(strings.TrimPrefix(origin, scheme), "."+host)
+		if ok && sub != "" && !strings.ContainsAny(sub, "/:") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) methodAllowed(method string) bool {
+	for _, m := range p.AllowedMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
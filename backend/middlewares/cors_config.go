@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Origem padrão do frontend servido pelo docker-compose
+const defaultCORSOrigins = "http://localhost:3000"
+
+// Tempo padrão, em segundos, de cache do preflight
+const defaultCORSMaxAge = 600
+
+// PublicCORSPolicy é a política das rotas de votação e estatísticas, lidas por
+// qualquer página pública. Origens configuráveis por CORS_PUBLIC_ORIGINS.
+func PublicCORSPolicy() *CORSPolicy {
+	return &CORSPolicy{
+		AllowedOrigins: originsFromEnv("CORS_PUBLIC_ORIGINS"),
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{
+			"Accept", "Content-Type", "Idempotency-Key", "X-Canal-Token", "X-Device-ID", "X-Requested-With",
+		},
+		ExposedHeaders: []string{"Idempotent-Replayed", "Retry-After"},
+		MaxAge:         maxAgeFromEnv(),
+	}
+}
+
+// AdminCORSPolicy é a política das rotas de administração, que aceitam
+// credenciais. Origens configuráveis por CORS_ADMIN_ORIGINS.
+func AdminCORSPolicy() *CORSPolicy {
+	return &CORSPolicy{
+		AllowedOrigins: originsFromEnv("CORS_ADMIN_ORIGINS"),
+		AllowedMethods: []string{
+			http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", APIKeyHeader, "X-Requested-With"},
+		AllowCredentials: true,
+		MaxAge:           maxAgeFromEnv(),
+	}
+}
+
+// originsFromEnv lê uma lista de origens separadas por vírgula
+func originsFromEnv(key string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		value = defaultCORSOrigins
+	}
+
+	origins := []string{}
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	return origins
+}
+
+func maxAgeFromEnv() int {
+	maxAge, err := strconv.Atoi(os.Getenv("CORS_MAX_AGE"))
+	if err != nil {
+		return defaultCORSMaxAge
+	}
+	return maxAge
+}
@@ -0,0 +1,244 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// corsHandler monta o middleware com as rotas de main.go: leituras e votos são públicos,
+// o resto é administração
+func corsHandler(t *testing.T) http.Handler {
+	t.Helper()
+	t.Setenv("CORS_PUBLIC_ORIGINS", "https://paredao.com, https://*.paredao.com")
+	t.Setenv("CORS_ADMIN_ORIGINS", "https://admin.paredao.com")
+	t.Setenv("CORS_MAX_AGE", "300")
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return CORS(
+		CORSRoute{Match: IsPublicRoute, Policy: PublicCORSPolicy()},
+		CORSRoute{Match: IsAdminRoute, Policy: AdminCORSPolicy()},
+	)(next)
+}
+
+func serveCORS(
+	handler http.Handler, method, path, origin string, headers map[string]string,
+) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func preflight(method, headers string) map[string]string {
+	return map[string]string{"Access-Control-Request-Method": method, "Access-Control-Request-Headers": headers}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	handler := corsHandler(t)
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{"exact origin", "https://paredao.com", true},
+		{"exact origin, other case", "https://PAREDAO.com", true},
+		{"wildcard subdomain", "https://votos.paredao.com", true},
+		{"nested subdomain", "https://a.b.paredao.com", true},
+		{"unknown origin", "https://evil.com", false},
+		{"suffix attack", "https://votos.paredao.com.evil.com", false},
+		{"other scheme", "http://votos.paredao.com", false},
+		{"port in subdomain", "https://votos.paredao.com:8443", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCORS(handler, http.MethodGet, "/votacoes", tt.origin, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: the request must reach the handler", rec.Code)
+			}
+
+			got := rec.Header().Get("Access-Control-Allow-Origin")
+			if tt.allowed && got != tt.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.origin)
+			}
+			if !tt.allowed && got != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+			}
+			if !slices.Contains(rec.Header().Values("Vary"), "Origin") {
+				t.Errorf("Vary = %v, want Origin", rec.Header().Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := corsHandler(t)
+
+	rec := serveCORS(handler, http.MethodOptions, "/votos", "https://votos.paredao.com",
+		preflight(http.MethodPost, "Content-Type, Idempotency-Key, X-Canal-Token"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", rec.Code)
+	}
+
+	headers := rec.Header()
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://votos.paredao.com",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Max-Age":       "300",
+	}
+	for name, value := range want {
+		if got := headers.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if got := headers.Get("Access-Control-Allow-Headers"); got == "" {
+		t.Error("Access-Control-Allow-Headers is missing")
+	}
+	for _, header := range []string{"Idempotency-Key", "X-Canal-Token", "X-Device-ID"} {
+		if !slices.Contains(PublicCORSPolicy().AllowedHeaders, header) {
+			t.Errorf("the public policy does not allow %s", header)
+		}
+	}
+	// As rotas públicas não aceitam credenciais
+	if got := headers.Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q on a public route", got)
+	}
+	for _, vary := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+		if !slices.Contains(headers.Values("Vary"), vary) {
+			t.Errorf("Vary = %v, want %s", headers.Values("Vary"), vary)
+		}
+	}
+}
+
+func TestCORSPreflightDenied(t *testing.T) {
+	handler := corsHandler(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		origin string
+		method string
+	}{
+		{"unknown origin", "/votos", "https://evil.com", http.MethodPost},
+		// Um DELETE é administrativo, e a origem pública não pode fazê-lo
+		{"public origin on an admin route", "/participantes/1", "https://paredao.com", http.MethodDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCORS(handler, http.MethodOptions, tt.path, tt.origin, preflight(tt.method, ""))
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want 204", rec.Code)
+			}
+			denied := []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods", "Access-Control-Max-Age"}
+			for _, name := range denied {
+				if got := rec.Header().Get(name); got != "" {
+					t.Errorf("%s = %q, want none", name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSPublicAndAdminPolicies(t *testing.T) {
+	handler := corsHandler(t)
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		origin          string
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{"admin origin on an admin route", http.MethodPatch, "/participantes/1", "https://admin.paredao.com",
+			"https://admin.paredao.com", "true"},
+		{"public origin on an admin route", http.MethodPatch, "/participantes/1", "https://paredao.com", "", ""},
+		{"public origin on a public route", http.MethodPost, "/votos", "https://paredao.com", "https://paredao.com", ""},
+		// Os curingas públicos não valem nas rotas administrativas
+		{"wildcard origin on an admin route", http.MethodPost, "/votacoes", "https://votos.paredao.com", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCORS(handler, tt.method, tt.path, tt.origin, nil)
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCredentials)
+			}
+		})
+	}
+
+	// O preflight da administração anuncia os métodos e o cabeçalho da chave de API
+	rec := serveCORS(handler, http.MethodOptions, "/participantes/1", "https://admin.paredao.com",
+		preflight(http.MethodPatch, "Content-Type, X-API-Key"))
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT, PATCH, DELETE" {
+		t.Errorf("admin Access-Control-Allow-Methods = %q", got)
+	}
+	if !slices.Contains(AdminCORSPolicy().AllowedHeaders, APIKeyHeader) {
+		t.Errorf("the admin policy does not allow %s", APIKeyHeader)
+	}
+}
+
+func TestRouteMatchers(t *testing.T) {
+	tests := []struct {
+		method, path string
+		public       bool
+		tenantAdmin  bool
+	}{
+		// Leituras das páginas de votação e relatórios
+		{http.MethodGet, "/participantes", true, false},
+		{http.MethodGet, "/participantes/1", true, false},
+		{http.MethodGet, "/votacoes", true, false},
+		{http.MethodGet, "/votacoes/1/participantes", true, false},
+		{http.MethodGet, "/temporadas/1/paredoes", true, false},
+		{http.MethodGet, "/votos", true, false},
+		{http.MethodGet, "/votos/1/2", true, false},
+		{http.MethodGet, "/estatisticas/votacoes/1/resultado", true, false},
+		{http.MethodGet, "/estatisticas/votacoes/1/eventos", true, false},
+		{http.MethodPost, "/votos", true, false},
+		// Leituras com a origem dos votos ou a análise de fraude
+		{http.MethodGet, "/votacoes/1/votos", false, false},
+		{http.MethodGet, "/votacoes/1/alertas", false, false},
+		{http.MethodGet, "/votacoes/1/invalidacoes", false, false},
+		// Alterações
+		{http.MethodPost, "/participantes", false, false},
+		{http.MethodPatch, "/participantes/1", false, false},
+		{http.MethodDelete, "/votacoes/1", false, false},
+		{http.MethodPost, "/votacoes/1/encerrar", false, false},
+		{http.MethodPost, "/votacoes/1/alertas/2/invalidar", false, false},
+		{http.MethodPost, "/votacoes/1/invalidacoes/2/reverter", false, false},
+		{http.MethodPut, "/temporadas/1/participantes/2", false, false},
+		{http.MethodPost, "/import", false, false},
+		// Só POST /votos é público: a rota de um voto não aceita outros métodos
+		{http.MethodPost, "/votos/1/2", false, false},
+		{http.MethodOptions, "/votos", false, false},
+		// A administração de tenants nunca é pública, nem nas leituras
+		{http.MethodGet, "/admin/tenants", false, true},
+		{http.MethodPost, "/admin/tenants/1/chave", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := IsPublicRoute(tt.path, tt.method); got != tt.public {
+				t.Errorf("IsPublicRoute = %t, want %t", got, tt.public)
+			}
+			if got := IsAdminRoute(tt.path, tt.method); got == tt.public {
+				t.Errorf("IsAdminRoute = %t, want %t", got, !tt.public)
+			}
+			if got := IsTenantAdminRoute(tt.path); got != tt.tenantAdmin {
+				t.Errorf("IsTenantAdminRoute = %t, want %t", got, tt.tenantAdmin)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+)
+
+// IsPublicRoute identifica as rotas usadas pelas páginas de votação e relatórios. Os votos
+// com a sua origem, os alertas de fraude, as invalidações e os tenants são lidos apenas pela
+// administração.
+func IsPublicRoute(path, method string) bool {
+	if strings.HasSuffix(path, "/alertas") || strings.HasSuffix(path, "/invalidacoes") || IsTenantAdminRoute(path) {
+		return false
+	}
+	if strings.HasPrefix(path, "/votacoes/") && strings.HasSuffix(path, "/votos") {
+		return false
+	}
+	return method == http.MethodGet || (method == http.MethodPost && path == "/votos")
+}
+
+// IsAdminRoute identifica as rotas que alteram participantes e votações
+func IsAdminRoute(path, method string) bool {
+	return !IsPublicRoute(path, method)
+}
+
+// IsTenantAdminRoute identifica as rotas de administração de tenants
+func IsTenantAdminRoute(path string) bool {
+	return strings.HasPrefix(path, "/admin/")
+}
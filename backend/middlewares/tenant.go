@@ -0,0 +1,116 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// Cabeçalho com a chave de API que identifica o tenant nas rotas administrativas
+const APIKeyHeader = "X-API-Key"
+
+// Tenant usado quando nem a chave de API nem o host identificam outro, configurável por
+// DEFAULT_TENANT. Com a variável vazia, requisições não identificadas são recusadas.
+const defaultTenantSlug = "padrao"
+
+// Tenant resolve o tenant de cada requisição, pela chave de API (cabeçalho X-API-Key) ou
+// pelo host, e o associa ao contexto. Chaves de API inválidas são recusadas em vez de
+// cair no host. O host, que qualquer cliente escolhe, só identifica o tenant nas rotas
+// para as quais public retorna true; as demais exigem a chave. As rotas para as quais skip
+// retorna true seguem sem tenant.
+func Tenant(skip func(path string) bool, public func(path, method string) bool) func(http.Handler) http.Handler {
+	fallback, exists := os.LookupEnv("DEFAULT_TENANT")
+	if !exists {
+		fallback = defaultTenantSlug
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip != nil && skip(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			var tenant *entities.Tenant
+			var err error
+			if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+				tenant, err = cachedTenant(ctx, apiKeyCacheKey(apiKey), func() (*entities.Tenant, bool) {
+					return repositories.Tenants.GetByAPIKey(apiKey)
+				})
+				if err == nil && tenant == nil {
+					http.Error(w, "Invalid API key", http.StatusUnauthorized)
+					return
+				}
+			} else if public == nil || public(r.URL.Path, r.Method) {
+				tenant, err = tenantByHost(ctx, requestHost(r), fallback)
+			} else {
+				http.Error(w, "Missing API key", http.StatusUnauthorized)
+				return
+			}
+
+			if err != nil {
+				http.Error(w, "Error resolving tenant", http.StatusInternalServerError)
+				return
+			}
+			if tenant == nil {
+				http.Error(w, "Tenant not found", http.StatusNotFound)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(repositories.WithTenant(ctx, tenant)))
+		})
+	}
+}
+
+// apiKeyCacheKey é a chave de cache da busca por chave de API, que guarda apenas o hash
+// da chave
+func apiKeyCacheKey(apiKey string) string {
+	return fmt.Sprintf(repositories.TenantAPIKeyCacheKey, repositories.HashAPIKey(apiKey))
+}
+
+func tenantByHost(ctx context.Context, host, fallback string) (*entities.Tenant, error) {
+	tenant, err := cachedTenant(ctx, fmt.Sprintf(repositories.TenantHostCacheKey, host), func() (*entities.Tenant, bool) {
+		return repositories.Tenants.GetByHost(host)
+	})
+	if err != nil || tenant != nil || fallback == "" {
+		return tenant, err
+	}
+
+	return cachedTenant(ctx, fmt.Sprintf(repositories.TenantSlugCacheKey, fallback), func() (*entities.Tenant, bool) {
+		return repositories.Tenants.GetBySlug(fallback)
+	})
+}
+
+// cachedTenant busca o tenant no cache ou no banco. A ausência também fica em cache,
+// para que hosts desconhecidos não consultem o banco a cada requisição; as entradas
+// são descartadas sempre que um tenant é criado ou alterado.
+func cachedTenant(ctx context.Context, key string, load func() (*entities.Tenant, bool)) (*entities.Tenant, error) {
+	return repositories.GetOrLoadCache(
+		ctx,
+		key,
+		repositories.CacheOptions{TTL: repositories.EntityCacheTTL, Tags: []string{repositories.TenantsTag}},
+		func() (*entities.Tenant, error) {
+			tenant, exists := load()
+			if !exists {
+				return nil, nil
+			}
+			return tenant, nil
+		},
+	)
+}
+
+// requestHost retorna o host da requisição sem a porta e em minúsculas
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,136 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// setupTenants troca os tenants pelos em memória, com dois tenants de slug, host e chave
+// únicos, já que o cache de tenants é compartilhado entre os testes
+func setupTenants(t *testing.T) (a, b *entities.Tenant) {
+	t.Helper()
+	previous := repositories.Tenants
+	store := repositories.NewMemoryTenantStore()
+	repositories.Tenants = store
+	t.Cleanup(func() { repositories.Tenants = previous })
+
+	suffix := time.Now().UnixNano()
+	create := func(name string) *entities.Tenant {
+		slug := fmt.Sprintf("%s-%d", name, suffix)
+		tenant, err := store.Save(&entities.Tenant{Slug: slug, Nome: name, Host: slug + ".example.com"}, "chave-"+slug)
+		if err != nil {
+			t.Fatalf("creating tenant %s: %v", slug, err)
+		}
+		return tenant
+	}
+	return create("a"), create("b")
+}
+
+// tenantHandler responde com o slug do tenant associado à requisição
+var tenantHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := repositories.TenantFromContext(r.Context())
+	if !ok {
+		fmt.Fprint(w, "sem tenant")
+		return
+	}
+	fmt.Fprint(w, tenant.Slug)
+})
+
+func serveTenant(handler http.Handler, method, path, host, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = host
+	if apiKey != "" {
+		req.Header.Set(APIKeyHeader, apiKey)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTenantAdminRoutesRequireAPIKey(t *testing.T) {
+	a, b := setupTenants(t)
+	handler := Tenant(IsTenantAdminRoute, IsPublicRoute)(tenantHandler)
+
+	tests := []struct {
+		name       string
+		host       string
+		apiKey     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"no key", "", "", http.StatusUnauthorized, "Missing API key"},
+		// O host de outro tenant não substitui a chave
+		{"host only", b.Host, "", http.StatusUnauthorized, "Missing API key"},
+		{"invalid key", a.Host, "chave-errada", http.StatusUnauthorized, "Invalid API key"},
+		{"valid key", "", "chave-" + a.Slug, http.StatusOK, a.Slug},
+		// A chave decide o tenant mesmo com o host de outro
+		{"key wins over host", b.Host, "chave-" + a.Slug, http.StatusOK, a.Slug},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTenant(handler, http.MethodPost, "/participantes", tt.host, tt.apiKey)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTenantPublicRoutesResolveByHost(t *testing.T) {
+	a, b := setupTenants(t)
+	t.Setenv("DEFAULT_TENANT", a.Slug)
+	handler := Tenant(IsTenantAdminRoute, IsPublicRoute)(tenantHandler)
+
+	tests := []struct {
+		name     string
+		host     string
+		apiKey   string
+		wantBody string
+	}{
+		{"host", b.Host + ":8080", "", b.Slug},
+		{"unknown host falls back to the default", fmt.Sprintf("outro-%d.example.com", time.Now().UnixNano()), "", a.Slug},
+		{"api key", b.Host, "chave-" + a.Slug, a.Slug},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTenant(handler, http.MethodGet, "/votacoes", tt.host, tt.apiKey)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+			if body := rec.Body.String(); body != tt.wantBody {
+				t.Errorf("tenant = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTenantWithoutDefault(t *testing.T) {
+	setupTenants(t)
+	t.Setenv("DEFAULT_TENANT", "")
+	handler := Tenant(IsTenantAdminRoute, IsPublicRoute)(tenantHandler)
+
+	host := fmt.Sprintf("desconhecido-%d.example.com", time.Now().UnixNano())
+	if rec := serveTenant(handler, http.MethodGet, "/votacoes", host, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown host without a default: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestTenantSkippedRoutes(t *testing.T) {
+	setupTenants(t)
+	handler := Tenant(IsTenantAdminRoute, IsPublicRoute)(tenantHandler)
+
+	rec := serveTenant(handler, http.MethodPost, "/admin/tenants", "", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "sem tenant" {
+		t.Errorf("skipped route: got %d %q, want 200 without a tenant", rec.Code, rec.Body)
+	}
+}
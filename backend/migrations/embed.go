@@ -0,0 +1,9 @@
+// Package migrations embute no binário as migrações que o próprio backend aplica
+package migrations
+
+import "embed"
+
+// SQLite são as migrações do banco embutido, aplicadas ao abrir o banco
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
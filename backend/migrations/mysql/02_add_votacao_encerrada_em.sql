@@ -0,0 +1,4 @@
+USE paredao;
+
+-- Add closing timestamp to votacoes
+ALTER TABLE votacoes ADD COLUMN encerrada_em DATETIME NULL;
@@ -0,0 +1,21 @@
+USE paredao;
+
+-- Create votos_por_minuto rollup table
+CREATE TABLE IF NOT EXISTS votos_por_minuto (
+    votacao_id BIGINT NOT NULL,
+    participante_id BIGINT NOT NULL,
+    minuto DATETIME NOT NULL,
+    total BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (votacao_id, participante_id, minuto),
+    FOREIGN KEY (participante_id) REFERENCES participantes(id) ON DELETE CASCADE,
+    FOREIGN KEY (votacao_id) REFERENCES votacoes(id) ON DELETE CASCADE
+);
+
+-- Create rollup_watermark table, holding the last votos.id already aggregated
+CREATE TABLE IF NOT EXISTS rollup_watermark (
+    nome VARCHAR(64) PRIMARY KEY,
+    ultimo_voto_id BIGINT NOT NULL
+);
+
+INSERT INTO rollup_watermark (nome, ultimo_voto_id) VALUES ('votos_por_minuto', 0)
+ON DUPLICATE KEY UPDATE nome = nome;
@@ -0,0 +1,29 @@
+USE paredao;
+
+-- Add vote source columns, stored as salted hashes
+ALTER TABLE votos
+    ADD COLUMN ip_hash CHAR(32) NULL,
+    ADD COLUMN user_agent_hash CHAR(32) NULL,
+    ADD COLUMN device_id VARCHAR(128) NULL,
+    ADD COLUMN invalidado BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD INDEX idx_votos_votacao_data_hora (votacao_id, data_hora);
+
+-- Create alertas table
+CREATE TABLE IF NOT EXISTS alertas (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    votacao_id BIGINT NOT NULL,
+    participante_id BIGINT NOT NULL,
+    tipo VARCHAR(32) NOT NULL,
+    origem CHAR(32) NOT NULL,
+    valor DOUBLE NOT NULL,
+    limite DOUBLE NOT NULL,
+    votos INT NOT NULL,
+    janela_inicio DATETIME NOT NULL,
+    janela_fim DATETIME NOT NULL,
+    ocorrencias INT NOT NULL DEFAULT 1,
+    criado_em DATETIME NOT NULL,
+    atualizado_em DATETIME NOT NULL,
+    UNIQUE KEY uk_alertas (votacao_id, tipo, origem, participante_id),
+    FOREIGN KEY (participante_id) REFERENCES participantes(id) ON DELETE CASCADE,
+    FOREIGN KEY (votacao_id) REFERENCES votacoes(id) ON DELETE CASCADE
+);
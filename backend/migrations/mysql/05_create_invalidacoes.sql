@@ -0,0 +1,22 @@
+USE paredao;
+
+-- Create invalidacoes table, one row per invalidation operation
+CREATE TABLE IF NOT EXISTS invalidacoes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    votacao_id BIGINT NOT NULL,
+    participante_id BIGINT NULL,
+    ip_hash CHAR(32) NULL,
+    inicio DATETIME NULL,
+    fim DATETIME NULL,
+    motivo VARCHAR(255) NOT NULL,
+    votos INT NOT NULL,
+    criado_em DATETIME NOT NULL,
+    revertida_em DATETIME NULL,
+    INDEX idx_invalidacoes_votacao (votacao_id),
+    FOREIGN KEY (votacao_id) REFERENCES votacoes(id) ON DELETE CASCADE
+);
+
+-- Link each invalidated vote to the operation that invalidated it, so it can be reverted
+ALTER TABLE votos
+    ADD COLUMN invalidacao_id BIGINT NULL,
+    ADD INDEX idx_votos_invalidacao (invalidacao_id);
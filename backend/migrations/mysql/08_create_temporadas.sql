@@ -0,0 +1,24 @@
+USE paredao;
+
+-- Create temporadas table
+CREATE TABLE IF NOT EXISTS temporadas (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    nome VARCHAR(255) NOT NULL
+);
+
+-- Create temporada_participante table with the participant status in each season
+CREATE TABLE IF NOT EXISTS temporada_participante (
+    temporada_id BIGINT NOT NULL,
+    participante_id BIGINT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'ativo',
+    votacao_eliminacao_id BIGINT NULL,
+    PRIMARY KEY (temporada_id, participante_id),
+    FOREIGN KEY (temporada_id) REFERENCES temporadas(id) ON DELETE CASCADE,
+    FOREIGN KEY (participante_id) REFERENCES participantes(id) ON DELETE CASCADE,
+    FOREIGN KEY (votacao_eliminacao_id) REFERENCES votacoes(id) ON DELETE SET NULL
+);
+
+-- Each votacao may belong to a season
+ALTER TABLE votacoes
+    ADD COLUMN temporada_id BIGINT NULL,
+    ADD CONSTRAINT fk_votacoes_temporada FOREIGN KEY (temporada_id) REFERENCES temporadas(id) ON DELETE SET NULL;
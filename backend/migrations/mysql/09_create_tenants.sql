@@ -0,0 +1,27 @@
+USE paredao;
+
+-- Create tenants table: each program sharing the deployment is a tenant
+CREATE TABLE IF NOT EXISTS tenants (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    nome VARCHAR(255) NOT NULL,
+    host VARCHAR(255) NULL UNIQUE,
+    api_key_hash CHAR(64) NULL UNIQUE,
+    config JSON NULL
+);
+
+-- Existing data belongs to the default tenant
+INSERT INTO tenants (id, slug, nome) VALUES (1, 'padrao', 'Paredão')
+    ON DUPLICATE KEY UPDATE slug = slug;
+
+ALTER TABLE participantes
+    ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1,
+    ADD CONSTRAINT fk_participantes_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id);
+
+ALTER TABLE votacoes
+    ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1,
+    ADD CONSTRAINT fk_votacoes_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id);
+
+ALTER TABLE temporadas
+    ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1,
+    ADD CONSTRAINT fk_temporadas_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id);
@@ -0,0 +1,63 @@
+USE paredao;
+
+-- Record when the votes of a votação were archived and where the archive file is
+ALTER TABLE votacoes
+    ADD COLUMN arquivada_em DATETIME NULL,
+    ADD COLUMN arquivo_votos VARCHAR(255) NULL;
+
+-- Partitioned tables cannot have foreign keys; the repositories delete the votes of a
+-- removed participante or votação themselves. The constraints were created without a
+-- name, so they are looked up instead of relying on the generated votos_ibfk_N names.
+SELECT COALESCE(
+    CONCAT('ALTER TABLE votos ', GROUP_CONCAT(CONCAT('DROP FOREIGN KEY `', CONSTRAINT_NAME, '`') SEPARATOR ', ')),
+    'DO 0'
+) INTO @remover_fks
+FROM information_schema.TABLE_CONSTRAINTS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'votos' AND CONSTRAINT_TYPE = 'FOREIGN KEY';
+PREPARE remover_fks FROM @remover_fks;
+EXECUTE remover_fks;
+DEALLOCATE PREPARE remover_fks;
+
+-- The single-column index created for the votacao_id foreign key may already be gone:
+-- MySQL drops it on its own when another index, like idx_votos_votacao_data_hora from
+-- migration 04, starts with the same column. Drop it only where it is still there.
+SELECT COALESCE(
+    CONCAT('ALTER TABLE votos ', GROUP_CONCAT(CONCAT('DROP INDEX `', INDEX_NAME, '`') SEPARATOR ', ')),
+    'DO 0'
+) INTO @remover_indice
+FROM (
+    SELECT INDEX_NAME
+    FROM information_schema.STATISTICS
+    WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'votos' AND INDEX_NAME <> 'PRIMARY'
+    GROUP BY INDEX_NAME
+    HAVING COUNT(*) = 1 AND MAX(COLUMN_NAME) = 'votacao_id'
+) indices;
+PREPARE remover_indice FROM @remover_indice;
+EXECUTE remover_indice;
+DEALLOCATE PREPARE remover_indice;
+
+-- Every unique key of a partitioned table must include the partitioning column. The
+-- composite indexes cover the final totals, the fraud analysis and the invalidations.
+ALTER TABLE votos
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (id, data_hora),
+    DROP INDEX idx_votos_votacao_data_hora,
+    ADD INDEX idx_votos_votacao_participante (votacao_id, participante_id, invalidado, peso),
+    ADD INDEX idx_votos_votacao_janela (votacao_id, data_hora, invalidado, participante_id, ip_hash);
+
+-- Partition votos by month of data_hora. Existing votes go to the partition ending at the
+-- start of next month; votos_pmax holds votes past the last monthly partition until the
+-- retention job splits it.
+SET @limite = DATE_FORMAT(UTC_TIMESTAMP() + INTERVAL 1 MONTH, '%Y-%m-01');
+SET @particionar = CONCAT(
+    'ALTER TABLE votos PARTITION BY RANGE COLUMNS (data_hora) (',
+    'PARTITION votos_p', DATE_FORMAT(@limite, '%Y%m%d'), ' VALUES LESS THAN (''', @limite, '''), ',
+    'PARTITION votos_pmax VALUES LESS THAN (MAXVALUE))'
+);
+PREPARE particionar FROM @particionar;
+EXECUTE particionar;
+DEALLOCATE PREPARE particionar;
+
+-- Cover the statistics read from votos_por_minuto in order of minute
+ALTER TABLE votos_por_minuto
+    ADD INDEX idx_votos_por_minuto_votacao_minuto (votacao_id, minuto, participante_id, total, pontos);
@@ -0,0 +1,8 @@
+USE paredao;
+
+-- Create execucoes_job table, one row per background job run by every replica. The row is
+-- locked to claim a run, so only one replica runs the job in each interval
+CREATE TABLE IF NOT EXISTS execucoes_job (
+    nome VARCHAR(64) PRIMARY KEY,
+    executado_em DATETIME NULL
+);
@@ -0,0 +1,15 @@
+USE paredao;
+
+-- Record the aggregation batch that added each vote to votos_por_minuto. A vote is aggregated
+-- once, whenever it commits, instead of trusting MAX(id) as the commit frontier; the batch
+-- counter lives in the rollup_watermark row, whose lock serializes the aggregators.
+ALTER TABLE votos
+    ADD COLUMN lote_agregacao BIGINT NULL,
+    ADD INDEX idx_votos_lote_agregacao (lote_agregacao, id);
+
+ALTER TABLE rollup_watermark ADD COLUMN ultimo_lote BIGINT NOT NULL DEFAULT 0;
+
+-- Votes up to the old watermark are already in votos_por_minuto; votes skipped by it are
+-- reported and fixed by -rebuild-rollups
+UPDATE votos SET lote_agregacao = 0
+WHERE id <= (SELECT ultimo_voto_id FROM rollup_watermark WHERE nome = 'votos_por_minuto');
@@ -0,0 +1,7 @@
+USE paredao;
+
+-- A votacao has at most one derived round per criterio: each derivation clears the source's
+-- elimination, so a second runoff from the same source would undo the first one's result.
+-- Original votacoes have NULL votacao_origem_id and criterio, which the key does not compare.
+ALTER TABLE votacoes
+    ADD UNIQUE INDEX uq_votacoes_origem_criterio (votacao_origem_id, criterio);
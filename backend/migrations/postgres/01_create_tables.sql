@@ -0,0 +1,48 @@
+-- Names compare ignoring case and accents, like the default MySQL collation
+CREATE COLLATION IF NOT EXISTS ci_ai (provider = icu, locale = 'und-u-ks-level1', deterministic = false);
+
+-- Create participantes table
+CREATE TABLE IF NOT EXISTS participantes (
+    id BIGSERIAL PRIMARY KEY,
+    nome VARCHAR(255) COLLATE ci_ai NOT NULL,
+    url_foto VARCHAR(255) NOT NULL
+);
+
+-- Create votacoes table
+CREATE TABLE IF NOT EXISTS votacoes (
+    id BIGSERIAL PRIMARY KEY,
+    descricao VARCHAR(255) NOT NULL
+);
+
+-- Create votacao_participante table
+CREATE TABLE IF NOT EXISTS votacao_participante (
+    participante_id BIGINT NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    votacao_id BIGINT NOT NULL REFERENCES votacoes(id) ON DELETE CASCADE,
+    PRIMARY KEY (participante_id, votacao_id)
+);
+
+-- Create votos table
+CREATE TABLE IF NOT EXISTS votos (
+    id BIGSERIAL PRIMARY KEY,
+    participante_id BIGINT NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    votacao_id BIGINT NOT NULL REFERENCES votacoes(id) ON DELETE CASCADE,
+    data_hora TIMESTAMPTZ NOT NULL
+);
+
+-- Insert data
+INSERT INTO participantes (id, nome, url_foto) VALUES
+(1, 'Johann Sebastian Bach', 'https://hips.hearstapps.com/hmg-prod/images/johann-sebastian-bach-gettyimages-51246888.jpg'),
+(2, 'Antonio Lucio Vivaldi', 'https://classicosdosclassicos.mus.br/wp-content/uploads/2021/03/antonio_vivaldi.png'),
+(3, 'Beethoven', 'https://i.natgeofe.com/n/42e08d5a-5fbd-4c02-aa50-7e8a237aea72/16-beethoven-portrait-og_square.jpg');
+
+INSERT INTO votacoes (id, descricao) VALUES
+(1, 'Votação 27/02/2025');
+
+INSERT INTO votacao_participante (participante_id, votacao_id) VALUES
+(1, 1),
+(2, 1),
+(3, 1);
+
+-- Rows inserted with explicit ids don't advance the sequences
+SELECT setval(pg_get_serial_sequence('participantes', 'id'), (SELECT MAX(id) FROM participantes));
+SELECT setval(pg_get_serial_sequence('votacoes', 'id'), (SELECT MAX(id) FROM votacoes));
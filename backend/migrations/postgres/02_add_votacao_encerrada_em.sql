@@ -0,0 +1,2 @@
+-- Add closing timestamp to votacoes
+ALTER TABLE votacoes ADD COLUMN encerrada_em TIMESTAMPTZ NULL;
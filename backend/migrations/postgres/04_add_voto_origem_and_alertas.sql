@@ -0,0 +1,26 @@
+-- Add vote source columns, stored as salted hashes
+ALTER TABLE votos
+    ADD COLUMN ip_hash CHAR(32) NULL,
+    ADD COLUMN user_agent_hash CHAR(32) NULL,
+    ADD COLUMN device_id VARCHAR(128) NULL,
+    ADD COLUMN invalidado BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_votos_votacao_data_hora ON votos (votacao_id, data_hora);
+
+-- Create alertas table
+CREATE TABLE IF NOT EXISTS alertas (
+    id BIGSERIAL PRIMARY KEY,
+    votacao_id BIGINT NOT NULL REFERENCES votacoes(id) ON DELETE CASCADE,
+    participante_id BIGINT NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    tipo VARCHAR(32) NOT NULL,
+    origem CHAR(32) NOT NULL,
+    valor DOUBLE PRECISION NOT NULL,
+    limite DOUBLE PRECISION NOT NULL,
+    votos INT NOT NULL,
+    janela_inicio TIMESTAMPTZ NOT NULL,
+    janela_fim TIMESTAMPTZ NOT NULL,
+    ocorrencias INT NOT NULL DEFAULT 1,
+    criado_em TIMESTAMPTZ NOT NULL,
+    atualizado_em TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uk_alertas UNIQUE (votacao_id, tipo, origem, participante_id)
+);
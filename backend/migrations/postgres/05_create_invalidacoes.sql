@@ -0,0 +1,20 @@
+-- Create invalidacoes table, one row per invalidation operation
+CREATE TABLE IF NOT EXISTS invalidacoes (
+    id BIGSERIAL PRIMARY KEY,
+    votacao_id BIGINT NOT NULL REFERENCES votacoes(id) ON DELETE CASCADE,
+    participante_id BIGINT NULL,
+    ip_hash CHAR(32) NULL,
+    inicio TIMESTAMPTZ NULL,
+    fim TIMESTAMPTZ NULL,
+    motivo VARCHAR(255) NOT NULL,
+    votos INT NOT NULL,
+    criado_em TIMESTAMPTZ NOT NULL,
+    revertida_em TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_invalidacoes_votacao ON invalidacoes (votacao_id);
+
+-- Link each invalidated vote to the operation that invalidated it, so it can be reverted
+ALTER TABLE votos ADD COLUMN invalidacao_id BIGINT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_votos_invalidacao ON votos (invalidacao_id);
@@ -0,0 +1,15 @@
+-- Add voting mode and channel weights to votacoes
+ALTER TABLE votacoes
+    ADD COLUMN modo VARCHAR(16) NOT NULL DEFAULT 'unica',
+    ADD COLUMN pesos JSONB NULL;
+
+-- Record the channel and the weight each vote was cast with
+ALTER TABLE votos
+    ADD COLUMN canal VARCHAR(32) NULL,
+    ADD COLUMN peso INT NOT NULL DEFAULT 1;
+
+-- Keep weighted totals alongside vote counts; existing votes all weigh 1
+ALTER TABLE votos_por_minuto
+    ADD COLUMN pontos BIGINT NOT NULL DEFAULT 0;
+
+UPDATE votos_por_minuto SET pontos = total;
@@ -0,0 +1,5 @@
+-- Link derived rounds (runoffs and tie-breakers) to the votacao they came from
+ALTER TABLE votacoes
+    ADD COLUMN votacao_origem_id BIGINT NULL,
+    ADD COLUMN criterio VARCHAR(16) NULL,
+    ADD CONSTRAINT fk_votacoes_origem FOREIGN KEY (votacao_origem_id) REFERENCES votacoes(id) ON DELETE SET NULL;
@@ -0,0 +1,19 @@
+-- Create temporadas table
+CREATE TABLE IF NOT EXISTS temporadas (
+    id BIGSERIAL PRIMARY KEY,
+    nome VARCHAR(255) NOT NULL
+);
+
+-- Create temporada_participante table with the participant status in each season
+CREATE TABLE IF NOT EXISTS temporada_participante (
+    temporada_id BIGINT NOT NULL REFERENCES temporadas(id) ON DELETE CASCADE,
+    participante_id BIGINT NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'ativo',
+    votacao_eliminacao_id BIGINT NULL REFERENCES votacoes(id) ON DELETE SET NULL,
+    PRIMARY KEY (temporada_id, participante_id)
+);
+
+-- Each votacao may belong to a season
+ALTER TABLE votacoes
+    ADD COLUMN temporada_id BIGINT NULL,
+    ADD CONSTRAINT fk_votacoes_temporada FOREIGN KEY (temporada_id) REFERENCES temporadas(id) ON DELETE SET NULL;
@@ -0,0 +1,27 @@
+-- Create tenants table: each program sharing the deployment is a tenant
+CREATE TABLE IF NOT EXISTS tenants (
+    id BIGSERIAL PRIMARY KEY,
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    nome VARCHAR(255) NOT NULL,
+    host VARCHAR(255) NULL UNIQUE,
+    api_key_hash CHAR(64) NULL UNIQUE,
+    config JSONB NULL
+);
+
+-- Existing data belongs to the default tenant
+INSERT INTO tenants (id, slug, nome) VALUES (1, 'padrao', 'Paredão')
+    ON CONFLICT (id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('tenants', 'id'), (SELECT MAX(id) FROM tenants));
+
+ALTER TABLE participantes
+    ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1,
+    ADD CONSTRAINT fk_participantes_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id);
+
+ALTER TABLE votacoes
+    ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1,
+    ADD CONSTRAINT fk_votacoes_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id);
+
+ALTER TABLE temporadas
+    ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1,
+    ADD CONSTRAINT fk_temporadas_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id);
@@ -0,0 +1,8 @@
+-- Optional profile fields of participantes
+ALTER TABLE participantes
+    ADD COLUMN apelido VARCHAR(50) NULL,
+    ADD COLUMN bio VARCHAR(500) NULL,
+    ADD COLUMN cidade VARCHAR(100) NULL,
+    ADD COLUMN data_nascimento DATE NULL,
+    ADD COLUMN redes_sociais JSONB NULL,
+    ADD COLUMN cor CHAR(7) NULL;
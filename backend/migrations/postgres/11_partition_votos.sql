@@ -0,0 +1,58 @@
+-- Record when the votes of a votação were archived and where the archive file is
+ALTER TABLE votacoes
+    ADD COLUMN arquivada_em TIMESTAMPTZ NULL,
+    ADD COLUMN arquivo_votos VARCHAR(255) NULL;
+
+-- Recreate votos partitioned by month of data_hora. The primary key of a partitioned
+-- table must include the partitioning column. The sequence outlives the old table.
+ALTER TABLE votos RENAME TO votos_antigos;
+ALTER SEQUENCE votos_id_seq OWNED BY NONE;
+
+CREATE TABLE votos (
+    id BIGINT NOT NULL DEFAULT nextval('votos_id_seq'),
+    participante_id BIGINT NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    votacao_id BIGINT NOT NULL REFERENCES votacoes(id) ON DELETE CASCADE,
+    data_hora TIMESTAMPTZ NOT NULL,
+    ip_hash CHAR(32) NULL,
+    user_agent_hash CHAR(32) NULL,
+    device_id VARCHAR(128) NULL,
+    invalidado BOOLEAN NOT NULL DEFAULT FALSE,
+    invalidacao_id BIGINT NULL,
+    canal VARCHAR(32) NULL,
+    peso INT NOT NULL DEFAULT 1,
+    PRIMARY KEY (id, data_hora)
+) PARTITION BY RANGE (data_hora);
+
+ALTER SEQUENCE votos_id_seq OWNED BY votos.id;
+
+-- Existing votes go to the partition ending at the start of next month (UTC). The default
+-- partition holds votes past the last monthly partition, should the retention job fall
+-- behind in creating them.
+DO $$
+DECLARE
+    limite TIMESTAMP := date_trunc('month', now() AT TIME ZONE 'UTC') + INTERVAL '1 month';
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE %I PARTITION OF votos FOR VALUES FROM (MINVALUE) TO (%L)',
+        'votos_p' || to_char(limite, 'YYYYMMDD'), limite AT TIME ZONE 'UTC'
+    );
+END
+$$;
+
+CREATE TABLE votos_padrao PARTITION OF votos DEFAULT;
+
+INSERT INTO votos
+SELECT id, participante_id, votacao_id, data_hora, ip_hash, user_agent_hash, device_id,
+       invalidado, invalidacao_id, canal, peso
+FROM votos_antigos;
+
+DROP TABLE votos_antigos;
+
+-- The composite indexes cover the final totals, the fraud analysis and the invalidations
+CREATE INDEX idx_votos_votacao_participante ON votos (votacao_id, participante_id, invalidado, peso);
+CREATE INDEX idx_votos_votacao_janela ON votos (votacao_id, data_hora, invalidado, participante_id, ip_hash);
+CREATE INDEX idx_votos_participante ON votos (participante_id);
+CREATE INDEX idx_votos_invalidacao ON votos (invalidacao_id);
+
+-- Cover the statistics read from votos_por_minuto in order of minute
+CREATE INDEX idx_votos_por_minuto_votacao_minuto ON votos_por_minuto (votacao_id, minuto, participante_id, total, pontos);
@@ -0,0 +1,44 @@
+-- Names compare ignoring case and accents, like the default MySQL collation; ci_ai is
+-- registered by the backend when it opens the database
+
+-- Create participantes table
+CREATE TABLE IF NOT EXISTS participantes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nome VARCHAR(255) COLLATE ci_ai NOT NULL,
+    url_foto VARCHAR(255) NOT NULL
+);
+
+-- Create votacoes table
+CREATE TABLE IF NOT EXISTS votacoes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    descricao VARCHAR(255) NOT NULL
+);
+
+-- Create votacao_participante table
+CREATE TABLE IF NOT EXISTS votacao_participante (
+    participante_id INTEGER NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    votacao_id INTEGER NOT NULL REFERENCES votacoes(id) ON DELETE CASCADE,
+    PRIMARY KEY (participante_id, votacao_id)
+);
+
+-- Create votos table; ids are never reused, since the rollup watermark relies on them
+CREATE TABLE IF NOT EXISTS votos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    participante_id INTEGER NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    votacao_id INTEGER NOT NULL REFERENCES votacoes(id) ON DELETE CASCADE,
+    data_hora DATETIME NOT NULL
+);
+
+-- Insert data
+INSERT INTO participantes (id, nome, url_foto) VALUES
+(1, 'Johann Sebastian Bach', 'https://hips.hearstapps.com/hmg-prod/images/johann-sebastian-bach-gettyimages-51246888.jpg'),
+(2, 'Antonio Lucio Vivaldi', 'https://classicosdosclassicos.mus.br/wp-content/uploads/2021/03/antonio_vivaldi.png'),
+(3, 'Beethoven', 'https://i.natgeofe.com/n/42e08d5a-5fbd-4c02-aa50-7e8a237aea72/16-beethoven-portrait-og_square.jpg');
+
+INSERT INTO votacoes (id, descricao) VALUES
+(1, 'Votação 27/02/2025');
+
+INSERT INTO votacao_participante (participante_id, votacao_id) VALUES
+(1, 1),
+(2, 1),
+(3, 1);
@@ -0,0 +1,17 @@
+-- Create votos_por_minuto rollup table
+CREATE TABLE IF NOT EXISTS votos_por_minuto (
+    votacao_id INTEGER NOT NULL REFERENCES votacoes(id) ON DELETE CASCADE,
+    participante_id INTEGER NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    minuto DATETIME NOT NULL,
+    total INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (votacao_id, participante_id, minuto)
+);
+
+-- Create rollup_watermark table, holding the last votos.id already aggregated
+CREATE TABLE IF NOT EXISTS rollup_watermark (
+    nome VARCHAR(64) PRIMARY KEY,
+    ultimo_voto_id INTEGER NOT NULL
+);
+
+INSERT INTO rollup_watermark (nome, ultimo_voto_id) VALUES ('votos_por_minuto', 0)
+ON CONFLICT (nome) DO NOTHING;
@@ -0,0 +1,25 @@
+-- Add vote source columns, stored as salted hashes; SQLite adds one column per statement
+ALTER TABLE votos ADD COLUMN ip_hash CHAR(32) NULL;
+ALTER TABLE votos ADD COLUMN user_agent_hash CHAR(32) NULL;
+ALTER TABLE votos ADD COLUMN device_id VARCHAR(128) NULL;
+ALTER TABLE votos ADD COLUMN invalidado BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_votos_votacao_data_hora ON votos (votacao_id, data_hora);
+
+-- Create alertas table
+CREATE TABLE IF NOT EXISTS alertas (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    votacao_id INTEGER NOT NULL REFERENCES votacoes(id) ON DELETE CASCADE,
+    participante_id INTEGER NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    tipo VARCHAR(32) NOT NULL,
+    origem CHAR(32) NOT NULL,
+    valor REAL NOT NULL,
+    limite REAL NOT NULL,
+    votos INT NOT NULL,
+    janela_inicio DATETIME NOT NULL,
+    janela_fim DATETIME NOT NULL,
+    ocorrencias INT NOT NULL DEFAULT 1,
+    criado_em DATETIME NOT NULL,
+    atualizado_em DATETIME NOT NULL,
+    CONSTRAINT uk_alertas UNIQUE (votacao_id, tipo, origem, participante_id)
+);
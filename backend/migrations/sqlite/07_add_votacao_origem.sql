@@ -0,0 +1,3 @@
+-- Link derived rounds (runoffs and tie-breakers) to the votacao they came from
+ALTER TABLE votacoes ADD COLUMN votacao_origem_id INTEGER NULL REFERENCES votacoes(id) ON DELETE SET NULL;
+ALTER TABLE votacoes ADD COLUMN criterio VARCHAR(16) NULL;
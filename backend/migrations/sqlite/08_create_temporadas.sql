@@ -0,0 +1,17 @@
+-- Create temporadas table
+CREATE TABLE IF NOT EXISTS temporadas (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nome VARCHAR(255) NOT NULL
+);
+
+-- Create temporada_participante table with the participant status in each season
+CREATE TABLE IF NOT EXISTS temporada_participante (
+    temporada_id INTEGER NOT NULL REFERENCES temporadas(id) ON DELETE CASCADE,
+    participante_id INTEGER NOT NULL REFERENCES participantes(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'ativo',
+    votacao_eliminacao_id INTEGER NULL REFERENCES votacoes(id) ON DELETE SET NULL,
+    PRIMARY KEY (temporada_id, participante_id)
+);
+
+-- Each votacao may belong to a season
+ALTER TABLE votacoes ADD COLUMN temporada_id INTEGER NULL REFERENCES temporadas(id) ON DELETE SET NULL;
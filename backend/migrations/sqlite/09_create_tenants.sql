@@ -0,0 +1,19 @@
+-- Create tenants table: each program sharing the deployment is a tenant
+CREATE TABLE IF NOT EXISTS tenants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    nome VARCHAR(255) NOT NULL,
+    host VARCHAR(255) NULL UNIQUE,
+    api_key_hash CHAR(64) NULL UNIQUE,
+    config TEXT NULL
+);
+
+-- Existing data belongs to the default tenant
+INSERT INTO tenants (id, slug, nome) VALUES (1, 'padrao', 'Paredão')
+    ON CONFLICT (id) DO NOTHING;
+
+-- A column referencing another table with a non-null default can only be added while
+-- foreign keys are off, as they are when the backend applies the migrations
+ALTER TABLE participantes ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE votacoes ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE temporadas ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id);
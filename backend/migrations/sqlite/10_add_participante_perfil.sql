@@ -0,0 +1,7 @@
+-- Optional profile fields of participantes; redes_sociais is a JSON document
+ALTER TABLE participantes ADD COLUMN apelido VARCHAR(50) NULL;
+ALTER TABLE participantes ADD COLUMN bio VARCHAR(500) NULL;
+ALTER TABLE participantes ADD COLUMN cidade VARCHAR(100) NULL;
+ALTER TABLE participantes ADD COLUMN data_nascimento DATE NULL;
+ALTER TABLE participantes ADD COLUMN redes_sociais TEXT NULL;
+ALTER TABLE participantes ADD COLUMN cor CHAR(7) NULL;
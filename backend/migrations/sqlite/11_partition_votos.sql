@@ -0,0 +1,13 @@
+-- Record when the votes of a votação were archived and where the archive file is
+ALTER TABLE votacoes ADD COLUMN arquivada_em DATETIME NULL;
+ALTER TABLE votacoes ADD COLUMN arquivo_votos VARCHAR(255) NULL;
+
+-- SQLite has no partitions; the composite indexes cover the final totals, the fraud
+-- analysis and the invalidations
+DROP INDEX IF EXISTS idx_votos_votacao_data_hora;
+CREATE INDEX IF NOT EXISTS idx_votos_votacao_participante ON votos (votacao_id, participante_id, invalidado, peso);
+CREATE INDEX IF NOT EXISTS idx_votos_votacao_janela ON votos (votacao_id, data_hora, invalidado, participante_id, ip_hash);
+CREATE INDEX IF NOT EXISTS idx_votos_participante ON votos (participante_id);
+
+-- Cover the statistics read from votos_por_minuto in order of minute
+CREATE INDEX IF NOT EXISTS idx_votos_por_minuto_votacao_minuto ON votos_por_minuto (votacao_id, minuto, participante_id, total, pontos);
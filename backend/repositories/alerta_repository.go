@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+const alertaColumns = `id, votacao_id, participante_id, tipo, origem, valor, limite, votos,
+	janela_inicio, janela_fim, ocorrencias, criado_em, atualizado_em`
+
+func scanAlerta(row interface{ Scan(...interface{}) error }, a *entities.Alerta) error {
+	return row.Scan(
+		&a.ID, &a.VotacaoID, &a.ParticipanteID, &a.Tipo, &a.Origem, &a.Valor, &a.Limite, &a.Votos,
+		&a.JanelaInicio, &a.JanelaFim, &a.Ocorrencias, &a.CriadoEm, &a.AtualizadoEm,
+	)
+}
+
+func GetAlertasByVotacaoID(tenantID, votacaoID int64) []*entities.Alerta {
+	rows, err := readDB("GetAlertasByVotacaoID").Query(
+		"SELECT "+alertaColumns+" FROM alertas WHERE votacao_id = ? AND "+inTenantVotacao("alertas")+
+			" ORDER BY atualizado_em DESC",
+		votacaoID, tenantID,
+	)
+	if err != nil {
+		log.Printf("Error querying alertas by votacao ID: %v", err)
+		return []*entities.Alerta{}
+	}
+	defer rows.Close()
+
+	alertas := []*entities.Alerta{}
+	for rows.Next() {
+		a := &entities.Alerta{}
+		if err := scanAlerta(rows, a); err != nil {
+			log.Printf("Error scanning alerta row: %v", err)
+			continue
+		}
+		alertas = append(alertas, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating alerta rows: %v", err)
+	}
+
+	return alertas
+}
+
+func GetAlertaByID(tenantID, id int64) (*entities.Alerta, bool) {
+	a := &entities.Alerta{}
+	err := scanAlerta(DB.QueryRow(
+		"SELECT "+alertaColumns+" FROM alertas WHERE id = ? AND "+inTenantVotacao("alertas"), id, tenantID,
+	), a)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false
+		}
+		log.Printf("Error querying alerta by ID: %v", err)
+		return nil, false
+	}
+
+	return a, true
+}
+
+// SaveAlerta grava um alerta novo ou, se a mesma origem já tiver sido sinalizada para o
+// participante, atualiza o valor, estende a janela até a nova análise e conta mais uma ocorrência
+func SaveAlerta(a *entities.Alerta) error {
+	now := time.Now()
+	excluded := dbDriver.Excluded
+	_, err := DB.Exec(`
+		INSERT INTO alertas (votacao_id, participante_id, tipo, origem, valor, limite, votos,
+			janela_inicio, janela_fim, ocorrencias, criado_em, atualizado_em)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)
+	`+dbDriver.OnConflictUpdate([]string{"votacao_id", "tipo", "origem", "participante_id"}, `
+		valor = `+excluded("valor")+`, limite = `+excluded("limite")+`, votos = `+excluded("votos")+`,
+		janela_inicio = `+dbDriver.Least("alertas.janela_inicio", excluded("janela_inicio"))+`,
+		janela_fim = `+excluded("janela_fim")+`,
+		ocorrencias = alertas.ocorrencias + 1, atualizado_em = `+excluded("atualizado_em")),
+		a.VotacaoID, a.ParticipanteID, a.Tipo, a.Origem, a.Valor, a.Limite, a.Votos,
+		a.JanelaInicio, a.JanelaFim, now, now)
+	if err != nil {
+		log.Printf("Error saving alerta: %v", err)
+	}
+	return err
+}
+
+// GetOpenVotacaoIDs retorna as votações que ainda aceitam votos
+func GetOpenVotacaoIDs() ([]int64, error) {
+	rows, err := DB.Query("SELECT id FROM votacoes WHERE encerrada_em IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetSourceCounts retorna os votos válidos por participante e origem em [from, to)
+func GetSourceCounts(votacaoID int64, from, to time.Time) ([]entities.SourceCount, error) {
+	rows, err := readDB("GetSourceCounts").Query(`
+		SELECT participante_id, ip_hash, COUNT(*)
+		FROM votos
+		WHERE votacao_id = ? AND data_hora >= ? AND data_hora < ? AND NOT invalidado AND ip_hash IS NOT NULL
+		GROUP BY participante_id, ip_hash
+	`, votacaoID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []entities.SourceCount{}
+	for rows.Next() {
+		var c entities.SourceCount
+		if err := rows.Scan(&c.ParticipanteID, &c.Origem, &c.Total); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
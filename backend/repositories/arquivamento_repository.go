@@ -0,0 +1,366 @@
+package repositories
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// ErrVotacaoArquivada indica que os votos da votação já foram arquivados e removidos do banco
+var ErrVotacaoArquivada = errors.New("votacao votos are archived")
+
+// ErrVotosNaoAgregados indica que a votação ainda tem votos sem lote de agregação, que
+// precisam estar nas agregações antes do arquivamento
+var ErrVotosNaoAgregados = errors.New("votos not aggregated yet")
+
+// Prefixo das partições mensais de votos, seguido do limite superior em AAAAMMDD
+const votoPartitionPrefix = "votos_p"
+
+// Número máximo de IDs de votos removidos por comando ao apagar os votos arquivados
+const archivePurgeBatchSize = 10000
+
+// Colunas do arquivo CSV dos votos arquivados, na ordem em que são gravadas
+var arquivoVotosHeader = []string{
+	"id", "participante_id", "votacao_id", "data_hora", "ip_hash", "user_agent_hash",
+	"device_id", "canal", "peso", "invalidado", "invalidacao_id",
+}
+
+// votoPartition é uma partição mensal de votos. A primeira não tem limite inferior.
+type votoPartition struct {
+	name         string
+	lower, upper time.Time
+}
+
+func votoPartitionName(upper time.Time) string {
+	return votoPartitionPrefix + upper.UTC().Format("20060102")
+}
+
+// scanVotoPartitions lê os nomes das partições, descartando as que não são mensais, como
+// a partição sem limite que recebe os votos depois da última
+func scanVotoPartitions(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if _, ok := votoPartitionUpper(name); ok {
+			names = append(names, name)
+		}
+	}
+	return names, rows.Err()
+}
+
+func votoPartitionUpper(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, votoPartitionPrefix) {
+		return time.Time{}, false
+	}
+	upper, err := time.Parse("20060102", strings.TrimPrefix(name, votoPartitionPrefix))
+	return upper, err == nil
+}
+
+// votoPartitions retorna as partições mensais de votos em ordem, com os limites de cada uma
+func votoPartitions() ([]votoPartition, error) {
+	names, err := dbDriver.VotoPartitions(DB)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]votoPartition, 0, len(names))
+	for _, name := range names {
+		upper, _ := votoPartitionUpper(name)
+		partitions = append(partitions, votoPartition{name: name, upper: upper})
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].upper.Before(partitions[j].upper) })
+	for i := 1; i < len(partitions); i++ {
+		partitions[i].lower = partitions[i-1].upper
+	}
+	return partitions, nil
+}
+
+// EnsureVotoPartitions cria as partições mensais de votos que faltam até until, para que
+// os votos novos não fiquem na partição sem limite. Sem partições, não faz nada. Retorna
+// os nomes das partições criadas.
+func EnsureVotoPartitions(until time.Time) ([]string, error) {
+	partitions, err := votoPartitions()
+	if err != nil || len(partitions) == 0 {
+		return nil, err
+	}
+
+	var created []string
+	for last := partitions[len(partitions)-1].upper; last.Before(until); {
+		next := last.AddDate(0, 1, 0)
+		if err := dbDriver.AddVotoPartition(DB, last, next); err != nil {
+			return created, err
+		}
+		created = append(created, votoPartitionName(next))
+		last = next
+	}
+	return created, nil
+}
+
+// DropArchivedVotoPartitions remove as partições de votos que terminam até before e só
+// têm votos de votações arquivadas ou removidas. Retorna os nomes das partições removidas.
+func DropArchivedVotoPartitions(before time.Time) ([]string, error) {
+	partitions, err := votoPartitions()
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped []string
+	for _, partition := range partitions {
+		if partition.upper.After(before) {
+			break
+		}
+
+		where, args := "data_hora < ?", []interface{}{partition.upper}
+		if !partition.lower.IsZero() {
+			where, args = "data_hora >= ? AND "+where, []interface{}{partition.lower, partition.upper}
+		}
+
+		var live bool
+		err := DB.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM votos
+				WHERE `+where+` AND votacao_id IN (SELECT id FROM votacoes WHERE arquivada_em IS NULL)
+			)
+		`, args...).Scan(&live)
+		if err != nil {
+			return dropped, err
+		}
+		if live {
+			continue
+		}
+
+		if err := dbDriver.DropVotoPartition(DB, partition.name); err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, partition.name)
+	}
+	return dropped, nil
+}
+
+// GetArchivableVotacaoIDs retorna as votações encerradas antes de before cujos votos ainda
+// não foram arquivados
+func GetArchivableVotacaoIDs(before time.Time) ([]int64, error) {
+	rows, err := DB.Query(
+		"SELECT id FROM votacoes WHERE encerrada_em < ? AND arquivada_em IS NULL ORDER BY id", before,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ArchiveVotos grava os votos da votação encerrada em um CSV compactado com gzip, no
+// armazenamento de arquivos, e marca a votação como arquivada. As agregações ficam como
+// estão e passam a ser a única fonte dos totais; os votos são removidos depois, com as
+// partições ou por PurgeArchivedVotos. Retorna ErrVotosNaoAgregados se o agregador ainda
+// não tiver contado todos os votos da votação.
+func ArchiveVotos(ctx context.Context, votacaoID int64) (*entities.ArquivoVotos, error) {
+	arquivo := &entities.ArquivoVotos{VotacaoID: votacaoID}
+	err := RunInTx(func(tx *Tx) error {
+		// A trava impede que outra réplica arquive a votação ao mesmo tempo e que os
+		// votos sejam invalidados durante o arquivamento
+		var arquivadaEm *time.Time
+		err := tx.QueryRow(
+			"SELECT tenant_id, arquivada_em FROM votacoes WHERE id = ? "+dbDriver.ForUpdate(), votacaoID,
+		).Scan(&arquivo.TenantID, &arquivadaEm)
+		if err != nil {
+			return err
+		}
+		if arquivadaEm != nil {
+			return ErrVotacaoArquivada
+		}
+
+		var pendentes bool
+		err = tx.QueryRow(
+			"SELECT EXISTS(SELECT 1 FROM votos WHERE votacao_id = ? AND lote_agregacao IS NULL)", votacaoID,
+		).Scan(&pendentes)
+		if err != nil {
+			return err
+		}
+		if pendentes {
+			return ErrVotosNaoAgregados
+		}
+
+		data, votos, err := exportVotos(tx, votacaoID)
+		if err != nil {
+			return err
+		}
+
+		// A chave é sempre a mesma para a votação: uma tentativa repetida sobrescreve o arquivo
+		arquivo.Arquivo = fmt.Sprintf("votos/%d/votacao-%d.csv.gz", arquivo.TenantID, votacaoID)
+		arquivo.Votos = votos
+		if err := Arquivos.Put(ctx, arquivo.Arquivo, Blob{Data: data, ContentType: "application/gzip"}); err != nil {
+			return err
+		}
+
+		arquivo.ArquivadaEm = time.Now().UTC()
+		_, err = tx.Exec(
+			"UPDATE votacoes SET arquivada_em = ?, arquivo_votos = ? WHERE id = ?",
+			arquivo.ArquivadaEm, arquivo.Arquivo, votacaoID,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return arquivo, nil
+}
+
+// exportVotos escreve os votos da votação, em ordem de ID, como CSV compactado com gzip
+func exportVotos(tx *Tx, votacaoID int64) ([]byte, int64, error) {
+	rows, err := tx.Query(`
+		SELECT id, participante_id, votacao_id, data_hora, ip_hash, user_agent_hash,
+			device_id, canal, peso, invalidado, invalidacao_id
+		FROM votos
+		WHERE votacao_id = ?
+		ORDER BY id
+	`, votacaoID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := csv.NewWriter(gz)
+	if err := w.Write(arquivoVotosHeader); err != nil {
+		return nil, 0, err
+	}
+
+	var votos int64
+	for rows.Next() {
+		var id, participanteID, votacaoID int64
+		var dataHora time.Time
+		var ipHash, userAgentHash, deviceID, canal sql.NullString
+		var peso int
+		var invalidado bool
+		var invalidacaoID sql.NullInt64
+		err := rows.Scan(&id, &participanteID, &votacaoID, &dataHora, &ipHash, &userAgentHash,
+			&deviceID, &canal, &peso, &invalidado, &invalidacaoID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var invalidacao string
+		if invalidacaoID.Valid {
+			invalidacao = strconv.FormatInt(invalidacaoID.Int64, 10)
+		}
+		err = w.Write([]string{
+			strconv.FormatInt(id, 10), strconv.FormatInt(participanteID, 10), strconv.FormatInt(votacaoID, 10),
+			dataHora.UTC().Format(time.RFC3339), ipHash.String, userAgentHash.String,
+			deviceID.String, canal.String, strconv.Itoa(peso), strconv.FormatBool(invalidado), invalidacao,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		votos++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), votos, nil
+}
+
+// PurgeArchivedVotos remove, em lotes de IDs, os votos das votações arquivadas que ficaram
+// em partições que ainda não podem ser removidas, ou em bancos sem partições. Retorna
+// quantos votos foram removidos.
+func PurgeArchivedVotos() (int64, error) {
+	type idRange struct{ votacaoID, first, last int64 }
+
+	rows, err := DB.Query(`
+		SELECT votacao_id, MIN(id), MAX(id)
+		FROM votos
+		WHERE votacao_id IN (SELECT id FROM votacoes WHERE arquivada_em IS NOT NULL)
+		GROUP BY votacao_id
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var ranges []idRange
+	for rows.Next() {
+		var r idRange
+		if err := rows.Scan(&r.votacaoID, &r.first, &r.last); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ranges = append(ranges, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, r := range ranges {
+		for first := r.first; first <= r.last; first += archivePurgeBatchSize {
+			result, err := DB.Exec(
+				"DELETE FROM votos WHERE votacao_id = ? AND id >= ? AND id < ?",
+				r.votacaoID, first, first+archivePurgeBatchSize,
+			)
+			if err != nil {
+				return purged, err
+			}
+			deleted, err := result.RowsAffected()
+			if err != nil {
+				return purged, err
+			}
+			purged += deleted
+		}
+	}
+	return purged, nil
+}
+
+// lockVotacaoVotos trava a votação do tenant em modo compartilhado até o fim da transação,
+// para que ela não seja arquivada enquanto os seus votos são alterados. Retorna ErrNotFound
+// se a votação não for do tenant e ErrVotacaoArquivada se os votos já tiverem sido arquivados.
+func lockVotacaoVotos(tx *Tx, tenantID, votacaoID int64) error {
+	var arquivada bool
+	err := tx.QueryRow(
+		"SELECT arquivada_em IS NOT NULL FROM votacoes WHERE id = ? AND tenant_id = ? "+dbDriver.ForShare(),
+		votacaoID, tenantID,
+	).Scan(&arquivada)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if arquivada {
+		return ErrVotacaoArquivada
+	}
+	return nil
+}
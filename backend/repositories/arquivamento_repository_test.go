@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+func TestArchivedVotacaoTotalsComeFromRollups(t *testing.T) {
+	previous := Arquivos
+	Arquivos = NewLocalBlobStore(t.TempDir())
+	t.Cleanup(func() { Arquivos = previous })
+
+	votacao, participante := newTestVotacao(t)
+	for _, peso := range []int{1, 2, 3} {
+		voto := &entities.Voto{Participante: participante, Votacao: votacao, Peso: peso}
+		if _, err := SaveVoto(entities.DefaultTenantID, voto); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// O arquivamento espera o agregador contar todos os votos
+	if _, err := ArchiveVotos(context.Background(), votacao.ID); !errors.Is(err, ErrVotosNaoAgregados) {
+		t.Fatalf("ArchiveVotos before aggregating: got %v, want ErrVotosNaoAgregados", err)
+	}
+	for {
+		processed, err := AggregateNewVotos()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if processed == 0 {
+			break
+		}
+	}
+
+	arquivo, err := ArchiveVotos(context.Background(), votacao.ID)
+	if err != nil {
+		t.Fatalf("ArchiveVotos: %v", err)
+	}
+	if arquivo.Votos != 3 {
+		t.Errorf("archived %d votos, want 3", arquivo.Votos)
+	}
+	if _, err := Arquivos.Get(context.Background(), arquivo.Arquivo); err != nil {
+		t.Errorf("archive file %s: %v", arquivo.Arquivo, err)
+	}
+	if _, err := ArchiveVotos(context.Background(), votacao.ID); !errors.Is(err, ErrVotacaoArquivada) {
+		t.Errorf("second ArchiveVotos: got %v, want ErrVotacaoArquivada", err)
+	}
+
+	if _, err := PurgeArchivedVotos(); err != nil {
+		t.Fatalf("PurgeArchivedVotos: %v", err)
+	}
+	if n := len(GetVotosByVotacaoID(entities.DefaultTenantID, votacao.ID)); n != 0 {
+		t.Fatalf("%d votos left after the purge, want none", n)
+	}
+
+	// Sem os votos, os totais finais vêm das agregações
+	totals, err := GetFinalTotalsByParticipante(entities.DefaultTenantID, votacao.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 1 || totals[0].Total != 3 || totals[0].Pontos != 6 {
+		t.Errorf("final totals = %+v, want 3 votos and 6 pontos", totals)
+	}
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path"
+	"strings"
+)
+
+// ErrBlobNotFound indica que não há arquivo gravado com a chave pedida
+var ErrBlobNotFound = errors.New("blob not found")
+
+// ErrInvalidBlobKey indica uma chave vazia, absoluta ou com componentes relativos
+var ErrInvalidBlobKey = errors.New("invalid blob key")
+
+// Blob é o conteúdo de um arquivo gravado e o seu tipo
+type Blob struct {
+	Data        []byte
+	ContentType string
+}
+
+// BlobStore grava arquivos por chave, no formato "diretorio/arquivo.ext"
+type BlobStore interface {
+	Put(ctx context.Context, key string, blob Blob) error
+	Get(ctx context.Context, key string) (*Blob, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Fotos é o armazenamento das fotos dos participantes
+var Fotos BlobStore
+
+// Arquivos é o armazenamento dos votos arquivados pelo job de retenção. Fica separado das
+// fotos, que são servidas publicamente.
+var Arquivos BlobStore
+
+// InitBlobStore configura o armazenamento das fotos a partir de FOTO_STORAGE: "local"
+// (padrão), em FOTO_STORAGE_DIR, ou "s3", em um serviço compatível com o S3. Os votos
+// arquivados seguem ARCHIVE_STORAGE, com o mesmo padrão, em ARCHIVE_STORAGE_DIR ou no
+// bucket ARCHIVE_S3_BUCKET.
+func InitBlobStore() {
+	Fotos = newBlobStore("fotos", getEnv("FOTO_STORAGE", "local"),
+		getEnv("FOTO_STORAGE_DIR", "data/fotos"), getEnv("S3_BUCKET", "paredao"))
+	Arquivos = newBlobStore("archived votos", getEnv("ARCHIVE_STORAGE", getEnv("FOTO_STORAGE", "local")),
+		getEnv("ARCHIVE_STORAGE_DIR", "data/arquivos"), getEnv("ARCHIVE_S3_BUCKET", "paredao-arquivos"))
+}
+
+func newBlobStore(what, storage, dir, bucket string) BlobStore {
+	switch storage {
+	case "s3":
+		log.Printf("Storing %s in S3-compatible storage", what)
+		return NewS3BlobStore(S3Config{
+			Endpoint:        getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          bucket,
+			AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		})
+	default:
+		if storage != "local" {
+			log.Printf("Unknown storage %q for %s, using local storage", storage, what)
+		}
+		log.Printf("Storing %s in local filesystem", what)
+		return NewLocalBlobStore(dir)
+	}
+}
+
+// validBlobKey impede que uma chave aponte para fora do armazenamento
+func validBlobKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "/") && path.Clean(key) == key && !strings.HasPrefix(key, "..")
+}
@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalBlobStore(t.TempDir())
+	key := "participantes/1/2/abc/media.jpg"
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("Get before Put: got %v, want ErrBlobNotFound", err)
+	}
+
+	if err := store.Put(ctx, key, Blob{Data: []byte("foto"), ContentType: "image/jpeg"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	blob, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(blob.Data) != "foto" || blob.ContentType != "image/jpeg" {
+		t.Errorf("Get: got %q %q, want %q %q", blob.Data, blob.ContentType, "foto", "image/jpeg")
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Get after Delete: got %v, want ErrBlobNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestBlobStoresRejectInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]BlobStore{
+		"local": NewLocalBlobStore(t.TempDir()),
+		// Nenhuma requisição deve chegar ao endereço, que não existe
+		"s3": NewS3BlobStore(S3Config{Endpoint: "http://127.0.0.1:1", Region: "us-east-1", Bucket: "b"}),
+	}
+
+	for name, store := range stores {
+		for _, key := range []string{"", "/etc/passwd", "../fora", "a/../../fora", "a//b", "a/./b"} {
+			if err := store.Put(ctx, key, Blob{Data: []byte("x")}); !errors.Is(err, ErrInvalidBlobKey) {
+				t.Errorf("%s Put(%q): got %v, want ErrInvalidBlobKey", name, key, err)
+			}
+			if _, err := store.Get(ctx, key); !errors.Is(err, ErrInvalidBlobKey) {
+				t.Errorf("%s Get(%q): got %v, want ErrInvalidBlobKey", name, key, err)
+			}
+			if err := store.Delete(ctx, key); !errors.Is(err, ErrInvalidBlobKey) {
+				t.Errorf("%s Delete(%q): got %v, want ErrInvalidBlobKey", name, key, err)
+			}
+		}
+	}
+}
+
+// fakeS3 é um bucket em memória que só aceita requisições com a assinatura AWS Signature V4
+// correta, recalculada a partir da requisição recebida. Sem t, as requisições recusadas
+// não falham o teste.
+type fakeS3 struct {
+	t       *testing.T
+	config  S3Config
+	mu      sync.Mutex
+	objects map[string]Blob
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := f.verify(r, body); err != nil {
+		if f.t != nil {
+			f.t.Errorf("%s %s: %v", r.Method, r.URL.EscapedPath(), err)
+		}
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	objectPath := r.URL.EscapedPath()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[objectPath] = Blob{Data: body, ContentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		blob, found := f.objects[objectPath]
+		if !found {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", blob.ContentType)
+		w.Write(blob.Data)
+	case http.MethodDelete:
+		delete(f.objects, objectPath)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) verify(r *http.Request, body []byte) error {
+	payloadHash := hex.EncodeToString(sha256Sum(body))
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		return errors.New("X-Amz-Content-Sha256 does not match the body")
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return errors.New("missing or malformed X-Amz-Date")
+	}
+	if d := time.Since(signedAt); d < -time.Minute || d > 15*time.Minute {
+		return errors.New("X-Amz-Date is out of range")
+	}
+
+	date := amzDate[:8]
+	scope := date + "/" + f.config.Region + "/s3/aws4_request"
+	prefix := "AWS4-HMAC-SHA256 Credential=" + f.config.AccessKeyID + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, prefix) {
+		return errors.New("unexpected Authorization: " + authorization)
+	}
+
+	canonicalRequest := r.Method + "\n" +
+		r.URL.EscapedPath() + "\n" +
+		r.URL.RawQuery + "\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		payloadHash
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" +
+		hex.EncodeToString(sha256Sum([]byte(canonicalRequest)))
+
+	key := []byte("AWS4" + f.config.SecretAccessKey)
+	for _, part := range []string{date, f.config.Region, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	if strings.TrimPrefix(authorization, prefix) != hex.EncodeToString(key) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func TestS3BlobStore(t *testing.T) {
+	ctx := context.Background()
+	config := S3Config{
+		Region:          "sa-east-1",
+		Bucket:          "paredao-fotos",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	fake := &fakeS3{t: t, config: config, objects: map[string]Blob{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	config.Endpoint = server.URL + "/"
+	store := NewS3BlobStore(config)
+
+	// A chave tem caracteres que a assinatura exige codificados
+	key := "participantes/1/2/abc/foto grande+1=2.jpg"
+	data := []byte("conteúdo da foto")
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("Get before Put: got %v, want ErrBlobNotFound", err)
+	}
+
+	if err := store.Put(ctx, key, Blob{Data: data, ContentType: "image/jpeg"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, found := fake.objects["/paredao-fotos/participantes/1/2/abc/foto%20grande%2B1%3D2.jpg"]; !found {
+		t.Errorf("Put: object not stored under the escaped path, got %v", fake.objects)
+	}
+
+	blob, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !bytes.Equal(blob.Data, data) || blob.ContentType != "image/jpeg" {
+		t.Errorf("Get: got %q %q, want %q %q", blob.Data, blob.ContentType, data, "image/jpeg")
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Get after Delete: got %v, want ErrBlobNotFound", err)
+	}
+}
+
+func TestS3BlobStoreWrongCredentials(t *testing.T) {
+	config := S3Config{Region: "sa-east-1", Bucket: "b", AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "certa"}
+	fake := &fakeS3{config: config, objects: map[string]Blob{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	config.Endpoint = server.URL
+	config.SecretAccessKey = "errada"
+	err := NewS3BlobStore(config).Put(context.Background(), "a.jpg", Blob{Data: []byte("x")})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with a wrong secret: got %v, want a 403 error", err)
+	}
+}
@@ -2,7 +2,6 @@ package repositories
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -13,11 +12,33 @@ import (
 // TTL do Cache
 const cacheTTL = 1 * time.Second
 
+// Versão do formato dos valores em cache. Deve ser incrementada sempre que uma
+// entidade armazenada mudar de forma incompatível.
+const cacheSchemaVersion = 5
+
+// TTL das leituras de participantes e votações, que só mudam por ações administrativas
+// e são invalidadas explicitamente
+const EntityCacheTTL = 5 * time.Minute
+
 // Prefixos das chaves de cache
 const (
 	TotalCacheKey       = "stats:total:%d"
 	ParticipantCacheKey = "stats:participant:%d"
 	HourlyCacheKey      = "stats:hourly:%d"
+	MinuteCacheKey      = "stats:minute:%d"
+	// Totais finais de uma votação encerrada, contados no primário
+	ResultadoCacheKey = "stats:resultado:%d"
+
+	ParticipantesCacheKey        = "participantes:all"
+	ParticipanteCacheKey         = "participante:%d"
+	VotacoesCacheKey             = "votacoes:all"
+	VotacaoCacheKey              = "votacao:%d"
+	VotacaoParticipantesCacheKey = "votacao:%d:participantes"
+
+	// Buscas de tenant, feitas antes de o tenant da requisição ser conhecido
+	TenantHostCacheKey   = "tenants:host:%s"
+	TenantAPIKeyCacheKey = "tenants:apikey:%s"
+	TenantSlugCacheKey   = "tenants:slug:%s"
 )
 
 // RedisClient é o cliente Redis global
@@ -47,54 +68,96 @@ func InitRedis(host, port string) {
 		log.Printf("Warning: Redis connection failed: %v. Continuing without cache.", err)
 	} else {
 		log.Println("Connected to Redis successfully")
+		subscribeInvalidations()
+		subscribeStatsUpdates()
 	}
 }
 
 func CloseRedis() {
+	if invalidationSub != nil {
+		invalidationSub.Close()
+	}
+	if statsUpdatesSub != nil {
+		statsUpdatesSub.Close()
+	}
 	if RedisClient != nil {
 		RedisClient.Close()
 		log.Println("Redis connection closed")
 	}
 }
 
-func GetFromCache(ctx context.Context, key string, result interface{}) (bool, error) {
+// GetFromCache busca a chave no cache e a decodifica no tipo T
+func GetFromCache[T any](ctx context.Context, key string) (T, bool, error) {
+	var result T
+
+	data, found, err := getRaw(ctx, storageKey(scopedKey(ctx, key)))
+	if !found || err != nil {
+		return result, false, err
+	}
+
+	// Desserializa os dados em cache
+	if err := CacheCodec.Unmarshal(data, &result); err != nil {
+		return result, false, err
+	}
+
+	return result, true, nil
+}
+
+// SetCache grava um valor do tipo T no cache
+func SetCache[T any](ctx context.Context, key string, value T) error {
+	// Serializa os dados
+	data, err := CacheCodec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return setRaw(ctx, storageKey(scopedKey(ctx, key)), data, cacheTTL)
+}
+
+// storageKey acrescenta à chave a versão do esquema e o codec, para que mudanças
+// em qualquer um deles nunca leiam entradas incompatíveis
+func storageKey(key string) string {
+	return fmt.Sprintf("v%d:%s:%s", cacheSchemaVersion, CacheCodec.Name(), key)
+}
+
+func getRaw(ctx context.Context, key string) ([]byte, bool, error) {
+	// Consulta primeiro o cache em memória, evitando a ida ao Redis
+	if data, found := LocalCache.Get(key); found {
+		return data, true, nil
+	}
+
 	if RedisClient == nil {
-		return false, nil
+		// Sem Redis, o armazenamento em memória faz o papel dele
+		data, found := memStore.Get(key)
+		if found {
+			LocalCache.Set(key, data)
+		}
+		return data, found, nil
 	}
 
-	data, err := RedisClient.Get(ctx, key).Result()
+	data, err := RedisClient.Get(ctx, key).Bytes()
 	if err == redis.Nil {
 		// Chave não existe no cache
-		return false, nil
+		return nil, false, nil
 	} else if err != nil {
 		// Erro ao acessar o Redis
 		log.Printf("Redis error: %v", err)
-		return false, err
-	}
-
-	// Desserializa os dados em cache
-	err = json.Unmarshal([]byte(data), result)
-	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 
-	return true, nil
+	LocalCache.Set(key, data)
+	return data, true, nil
 }
 
-func SetCache(ctx context.Context, key string, data interface{}) error {
+func setRaw(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	LocalCache.Set(key, data)
 	if RedisClient == nil {
+		memStore.Set(key, data, ttl)
 		return nil
 	}
 
-	// Serializa os dados
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-
 	// Define no Redis com TTL
-	err = RedisClient.Set(ctx, key, jsonData, cacheTTL).Err()
-	if err != nil {
+	if err := RedisClient.Set(ctx, key, data, ttl).Err(); err != nil {
 		log.Printf("Redis set error: %v", err)
 		return err
 	}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec serializa os valores gravados no cache
+type Codec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+
+// Usa as tags json das entidades para que os nomes dos campos sejam os mesmos nos dois codecs
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// Codecs disponíveis, selecionáveis por CACHE_CODEC
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+)
+
+// CacheCodec é o codec usado para ler e gravar o cache
+var CacheCodec = JSONCodec
+
+// InitCacheCodec seleciona o codec do cache a partir das variáveis de ambiente
+func InitCacheCodec() {
+	switch name := getEnv("CACHE_CODEC", JSONCodec.Name()); name {
+	case JSONCodec.Name():
+		CacheCodec = JSONCodec
+	case MsgpackCodec.Name():
+		CacheCodec = MsgpackCodec
+	default:
+		log.Printf("Unknown cache codec %q, using %s", name, JSONCodec.Name())
+		CacheCodec = JSONCodec
+	}
+}
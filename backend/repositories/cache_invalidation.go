@@ -0,0 +1,218 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Canal pelo qual as réplicas avisam umas às outras sobre chaves invalidadas
+const cacheInvalidationChannel = "cache:invalidate"
+
+// Tags que agrupam as chaves de cache por entidade
+const (
+	ParticipantesTag = "participantes"
+	VotacoesTag      = "votacoes"
+	TenantsTag       = "tenants"
+	participanteTag  = "participante:%d"
+	votacaoTag       = "votacao:%d"
+)
+
+var invalidationSub *redis.PubSub
+
+// Índice em memória de tags para chaves, usado quando o Redis não está disponível. Cada
+// chave guarda quando vence no armazenamento em memória, e as vencidas são removidas do
+// índice de tempos em tempos, para que ele não cresça com chaves que já não existem.
+var localTags = struct {
+	sync.Mutex
+	keys      map[string]map[string]time.Time
+	lastSweep time.Time
+}{keys: make(map[string]map[string]time.Time), lastSweep: time.Now()}
+
+// ParticipanteTag agrupa todas as chaves que contêm dados de um participante
+func ParticipanteTag(participanteID int64) string {
+	return fmt.Sprintf(participanteTag, participanteID)
+}
+
+// VotacaoTag agrupa todas as chaves que contêm dados de uma votação
+func VotacaoTag(votacaoID int64) string {
+	return fmt.Sprintf(votacaoTag, votacaoID)
+}
+
+// InvalidateVotacaoCache remove do cache todas as chaves de uma votação
+func InvalidateVotacaoCache(ctx context.Context, votacaoID int64) error {
+	return InvalidateCacheTags(ctx, VotacaoTag(votacaoID), VotacoesTag)
+}
+
+// InvalidateParticipanteCache remove do cache todas as chaves de um participante,
+// incluindo as das votações das quais ele participa (as estatísticas exibem o nome)
+func InvalidateParticipanteCache(ctx context.Context, participanteID int64, votacaoIDs []int64) error {
+	tags := []string{ParticipanteTag(participanteID), ParticipantesTag}
+	for _, votacaoID := range votacaoIDs {
+		tags = append(tags, VotacaoTag(votacaoID))
+	}
+	return InvalidateCacheTags(ctx, tags...)
+}
+
+// InvalidateCacheTags remove do Redis e do cache em memória de todas as réplicas
+// as chaves associadas às tags informadas, no tenant da requisição
+func InvalidateCacheTags(ctx context.Context, tags ...string) error {
+	notePrimaryWrite()
+	tags = scopedTags(ctx, tags)
+	keys := takeLocalTagKeys(tags)
+
+	if RedisClient != nil {
+		// Lê e remove os conjuntos de cada tag atomicamente, para não perder
+		// chaves registradas entre a leitura e a remoção
+		members := make([]*redis.StringSliceCmd, len(tags))
+		_, err := RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, tag := range tags {
+				members[i] = pipe.SMembers(ctx, tagStorageKey(tag))
+				pipe.Del(ctx, tagStorageKey(tag))
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Redis tag read error: %v", err)
+			return err
+		}
+
+		for _, cmd := range members {
+			keys = append(keys, cmd.Val()...)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return invalidateKeys(ctx, keys)
+}
+
+// tagKey associa uma chave já gravada às suas tags
+func tagKey(ctx context.Context, key string, tags []string, ttl time.Duration) {
+	if len(tags) == 0 {
+		return
+	}
+
+	if RedisClient == nil {
+		addLocalTagKey(key, tags, ttl)
+		return
+	}
+
+	// O conjunto de cada tag vive pelo menos tanto quanto a chave mais longa associada a ele
+	_, err := RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			pipe.SAdd(ctx, tagStorageKey(tag), key)
+			pipe.ExpireNX(ctx, tagStorageKey(tag), ttl)
+			pipe.ExpireGT(ctx, tagStorageKey(tag), ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Redis tag write error: %v", err)
+	}
+}
+
+func tagStorageKey(tag string) string {
+	return storageKey("tag:" + tag)
+}
+
+func addLocalTagKey(key string, tags []string, ttl time.Duration) {
+	localTags.Lock()
+	defer localTags.Unlock()
+
+	now := time.Now()
+	sweepLocalTags(now)
+	for _, tag := range tags {
+		if localTags.keys[tag] == nil {
+			localTags.keys[tag] = make(map[string]time.Time)
+		}
+		localTags.keys[tag][key] = now.Add(ttl)
+	}
+}
+
+func takeLocalTagKeys(tags []string) []string {
+	localTags.Lock()
+	defer localTags.Unlock()
+
+	keys := []string{}
+	for _, tag := range tags {
+		for key := range localTags.keys[tag] {
+			keys = append(keys, key)
+		}
+		delete(localTags.keys, tag)
+	}
+	return keys
+}
+
+// sweepLocalTags remove do índice, no máximo uma vez por memoryStoreSweepInterval, as
+// chaves que já venceram e as tags que ficaram sem chaves
+func sweepLocalTags(now time.Time) {
+	if now.Sub(localTags.lastSweep) < memoryStoreSweepInterval {
+		return
+	}
+	localTags.lastSweep = now
+
+	for tag, keys := range localTags.keys {
+		for key, expiresAt := range keys {
+			if !now.Before(expiresAt) {
+				delete(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			delete(localTags.keys, tag)
+		}
+	}
+}
+
+// invalidateKeys remove chaves já prefixadas do Redis e avisa as demais réplicas
+func invalidateKeys(ctx context.Context, keys []string) error {
+	LocalCache.Delete(keys...)
+	if RedisClient == nil {
+		memStore.Del(keys...)
+		return nil
+	}
+
+	if err := RedisClient.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("Redis delete error: %v", err)
+		return err
+	}
+
+	message, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	if err := RedisClient.Publish(ctx, cacheInvalidationChannel, message).Err(); err != nil {
+		log.Printf("Redis publish error: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// subscribeInvalidations escuta o canal de invalidação e remove do cache em
+// memória as chaves alteradas por outras réplicas
+func subscribeInvalidations() {
+	invalidationSub = RedisClient.Subscribe(context.Background(), cacheInvalidationChannel)
+
+	go func() {
+		for msg := range invalidationSub.Channel() {
+			var keys []string
+			if err := json.Unmarshal([]byte(msg.Payload), &keys); err != nil {
+				log.Printf("Error decoding cache invalidation message: %v", err)
+				continue
+			}
+			// A alteração feita por outra réplica da aplicação também afasta as leituras
+			// das réplicas do banco até que elas a recebam
+			notePrimaryWrite()
+			LocalCache.Delete(keys...)
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLocalTagsDropExpiredKeys(t *testing.T) {
+	ctx := context.Background()
+	tag := testCacheKey(t)
+
+	tagKey(ctx, "vencida", []string{tag}, time.Millisecond)
+	tagKey(ctx, "atual", []string{tag}, time.Hour)
+	tagKey(ctx, "sozinha", []string{tag + ":outra"}, time.Millisecond)
+
+	// Força a próxima gravação a varrer o índice
+	time.Sleep(5 * time.Millisecond)
+	localTags.Lock()
+	localTags.lastSweep = time.Time{}
+	localTags.Unlock()
+	tagKey(ctx, "nova", []string{tag + ":nova"}, time.Hour)
+
+	localTags.Lock()
+	_, vencida := localTags.keys[tag]["vencida"]
+	_, atual := localTags.keys[tag]["atual"]
+	_, sozinha := localTags.keys[tag+":outra"]
+	localTags.Unlock()
+
+	if vencida {
+		t.Error("expired key is still indexed")
+	}
+	if !atual {
+		t.Error("live key was dropped from the index")
+	}
+	if sozinha {
+		t.Error("tag without live keys is still indexed")
+	}
+
+	if keys := takeLocalTagKeys([]string{tag}); len(keys) != 1 || keys[0] != "atual" {
+		t.Errorf("takeLocalTagKeys = %v, want [atual]", keys)
+	}
+}
@@ -0,0 +1,271 @@
+package repositories
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Por quanto tempo um valor vencido ainda pode ser servido enquanto é atualizado
+const staleTTL = 30 * time.Second
+
+// Variação aleatória somada ao TTL para que as chaves não vençam todas juntas
+const ttlJitter = 250 * time.Millisecond
+
+// Trava distribuída que impede várias réplicas de recalcular a mesma chave
+const (
+	lockTTL          = 2 * time.Second
+	lockWaitTimeout  = 500 * time.Millisecond
+	lockPollInterval = 25 * time.Millisecond
+)
+
+// CacheOptions define por quanto tempo um valor é considerado atual e a quais
+// tags ele pertence. Invalidar uma tag remove todas as chaves associadas a ela.
+type CacheOptions struct {
+	TTL  time.Duration
+	Tags []string
+}
+
+func (o CacheOptions) ttl() time.Duration {
+	if o.TTL <= 0 {
+		return cacheTTL
+	}
+	return o.TTL
+}
+
+// Tamanho do cabeçalho do envelope: o instante, em milissegundos, até o qual o valor é atual
+const envelopeHeaderSize = 8
+
+// Contadores de uso do cache
+var cacheStats struct {
+	hits      atomic.Uint64
+	staleHits atomic.Uint64
+	misses    atomic.Uint64
+	loads     atomic.Uint64
+	coalesced atomic.Uint64
+	lockWaits atomic.Uint64
+}
+
+// Agrupa cargas concorrentes da mesma chave dentro deste processo
+var loadGroup singleflight.Group
+
+// Chaves com uma atualização em segundo plano em andamento neste processo
+var refreshing sync.Map
+
+// Remove a trava apenas se ela ainda pertencer a quem a adquiriu. Uma carga mais longa
+// que lockTTL perde a trava para outra réplica, e a remoção não pode apagar a trava nova.
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// GetOrLoadCache busca a chave no cache e, se não existir, executa load uma única vez
+// para todas as requisições concorrentes. Valores vencidos são servidos enquanto uma
+// única atualização acontece em segundo plano. O valor é sempre decodificado em T, de
+// modo que a resposta é idêntica com ou sem acerto no cache. A chave e as tags são
+// separadas por tenant.
+func GetOrLoadCache[T any](ctx context.Context, key string, opts CacheOptions, load func() (T, error)) (T, error) {
+	var result T
+
+	opts.Tags = scopedTags(ctx, opts.Tags)
+	data, err := getOrLoadRaw(ctx, storageKey(scopedKey(ctx, key)), opts, func() ([]byte, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return CacheCodec.Marshal(value)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = CacheCodec.Unmarshal(data, &result)
+	return result, err
+}
+
+// GetCacheStats retorna os contadores de uso do cache desde o início do processo
+func GetCacheStats() entities.CacheStatsResponse {
+	return entities.CacheStatsResponse{
+		Hits:      cacheStats.hits.Load(),
+		StaleHits: cacheStats.staleHits.Load(),
+		Misses:    cacheStats.misses.Load(),
+		Loads:     cacheStats.loads.Load(),
+		Coalesced: cacheStats.coalesced.Load(),
+		LockWaits: cacheStats.lockWaits.Load(),
+		Local:     LocalCache.Stats(),
+	}
+}
+
+func getOrLoadRaw(ctx context.Context, key string, opts CacheOptions, load func() ([]byte, error)) ([]byte, error) {
+	// A carga é compartilhada, então não pode ser cancelada pela requisição que a iniciou
+	ctx = context.WithoutCancel(ctx)
+
+	data, freshUntil, found := readEnvelope(ctx, key)
+	if found {
+		if time.Now().UnixMilli() < freshUntil {
+			cacheStats.hits.Add(1)
+		} else {
+			cacheStats.staleHits.Add(1)
+			refreshInBackground(ctx, key, opts, load)
+		}
+		return data, nil
+	}
+
+	// O Do informa shared também para a requisição que executou a carga, então só as que
+	// aguardaram o resultado de outra são contadas como agrupadas
+	cacheStats.misses.Add(1)
+	leader := false
+	value, err, _ := loadGroup.Do(key, func() (any, error) {
+		leader = true
+		return loadWithLock(ctx, key, opts, load)
+	})
+	if !leader {
+		cacheStats.coalesced.Add(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+// loadWithLock carrega o valor quando ele não existe no cache. Se outra réplica já
+// estiver carregando, aguarda brevemente pelo resultado dela antes de carregar também.
+func loadWithLock(ctx context.Context, key string, opts CacheOptions, load func() ([]byte, error)) ([]byte, error) {
+	if RedisClient != nil {
+		release, acquired, err := acquireLoadLock(ctx, key)
+		if err != nil {
+			log.Printf("Redis lock error: %v", err)
+		} else if acquired {
+			defer release()
+		} else {
+			cacheStats.lockWaits.Add(1)
+			if data, found := waitForEnvelope(ctx, key); found {
+				return data, nil
+			}
+		}
+	}
+
+	return loadAndStore(ctx, key, opts, load)
+}
+
+// refreshInBackground atualiza um valor vencido sem bloquear a requisição atual.
+// Apenas uma atualização por chave acontece no processo e, com a trava, entre réplicas.
+func refreshInBackground(ctx context.Context, key string, opts CacheOptions, load func() ([]byte, error)) {
+	if _, loaded := refreshing.LoadOrStore(key, struct{}{}); loaded {
+		cacheStats.coalesced.Add(1)
+		return
+	}
+
+	go func() {
+		defer refreshing.Delete(key)
+		// Sem uma requisição para recuperar o pânico, ele derrubaria o processo
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Cache refresh panic for %s: %v", key, r)
+			}
+		}()
+
+		if RedisClient != nil {
+			release, acquired, err := acquireLoadLock(ctx, key)
+			if err != nil {
+				log.Printf("Redis lock error: %v", err)
+				return
+			}
+			if !acquired {
+				// Outra réplica já está atualizando esta chave
+				return
+			}
+			defer release()
+		}
+		if _, err := loadAndStore(ctx, key, opts, load); err != nil {
+			log.Printf("Cache refresh error for %s: %v", key, err)
+		}
+	}()
+}
+
+// acquireLoadLock tenta adquirir a trava de carga da chave entre as réplicas. A trava guarda
+// um valor aleatório, e release só a remove se ela ainda guardar esse valor.
+func acquireLoadLock(ctx context.Context, key string) (release func(), acquired bool, err error) {
+	lockKey := key + ":lock"
+	token := make([]byte, 16)
+	if _, err := crand.Read(token); err != nil {
+		return nil, false, err
+	}
+	value := hex.EncodeToString(token)
+
+	acquired, err = RedisClient.SetNX(ctx, lockKey, value, lockTTL).Result()
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+
+	release = func() {
+		if err := releaseLockScript.Run(ctx, RedisClient, []string{lockKey}, value).Err(); err != nil {
+			log.Printf("Redis unlock error for %s: %v", lockKey, err)
+		}
+	}
+	return release, true, nil
+}
+
+func loadAndStore(ctx context.Context, key string, opts CacheOptions, load func() ([]byte, error)) ([]byte, error) {
+	cacheStats.loads.Add(1)
+	data, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	writeEnvelope(ctx, key, data, opts)
+	return data, nil
+}
+
+// readEnvelope lê o valor e o instante até o qual ele é considerado atual
+func readEnvelope(ctx context.Context, key string) ([]byte, int64, bool) {
+	raw, found, err := getRaw(ctx, key)
+	if err != nil || !found {
+		return nil, 0, false
+	}
+
+	if len(raw) < envelopeHeaderSize {
+		log.Printf("Invalid cache envelope for %s", key)
+		return nil, 0, false
+	}
+
+	freshUntil := int64(binary.BigEndian.Uint64(raw[:envelopeHeaderSize]))
+	return raw[envelopeHeaderSize:], freshUntil, true
+}
+
+func writeEnvelope(ctx context.Context, key string, data []byte, opts CacheOptions) {
+	freshTTL := opts.ttl() + rand.N(ttlJitter)
+
+	raw := make([]byte, envelopeHeaderSize, envelopeHeaderSize+len(data))
+	binary.BigEndian.PutUint64(raw, uint64(time.Now().Add(freshTTL).UnixMilli()))
+	raw = append(raw, data...)
+
+	if err := setRaw(ctx, key, raw, freshTTL+staleTTL); err == nil {
+		tagKey(ctx, key, opts.Tags, freshTTL+staleTTL)
+	}
+}
+
+// waitForEnvelope aguarda outra réplica gravar a chave, até lockWaitTimeout
+func waitForEnvelope(ctx context.Context, key string) ([]byte, bool) {
+	deadline := time.Now().Add(lockWaitTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(lockPollInterval)
+		if data, _, found := readEnvelope(ctx, key); found {
+			return data, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadCacheCoalescesConcurrentLoads(t *testing.T) {
+	const callers = 8
+	ctx := context.Background()
+	key := testCacheKey(t)
+	release := make(chan struct{})
+	var loads atomic.Int32
+	coalescedBefore := cacheStats.coalesced.Load()
+
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := GetOrLoadCache(ctx, key, CacheOptions{}, func() (int, error) {
+				loads.Add(1)
+				<-release
+				return 42, nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = value
+		}()
+	}
+
+	// Dá tempo para todas as chamadas aguardarem a mesma carga
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("load ran %d times, want 1", n)
+	}
+	for i, value := range results {
+		if value != 42 {
+			t.Errorf("caller %d got %d, want 42", i, value)
+		}
+	}
+	// A chamada que executou a carga não é contada como agrupada
+	if coalesced := cacheStats.coalesced.Load() - coalescedBefore; coalesced != callers-1 {
+		t.Errorf("coalesced = %d, want %d", coalesced, callers-1)
+	}
+}
+
+func TestGetOrLoadCachePanicReleasesWaiters(t *testing.T) {
+	const callers = 4
+	ctx := context.Background()
+	key := testCacheKey(t)
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	var panics atomic.Int32
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if recover() != nil {
+					panics.Add(1)
+				}
+			}()
+			GetOrLoadCache(ctx, key, CacheOptions{}, func() (int, error) {
+				<-release
+				panic("falha na carga")
+			})
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("callers waiting on a panicked load never returned")
+	}
+	if n := panics.Load(); n != callers {
+		t.Errorf("%d callers saw the panic, want %d", n, callers)
+	}
+
+	// Depois do pânico a chave pode ser carregada de novo
+	value, err := GetOrLoadCache(ctx, key, CacheOptions{}, func() (int, error) {
+		return 7, nil
+	})
+	if err != nil || value != 7 {
+		t.Errorf("reload after panic: got %d, %v", value, err)
+	}
+}
+
+func TestGetOrLoadCacheDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	key := testCacheKey(t)
+	errLoad := errors.New("falha")
+
+	_, err := GetOrLoadCache(ctx, key, CacheOptions{}, func() (int, error) { return 0, errLoad })
+	if !errors.Is(err, errLoad) {
+		t.Fatalf("got %v, want the load error", err)
+	}
+
+	value, err := GetOrLoadCache(ctx, key, CacheOptions{}, func() (int, error) { return 3, nil })
+	if err != nil || value != 3 {
+		t.Errorf("load after error: got %d, %v", value, err)
+	}
+}
+
+// testCacheKey gera uma chave nova a cada execução, já que o cache em memória é do pacote
+func testCacheKey(t *testing.T) string {
+	return fmt.Sprintf("teste:%s:%d", t.Name(), time.Now().UnixNano())
+}
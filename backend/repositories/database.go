@@ -2,46 +2,84 @@ package repositories
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 )
 
-var DB *sql.DB
+var DB *Database
 
+// Parâmetros padrão do pool de conexões
+const (
+	defaultMaxOpenConns    = 25
+	defaultConnMaxLifetime = 5 * time.Minute
+	defaultConnMaxIdleTime = time.Minute
+)
+
+// ErrNotFound indica que um registro usado pela operação não existe
+var ErrNotFound = errors.New("record not found")
+
+// execer executa comandos tanto direto no banco quanto dentro de uma transação
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryer consulta uma linha tanto direto no banco quanto dentro de uma transação
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// execQueryer executa comandos e consulta linhas, no banco ou em uma transação
+type execQueryer interface {
+	execer
+	queryer
+}
+
+// InitDB conecta ao banco escolhido por DB_DRIVER
 func InitDB() {
-	// Carrega o arquivo .env se existir
+	loadEnv()
+	connectDB(getEnv("DB_DRIVER", "mysql"))
+}
+
+// InitDevDB abre o banco embutido do modo de desenvolvimento, que dispensa servidores, e
+// cria os dados de demonstração na primeira execução
+func InitDevDB() {
+	loadEnv()
+	connectDB("sqlite")
+
+	if err := SeedDemoData(); err != nil {
+		log.Fatalf("Failed to seed demo data: %v", err)
+	}
+}
+
+// loadEnv carrega o arquivo .env se existir
+func loadEnv() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
+}
 
-	// Obtém detalhes de conexão do banco de dados das variáveis de ambiente
-	// com fallback para valores padrão
-	dbUser := getEnv("DB_USER", "root")
-	dbPassword := getEnv("DB_PASSWORD", "password")
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnv("DB_PORT", "3306")
-	dbName := getEnv("DB_NAME", "paredao")
-
-	// Cria string de conexão
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
-		dbUser, dbPassword, dbHost, dbPort, dbName)
+// connectDB abre o banco do driver informado, com os detalhes de conexão das variáveis
+// de ambiente, e aplica as migrações que o driver mantém
+func connectDB(driverName string) {
+	driver, err := selectDriver(driverName)
+	if err != nil {
+		log.Fatalf("Failed to select database driver: %v", err)
+	}
+	dbDriver = driver
 
 	// Abre conexão com o banco de dados
-	var err error
-	DB, err = sql.Open("mysql", dsn)
+	db, err := dbDriver.Open("")
 	if err != nil {
 		log.Fatalf("Failed to open database connection: %v", err)
 	}
+	DB = &Database{db}
 
-	// Define parâmetros do pool de conexões
-	DB.SetMaxOpenConns(25)
-	DB.SetMaxIdleConns(5)
-	DB.SetConnMaxLifetime(5 * time.Minute)
+	configurePool(DB.DB)
 
 	// Testa a conexão
 	err = DB.Ping()
@@ -49,11 +87,31 @@ func InitDB() {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
-	log.Println("Database connection established")
+	log.Printf("Database connection established (%s)", dbDriver.Name())
+
+	if err := dbDriver.Migrate(DB.DB); err != nil {
+		log.Fatalf("Failed to apply migrations: %v", err)
+	}
+
+	prepareStatements()
+	connectReplicas()
+}
+
+// configurePool define os parâmetros do pool de conexões a partir das variáveis de
+// ambiente. Por padrão as conexões ociosas ficam no pool até o limite de abertas, para
+// que os picos de votos não precisem abrir conexões nem preparar as consultas de novo.
+func configurePool(db *sql.DB) {
+	maxOpen := getEnvInt("DB_MAX_OPEN_CONNS", defaultMaxOpenConns)
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(getEnvInt("DB_MAX_IDLE_CONNS", maxOpen))
+	db.SetConnMaxLifetime(getEnvDuration("DB_CONN_MAX_LIFETIME", defaultConnMaxLifetime))
+	db.SetConnMaxIdleTime(getEnvDuration("DB_CONN_MAX_IDLE_TIME", defaultConnMaxIdleTime))
 }
 
 func CloseDB() {
 	if DB != nil {
+		closeStatements()
+		closeReplicas()
 		DB.Close()
 		log.Println("Database connection closed")
 	}
@@ -66,3 +124,21 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// Função auxiliar para obter variável de ambiente inteira com fallback
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Função auxiliar para obter variável de ambiente com duração (como "5m") e fallback
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Votos de demonstração da votação aberta, nas últimas horas, e da encerrada
+const (
+	demoVotosAbertos   = 1800
+	demoVotosEncerrada = 1200
+	demoDuracao        = 3 * time.Hour
+	// Número de origens distintas dos votos
+	demoOrigens = 300
+)
+
+// Perfis dos participantes criados pelas migrações
+var demoPerfis = []struct {
+	participanteID int64
+	apelido        string
+	cidade         string
+}{
+	{1, "Bach", "Leipzig"},
+	{2, "Il Prete Rosso", "Veneza"},
+	{3, "Ludwig", "Bonn"},
+}
+
+// demoVotacao descreve os votos de uma votação de demonstração: os participantes, o peso
+// de cada um na escolha dos votos e o período em que os votos foram dados
+type demoVotacao struct {
+	id              int64
+	participanteIDs []int64
+	preferencia     []int
+	inicio, fim     time.Time
+	votos           int
+}
+
+// SeedDemoData cria os dados de demonstração quando o banco ainda não tem temporadas: uma
+// temporada com os participantes das migrações, a votação das migrações aberta com votos
+// nas últimas horas e uma votação encerrada no dia anterior. Os votos são agregados pelo
+// agregador como quaisquer outros.
+func SeedDemoData() error {
+	var seeded bool
+	if err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM temporadas)").Scan(&seeded); err != nil {
+		return err
+	}
+	if seeded {
+		return nil
+	}
+
+	now := time.Now()
+	err := RunInTx(func(tx *Tx) error {
+		temporadaID, err := dbDriver.InsertID(tx,
+			"INSERT INTO temporadas (tenant_id, nome) VALUES (?, ?)", entities.DefaultTenantID, "Temporada de demonstração",
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, perfil := range demoPerfis {
+			_, err := tx.Exec(
+				"UPDATE participantes SET apelido = ?, cidade = ? WHERE id = ?",
+				perfil.apelido, perfil.cidade, perfil.participanteID,
+			)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(
+				"INSERT INTO temporada_participante (temporada_id, participante_id) VALUES (?, ?)",
+				temporadaID, perfil.participanteID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		// A votação das migrações passa a ser o paredão em andamento da temporada
+		_, err = tx.Exec("UPDATE votacoes SET temporada_id = ? WHERE id = ?", temporadaID, 1)
+		if err != nil {
+			return err
+		}
+
+		encerradaEm := now.Add(-24 * time.Hour)
+		encerradaID, err := dbDriver.InsertID(tx,
+			"INSERT INTO votacoes (tenant_id, descricao, encerrada_em, temporada_id) VALUES (?, ?, ?, ?)",
+			entities.DefaultTenantID, "Paredão de demonstração", encerradaEm, temporadaID,
+		)
+		if err != nil {
+			return err
+		}
+		for _, participanteID := range []int64{1, 3} {
+			_, err := tx.Exec(
+				"INSERT INTO votacao_participante (participante_id, votacao_id) VALUES (?, ?)",
+				participanteID, encerradaID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		rng := rand.New(rand.NewSource(1))
+		for _, votacao := range []demoVotacao{
+			{encerradaID, []int64{1, 3}, []int{2, 3}, encerradaEm.Add(-demoDuracao), encerradaEm, demoVotosEncerrada},
+			{1, []int64{1, 2, 3}, []int{5, 3, 2}, now.Add(-demoDuracao), now, demoVotosAbertos},
+		} {
+			if err := insertDemoVotos(tx, rng, votacao); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Println("Demo data created")
+	return nil
+}
+
+// insertDemoVotos grava os votos da votação espalhados pelo período, em ordem de data,
+// escolhendo o participante de cada um conforme a preferência
+func insertDemoVotos(tx *Tx, rng *rand.Rand, votacao demoVotacao) error {
+	soma := 0
+	for _, peso := range votacao.preferencia {
+		soma += peso
+	}
+
+	periodo := votacao.fim.Sub(votacao.inicio)
+	for i := 0; i < votacao.votos; i++ {
+		escolha, participanteID := rng.Intn(soma), votacao.participanteIDs[0]
+		for j, peso := range votacao.preferencia {
+			if escolha < peso {
+				participanteID = votacao.participanteIDs[j]
+				break
+			}
+			escolha -= peso
+		}
+
+		canal := "web"
+		if rng.Intn(3) == 0 {
+			canal = "app"
+		}
+
+		_, err := tx.Exec(
+			"INSERT INTO votos (participante_id, votacao_id, data_hora, ip_hash, canal, peso) VALUES (?, ?, ?, ?, ?, 1)",
+			participanteID, votacao.id, votacao.inicio.Add(periodo*time.Duration(i)/time.Duration(votacao.votos)),
+			fmt.Sprintf("%032x", rng.Intn(demoOrigens)), canal,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
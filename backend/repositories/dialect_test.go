@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+func TestRebind(t *testing.T) {
+	query := "SELECT id FROM votos WHERE canal = '?' AND votacao_id = ? AND participante_id = ?"
+	tests := map[string]string{
+		"mysql":    query,
+		"sqlite":   query,
+		"postgres": "SELECT id FROM votos WHERE canal = '?' AND votacao_id = $1 AND participante_id = $2",
+	}
+	for name, want := range tests {
+		driver, err := selectDriver(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := driver.Rebind(query); got != want {
+			t.Errorf("%s Rebind:\ngot  %s\nwant %s", name, got, want)
+		}
+	}
+}
+
+// newTestVotacao cria, no tenant padrão, uma votação aberta com um participante
+func newTestVotacao(t *testing.T) (votacao *entities.Votacao, participante *entities.Participante) {
+	t.Helper()
+	participante = Participantes.Save(entities.DefaultTenantID, &entities.Participante{Nome: t.Name(), URLFoto: "/p.jpg"})
+	if participante == nil {
+		t.Fatal("saving participante failed")
+	}
+	votacao = &entities.Votacao{Descricao: t.Name()}
+	if err := Votacoes.Save(entities.DefaultTenantID, votacao); err != nil {
+		t.Fatalf("saving votacao: %v", err)
+	}
+	if err := AddParticipanteToVotacaoInDB(entities.DefaultTenantID, participante.ID, votacao.ID); err != nil {
+		t.Fatalf("adding participante to votacao: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM alertas WHERE votacao_id = ?", votacao.ID)
+		DB.Exec("DELETE FROM votos_por_minuto WHERE votacao_id = ?", votacao.ID)
+		Votacoes.Delete(entities.DefaultTenantID, votacao.ID)
+		Participantes.Delete(entities.DefaultTenantID, participante.ID)
+	})
+	return votacao, participante
+}
+
+func TestAddParticipanteToVotacaoIgnoresDuplicates(t *testing.T) {
+	votacao, participante := newTestVotacao(t)
+
+	// A segunda inclusão cai no ON CONFLICT / INSERT IGNORE do banco
+	if err := AddParticipanteToVotacaoInDB(entities.DefaultTenantID, participante.ID, votacao.ID); err != nil {
+		t.Fatalf("adding the participante again: %v", err)
+	}
+
+	var count int
+	err := DB.QueryRow(
+		"SELECT COUNT(*) FROM votacao_participante WHERE votacao_id = ? AND participante_id = ?",
+		votacao.ID, participante.ID,
+	).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("votacao_participante has %d rows, want 1", count)
+	}
+}
+
+func TestClaimJobRun(t *testing.T) {
+	nome := "teste-" + time.Now().Format("150405.000000000")
+	t.Cleanup(func() { DB.Exec("DELETE FROM execucoes_job WHERE nome = ?", nome) })
+
+	if claimed, err := ClaimJobRun(nome, time.Hour); err != nil || !claimed {
+		t.Fatalf("first claim: got %v %v, want true", claimed, err)
+	}
+	if claimed, err := ClaimJobRun(nome, time.Hour); err != nil || claimed {
+		t.Errorf("claim within the interval: got %v %v, want false", claimed, err)
+	}
+
+	// O DATETIME do MySQL arredonda os segundos; a execução anterior é recuada em vez de
+	// depender de um intervalo curto
+	if _, err := DB.Exec("UPDATE execucoes_job SET executado_em = ? WHERE nome = ?",
+		time.Now().UTC().Add(-2*time.Hour), nome); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := ClaimJobRun(nome, time.Hour); err != nil || !claimed {
+		t.Errorf("claim after the interval: got %v %v, want true", claimed, err)
+	}
+}
+
+func TestSaveAlertaUpdatesExistingAlerta(t *testing.T) {
+	votacao, participante := newTestVotacao(t)
+	inicio := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+
+	alerta := func(valor float64, janelaInicio time.Time) *entities.Alerta {
+		return &entities.Alerta{
+			VotacaoID: votacao.ID, ParticipanteID: participante.ID, Tipo: entities.AlertaTaxaOrigem,
+			Origem: "origem", Valor: valor, Limite: 10, Votos: int(valor),
+			JanelaInicio: janelaInicio, JanelaFim: janelaInicio.Add(time.Minute),
+		}
+	}
+	if err := SaveAlerta(alerta(20, inicio)); err != nil {
+		t.Fatalf("first SaveAlerta: %v", err)
+	}
+	// A segunda análise começa depois; a janela mantém o início mais antigo
+	if err := SaveAlerta(alerta(30, inicio.Add(time.Minute))); err != nil {
+		t.Fatalf("second SaveAlerta: %v", err)
+	}
+
+	alertas := GetAlertasByVotacaoID(entities.DefaultTenantID, votacao.ID)
+	if len(alertas) != 1 {
+		t.Fatalf("got %d alertas, want 1", len(alertas))
+	}
+	got := alertas[0]
+	if got.Ocorrencias != 2 || got.Valor != 30 || got.Votos != 30 {
+		t.Errorf("got ocorrencias %d, valor %v, votos %d; want 2, 30, 30", got.Ocorrencias, got.Valor, got.Votos)
+	}
+	if !got.JanelaInicio.Equal(inicio) || !got.JanelaFim.Equal(inicio.Add(2*time.Minute)) {
+		t.Errorf("got janela %v - %v, want %v - %v", got.JanelaInicio, got.JanelaFim, inicio, inicio.Add(2*time.Minute))
+	}
+}
+
+func TestAggregateNewVotosAddsToExistingRollups(t *testing.T) {
+	votacao, participante := newTestVotacao(t)
+	minuto := time.Now().UTC().Truncate(time.Minute)
+
+	vote := func(peso int) {
+		t.Helper()
+		voto := &entities.Voto{Participante: participante, Votacao: votacao, DataHora: minuto.Add(time.Second), Peso: peso}
+		if _, err := SaveVoto(entities.DefaultTenantID, voto); err != nil {
+			t.Fatalf("SaveVoto: %v", err)
+		}
+	}
+	aggregate := func(wantTotal, wantPontos int) {
+		t.Helper()
+		if _, err := AggregateNewVotos(); err != nil {
+			t.Fatalf("AggregateNewVotos: %v", err)
+		}
+		var total, pontos int
+		err := DB.QueryRow(
+			"SELECT COALESCE(SUM(total), 0), COALESCE(SUM(pontos), 0) FROM votos_por_minuto WHERE votacao_id = ?",
+			votacao.ID,
+		).Scan(&total, &pontos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != wantTotal || pontos != wantPontos {
+			t.Errorf("votos_por_minuto: got %d votos, %d pontos; want %d, %d", total, pontos, wantTotal, wantPontos)
+		}
+	}
+
+	vote(1)
+	vote(2)
+	aggregate(2, 3)
+
+	// O segundo lote soma ao minuto já agregado, pelo ON CONFLICT / ON DUPLICATE KEY
+	vote(3)
+	aggregate(3, 6)
+
+	discrepancies, err := RebuildRollups(votacao.ID, false)
+	if err != nil {
+		t.Fatalf("RebuildRollups: %v", err)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("RebuildRollups found discrepancies: %+v", discrepancies)
+	}
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Driver isola o que muda entre os bancos suportados. Os repositórios escrevem o SQL
+// comum a todos, com placeholders ?, e pedem ao driver apenas os trechos que diferem.
+type Driver interface {
+	// Name é o valor de DB_DRIVER que seleciona o driver
+	Name() string
+	// Open abre o pool de conexões com o servidor em addr (host:porta), ou com o de DB_HOST
+	// e DB_PORT quando addr é vazio. Os demais parâmetros vêm das variáveis de ambiente.
+	Open(addr string) (*sql.DB, error)
+	// Migrate aplica as migrações mantidas pelo próprio backend. Nos bancos que recebem as
+	// migrações de fora, como os containers do docker-compose, não faz nada.
+	Migrate(db *sql.DB) error
+	// Rebind troca os placeholders ? pelos do banco
+	Rebind(query string) string
+	// Args adapta os valores dos parâmetros ao banco
+	Args(args []interface{}) []interface{}
+	// Placeholder é o placeholder de um valor do tipo informado, para posições em que o
+	// banco não deduz o tipo sozinho, como a lista do SELECT de um INSERT ... SELECT
+	Placeholder(sqlType string) string
+	// TruncMinute trunca a data e hora da coluna no minuto
+	TruncMinute(column string) string
+	// FormatMinute formata a data e hora da coluna como AAAA-MM-DD HH:MM:00
+	FormatMinute(column string) string
+	// Hour extrai a hora, de 0 a 23, da data e hora da coluna
+	Hour(column string) string
+	// Least é o menor entre os valores das expressões
+	Least(a, b string) string
+	// ForShare completa um SELECT em uma transação para travar as linhas lidas em modo
+	// compartilhado
+	ForShare() string
+	// ForUpdate completa um SELECT em uma transação para travar as linhas lidas, apenas
+	// das tabelas informadas quando houver junções
+	ForUpdate(tables ...string) string
+	// OnConflictIgnore completa um INSERT para que uma linha com a chave já existente
+	// fique como está
+	OnConflictIgnore(key ...string) string
+	// OnConflictUpdate completa um INSERT para que uma linha com a chave já existente
+	// seja atualizada com as atribuições em set, que usam Excluded para o valor novo
+	OnConflictUpdate(key []string, set string) string
+	// Excluded referencia o valor que seria inserido na coluna, dentro de OnConflictUpdate
+	Excluded(column string) string
+	// InsertID executa o INSERT e retorna o ID gerado para a linha
+	InsertID(db execQueryer, query string, args ...interface{}) (int64, error)
+	// IsDuplicateKey indica se o erro é uma violação de chave única
+	IsDuplicateKey(err error) bool
+	// ReplicaLag mede o atraso da réplica em relação ao primário. Retorna erro se o
+	// servidor não for uma réplica ou se a replicação estiver parada.
+	ReplicaLag(db *Database) (time.Duration, error)
+	// VotoPartitions lista as partições mensais de votos, nomeadas pelo limite superior
+	// (votos_pAAAAMMDD). Nos bancos sem partições, ou com a tabela sem partições, a lista
+	// é vazia.
+	VotoPartitions(db *Database) ([]string, error)
+	// AddVotoPartition cria a partição de votos de lower até upper, logo depois da última
+	AddVotoPartition(db *Database, lower, upper time.Time) error
+	// DropVotoPartition remove a partição de votos com os votos que ela contém
+	DropVotoPartition(db *Database, name string) error
+	// IsLockConflict indica se o banco desfez a transação por deadlock ou por tempo de
+	// espera de trava esgotado, casos em que ela pode ser repetida
+	IsLockConflict(err error) bool
+}
+
+// Drivers disponíveis, pelo valor de DB_DRIVER
+var drivers = map[string]Driver{
+	"mysql":    mysqlDriver{},
+	"postgres": postgresDriver{},
+	"sqlite":   sqliteDriver{},
+}
+
+// Driver do banco em uso, escolhido por DB_DRIVER em InitDB
+var dbDriver Driver = mysqlDriver{}
+
+// serverAddr completa o endereço do servidor: sem addr, usa DB_HOST e DB_PORT; sem porta,
+// usa a porta padrão do banco
+func serverAddr(addr, defaultPort string) string {
+	if addr == "" {
+		return net.JoinHostPort(getEnv("DB_HOST", "localhost"), getEnv("DB_PORT", defaultPort))
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return net.JoinHostPort(addr, defaultPort)
+	}
+	return addr
+}
+
+// selectDriver escolhe o driver pelo nome
+func selectDriver(name string) (Driver, error) {
+	driver, ok := drivers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", name)
+	}
+	return driver, nil
+}
+
+// Database é o pool de conexões do banco em uso. Os comandos recebem o SQL com
+// placeholders ?, que o driver traduz antes de enviar ao banco com os parâmetros.
+type Database struct {
+	*sql.DB
+}
+
+func (db *Database) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(dbDriver.Rebind(query), dbDriver.Args(args)...)
+}
+
+func (db *Database) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(dbDriver.Rebind(query), dbDriver.Args(args)...)
+}
+
+func (db *Database) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(dbDriver.Rebind(query), dbDriver.Args(args)...)
+}
+
+func (db *Database) Prepare(query string) (*sql.Stmt, error) {
+	return db.DB.Prepare(dbDriver.Rebind(query))
+}
+
+func (db *Database) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx}, nil
+}
+
+// Tx é uma transação do banco em uso, com os placeholders e parâmetros traduzidos como
+// em Database
+type Tx struct {
+	*sql.Tx
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(dbDriver.Rebind(query), dbDriver.Args(args)...)
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(dbDriver.Rebind(query), dbDriver.Args(args)...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(dbDriver.Rebind(query), dbDriver.Args(args)...)
+}
@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Códigos de erro do MySQL tratados pelos repositórios
+const (
+	mysqlDuplicateEntry  = 1062
+	mysqlLockWaitTimeout = 1205
+	mysqlDeadlock        = 1213
+)
+
+// mysqlDriver é o driver do MySQL 8, o banco padrão
+type mysqlDriver struct{}
+
+func (mysqlDriver) Name() string {
+	return "mysql"
+}
+
+func (mysqlDriver) Open(addr string) (*sql.DB, error) {
+	// Os horários são lidos e gravados em UTC, o padrão do driver
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true",
+		getEnv("DB_USER", "root"), getEnv("DB_PASSWORD", "password"),
+		serverAddr(addr, "3306"), getEnv("DB_NAME", "paredao"))
+	return sql.Open("mysql", dsn)
+}
+
+// As migrações são aplicadas pelo container do banco, a partir de migrations/mysql
+func (mysqlDriver) Migrate(*sql.DB) error {
+	return nil
+}
+
+func (mysqlDriver) Rebind(query string) string {
+	return query
+}
+
+func (mysqlDriver) Args(args []interface{}) []interface{} {
+	return args
+}
+
+func (mysqlDriver) Placeholder(string) string {
+	return "?"
+}
+
+func (mysqlDriver) TruncMinute(column string) string {
+	return "DATE_FORMAT(" + column + ", '%Y-%m-%d %H:%i:00')"
+}
+
+func (d mysqlDriver) FormatMinute(column string) string {
+	return d.TruncMinute(column)
+}
+
+func (mysqlDriver) Hour(column string) string {
+	return "HOUR(" + column + ")"
+}
+
+func (mysqlDriver) Least(a, b string) string {
+	return "LEAST(" + a + ", " + b + ")"
+}
+
+func (mysqlDriver) ForShare() string {
+	return "FOR SHARE"
+}
+
+func (mysqlDriver) ForUpdate(tables ...string) string {
+	if len(tables) == 0 {
+		return "FOR UPDATE"
+	}
+	return "FOR UPDATE OF " + strings.Join(tables, ", ")
+}
+
+func (mysqlDriver) OnConflictIgnore(key ...string) string {
+	return "ON DUPLICATE KEY UPDATE " + key[0] + " = " + key[0]
+}
+
+func (mysqlDriver) OnConflictUpdate(_ []string, set string) string {
+	return "ON DUPLICATE KEY UPDATE " + set
+}
+
+func (mysqlDriver) Excluded(column string) string {
+	return "VALUES(" + column + ")"
+}
+
+func (mysqlDriver) InsertID(db execQueryer, query string, args ...interface{}) (int64, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ReplicaLag lê Seconds_Behind_Source, que o MySQL informa a partir da versão 8.0.22 e
+// que fica nulo quando a replicação está parada
+func (mysqlDriver) ReplicaLag(db *Database) (time.Duration, error) {
+	rows, err := db.Query("SHOW REPLICA STATUS")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("server is not a replica")
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errors.New("replication is not running")
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, errors.New("replica status has no Seconds_Behind_Source")
+}
+
+func (mysqlDriver) VotoPartitions(db *Database) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT PARTITION_NAME FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'votos' AND PARTITION_NAME IS NOT NULL
+		ORDER BY PARTITION_ORDINAL_POSITION
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanVotoPartitions(rows)
+}
+
+// AddVotoPartition separa a nova partição de votos_pmax, a partição sem limite que recebe
+// os votos depois da última partição mensal
+func (mysqlDriver) AddVotoPartition(db *Database, _, upper time.Time) error {
+	_, err := db.Exec(fmt.Sprintf(`
+		ALTER TABLE votos REORGANIZE PARTITION votos_pmax INTO (
+			PARTITION %s VALUES LESS THAN ('%s'),
+			PARTITION votos_pmax VALUES LESS THAN (MAXVALUE)
+		)
+	`, votoPartitionName(upper), upper.UTC().Format(time.DateTime)))
+	return err
+}
+
+func (mysqlDriver) DropVotoPartition(db *Database, name string) error {
+	_, err := db.Exec("ALTER TABLE votos DROP PARTITION " + name)
+	return err
+}
+
+func (mysqlDriver) IsDuplicateKey(err error) bool {
+	return isMySQLError(err, mysqlDuplicateEntry)
+}
+
+func (mysqlDriver) IsLockConflict(err error) bool {
+	return isMySQLError(err, mysqlDeadlock, mysqlLockWaitTimeout)
+}
+
+// isMySQLError indica se err é um erro do MySQL com um dos códigos informados
+func isMySQLError(err error, numbers ...uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	for _, number := range numbers {
+		if mysqlErr.Number == number {
+			return true
+		}
+	}
+	return false
+}
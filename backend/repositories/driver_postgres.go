@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Códigos de erro do PostgreSQL tratados pelos repositórios
+const (
+	postgresUniqueViolation      = "23505"
+	postgresSerializationFailure = "40001"
+	postgresDeadlock             = "40P01"
+	postgresLockNotAvailable     = "55P03"
+)
+
+// postgresDriver é o driver do PostgreSQL, a partir da versão 12
+type postgresDriver struct{}
+
+func (postgresDriver) Name() string {
+	return "postgres"
+}
+
+func (postgresDriver) Open(addr string) (*sql.DB, error) {
+	query := url.Values{}
+	query.Set("sslmode", getEnv("DB_SSLMODE", "disable"))
+	// As datas são agrupadas e formatadas em UTC, como no MySQL
+	query.Set("timezone", "UTC")
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(getEnv("DB_USER", "postgres"), getEnv("DB_PASSWORD", "password")),
+		Host:     serverAddr(addr, "5432"),
+		Path:     getEnv("DB_NAME", "paredao"),
+		RawQuery: query.Encode(),
+	}
+	return sql.Open("pgx", dsn.String())
+}
+
+// As migrações são aplicadas pelo container do banco, a partir de migrations/postgres
+func (postgresDriver) Migrate(*sql.DB) error {
+	return nil
+}
+
+// Rebind numera os placeholders, que no PostgreSQL são $1, $2..., sem mexer nos ? dentro
+// de textos entre aspas
+func (postgresDriver) Rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n, quoted := 0, false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (postgresDriver) Args(args []interface{}) []interface{} {
+	return args
+}
+
+func (postgresDriver) Placeholder(sqlType string) string {
+	return "CAST(? AS " + sqlType + ")"
+}
+
+func (postgresDriver) TruncMinute(column string) string {
+	return "date_trunc('minute', " + column + ")"
+}
+
+func (postgresDriver) FormatMinute(column string) string {
+	return "to_char(" + column + ", 'YYYY-MM-DD HH24:MI:00')"
+}
+
+func (postgresDriver) Hour(column string) string {
+	return "CAST(EXTRACT(HOUR FROM " + column + ") AS INTEGER)"
+}
+
+func (postgresDriver) Least(a, b string) string {
+	return "LEAST(" + a + ", " + b + ")"
+}
+
+func (postgresDriver) ForShare() string {
+	return "FOR SHARE"
+}
+
+func (postgresDriver) ForUpdate(tables ...string) string {
+	if len(tables) == 0 {
+		return "FOR UPDATE"
+	}
+	return "FOR UPDATE OF " + strings.Join(tables, ", ")
+}
+
+func (postgresDriver) OnConflictIgnore(key ...string) string {
+	return "ON CONFLICT (" + strings.Join(key, ", ") + ") DO NOTHING"
+}
+
+func (postgresDriver) OnConflictUpdate(key []string, set string) string {
+	return "ON CONFLICT (" + strings.Join(key, ", ") + ") DO UPDATE SET " + set
+}
+
+func (postgresDriver) Excluded(column string) string {
+	return "EXCLUDED." + column
+}
+
+func (postgresDriver) InsertID(db execQueryer, query string, args ...interface{}) (int64, error) {
+	var id int64
+	err := db.QueryRow(query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+// ReplicaLag mede há quanto tempo foi gerada a última transação aplicada pela réplica. Se
+// ela já aplicou tudo o que recebeu, não há atraso, mesmo sem transações novas no primário.
+func (postgresDriver) ReplicaLag(db *Database) (time.Duration, error) {
+	var inRecovery bool
+	var seconds sql.NullFloat64
+	err := db.QueryRow(`
+		SELECT pg_is_in_recovery(),
+			CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+			END
+	`).Scan(&inRecovery, &seconds)
+	switch {
+	case err != nil:
+		return 0, err
+	case !inRecovery:
+		return 0, errors.New("server is not a replica")
+	case !seconds.Valid:
+		return 0, errors.New("replica has not replayed any transaction")
+	}
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
+
+func (postgresDriver) VotoPartitions(db *Database) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'votos'::regclass
+		ORDER BY c.relname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanVotoPartitions(rows)
+}
+
+// AddVotoPartition cria a partição como tabela própria. Os votos de fora das partições
+// mensais ficam em votos_padrao, que não pode ter votos no intervalo da nova partição.
+func (postgresDriver) AddVotoPartition(db *Database, lower, upper time.Time) error {
+	const layout = "2006-01-02 15:04:05-07"
+	_, err := db.Exec(fmt.Sprintf(
+		"CREATE TABLE %s PARTITION OF votos FOR VALUES FROM ('%s') TO ('%s')",
+		votoPartitionName(upper), lower.UTC().Format(layout), upper.UTC().Format(layout),
+	))
+	return err
+}
+
+func (postgresDriver) DropVotoPartition(db *Database, name string) error {
+	_, err := db.Exec("DROP TABLE " + name)
+	return err
+}
+
+func (postgresDriver) IsDuplicateKey(err error) bool {
+	return isPostgresError(err, postgresUniqueViolation)
+}
+
+func (postgresDriver) IsLockConflict(err error) bool {
+	return isPostgresError(err, postgresDeadlock, postgresLockNotAvailable, postgresSerializationFailure)
+}
+
+// isPostgresError indica se err é um erro do PostgreSQL com um dos códigos informados
+func isPostgresError(err error, codes ...string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	for _, code := range codes {
+		if pgErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
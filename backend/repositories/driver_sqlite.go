@@ -0,0 +1,276 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/danielfs/paredao/backend/migrations"
+)
+
+// Arquivo padrão do banco embutido, relativo ao diretório de trabalho
+const defaultSQLitePath = "paredao.db"
+
+// Quanto uma escrita espera pela anterior antes de desistir com SQLITE_BUSY
+const sqliteBusyTimeout = 5 * time.Second
+
+// sqliteDriver é o banco embutido, sem servidor, usado no modo de desenvolvimento. O
+// SQLite aceita uma escrita por vez: cada transação reserva a escrita ao começar, o que
+// dispensa as travas de linha dos outros bancos.
+type sqliteDriver struct{}
+
+// ciAI compara textos ignorando maiúsculas e acentos, como a collation padrão do MySQL.
+// O Collator não pode ser usado por várias goroutines ao mesmo tempo.
+var ciAI = struct {
+	sync.Mutex
+	*collate.Collator
+}{Collator: collate.New(language.Und, collate.IgnoreCase, collate.IgnoreDiacritics)}
+
+func init() {
+	sqlite.MustRegisterCollationUtf8("ci_ai", func(a, b string) int {
+		ciAI.Lock()
+		defer ciAI.Unlock()
+		return ciAI.CompareString(a, b)
+	})
+}
+
+func (sqliteDriver) Name() string {
+	return "sqlite"
+}
+
+// Open abre o arquivo informado em DB_NAME, criando-o se não existir. O banco embutido não
+// tem servidor nem réplicas.
+func (sqliteDriver) Open(addr string) (*sql.DB, error) {
+	if addr != "" {
+		return nil, errors.New("sqlite does not support replicas")
+	}
+
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	// Com o log de escrita antecipada, as leituras não esperam pelas escritas
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", sqliteBusyTimeout.Milliseconds()))
+	query.Set("_time_format", "sqlite")
+	query.Set("_txlock", "immediate")
+
+	return sql.Open("sqlite", "file:"+getEnv("DB_NAME", defaultSQLitePath)+"?"+query.Encode())
+}
+
+// Migrate aplica as migrações embutidas de migrations/sqlite que ainda não constam em
+// schema_migrations, cada uma em uma transação
+func (sqliteDriver) Migrate(db *sql.DB) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			versao VARCHAR(255) PRIMARY KEY,
+			aplicada_em DATETIME NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// As chaves estrangeiras ficam desligadas durante as alterações de esquema, como o
+	// SQLite recomenda, e são conferidas no final. O PRAGMA não vale dentro de transações.
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	names, err := migrations.SQLite.ReadDir("sqlite")
+	if err != nil {
+		return err
+	}
+	for _, entry := range names {
+		versao := entry.Name()
+
+		var applied bool
+		err := conn.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE versao = ?)", versao,
+		).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+
+		script, err := migrations.SQLite.ReadFile(path.Join("sqlite", versao))
+		if err != nil {
+			return err
+		}
+		if err := applySQLiteMigration(ctx, conn, versao, string(script)); err != nil {
+			return fmt.Errorf("migration %s: %w", versao, err)
+		}
+		log.Printf("Applied migration %s", versao)
+	}
+
+	rows, err := conn.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return errors.New("migrations left rows violating foreign keys")
+	}
+	return rows.Err()
+}
+
+func applySQLiteMigration(ctx context.Context, conn *sql.Conn, versao, script string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (versao, aplicada_em) VALUES (?, ?)", versao, time.Now().UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (sqliteDriver) Rebind(query string) string {
+	return query
+}
+
+// Args grava as datas em UTC. O SQLite guarda datas como texto e as compara como texto,
+// o que só segue a ordem do tempo se todas estiverem no mesmo fuso.
+func (sqliteDriver) Args(args []interface{}) []interface{} {
+	converted, copied := args, false
+	for i, arg := range args {
+		var t time.Time
+		switch v := arg.(type) {
+		case time.Time:
+			t = v
+		case *time.Time:
+			if v == nil {
+				continue
+			}
+			t = *v
+		case sql.NullTime:
+			if !v.Valid {
+				continue
+			}
+			t = v.Time
+		default:
+			continue
+		}
+
+		// Copia os parâmetros na primeira conversão, para não alterar os de quem chamou
+		if !copied {
+			converted, copied = append([]interface{}(nil), args...), true
+		}
+		converted[i] = t.UTC()
+	}
+	return converted
+}
+
+func (sqliteDriver) Placeholder(string) string {
+	return "?"
+}
+
+// TruncMinute produz o texto que o driver grava para uma data em UTC, para que o minuto
+// agregado seja comparável às datas recebidas como parâmetro
+func (sqliteDriver) TruncMinute(column string) string {
+	return "strftime('%Y-%m-%d %H:%M:00+00:00', " + column + ")"
+}
+
+func (sqliteDriver) FormatMinute(column string) string {
+	return "strftime('%Y-%m-%d %H:%M:00', " + column + ")"
+}
+
+func (sqliteDriver) Hour(column string) string {
+	return "CAST(strftime('%H', " + column + ") AS INTEGER)"
+}
+
+func (sqliteDriver) Least(a, b string) string {
+	return "MIN(" + a + ", " + b + ")"
+}
+
+// As transações já reservam a escrita ao começar
+func (sqliteDriver) ForShare() string {
+	return ""
+}
+
+func (sqliteDriver) ForUpdate(...string) string {
+	return ""
+}
+
+func (sqliteDriver) OnConflictIgnore(key ...string) string {
+	return postgresDriver{}.OnConflictIgnore(key...)
+}
+
+func (sqliteDriver) OnConflictUpdate(key []string, set string) string {
+	return postgresDriver{}.OnConflictUpdate(key, set)
+}
+
+func (sqliteDriver) Excluded(column string) string {
+	return "excluded." + column
+}
+
+func (sqliteDriver) InsertID(db execQueryer, query string, args ...interface{}) (int64, error) {
+	return mysqlDriver{}.InsertID(db, query, args...)
+}
+
+func (sqliteDriver) ReplicaLag(*Database) (time.Duration, error) {
+	return 0, errors.New("sqlite does not support replicas")
+}
+
+// O SQLite não tem partições: os votos arquivados são removidos linha a linha
+func (sqliteDriver) VotoPartitions(*Database) ([]string, error) {
+	return nil, nil
+}
+
+func (sqliteDriver) AddVotoPartition(*Database, time.Time, time.Time) error {
+	return errors.New("sqlite does not support partitions")
+}
+
+func (sqliteDriver) DropVotoPartition(*Database, string) error {
+	return errors.New("sqlite does not support partitions")
+}
+
+func (sqliteDriver) IsDuplicateKey(err error) bool {
+	return isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+func (sqliteDriver) IsLockConflict(err error) bool {
+	return isSQLiteError(err, sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED)
+}
+
+// isSQLiteError indica se err é um erro do SQLite com um dos códigos informados. Os
+// códigos primários, como SQLITE_BUSY, também reconhecem suas variações.
+func isSQLiteError(err error, codes ...int) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	for _, code := range codes {
+		if sqliteErr.Code() == code || (code <= 0xff && sqliteErr.Code()&0xff == code) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,38 +1,95 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/danielfs/paredao/backend/entities"
 )
 
-func GetTotalVotesForVotacao(votacaoID int64) (int, error) {
-	var total int
-	query := "SELECT COUNT(*) FROM votos WHERE votacao_id = ?"
+// Consultas das estatísticas, lidas da tabela votos_por_minuto
+const (
+	totalVotesQuery = "SELECT COALESCE(SUM(total), 0), COALESCE(SUM(pontos), 0) FROM votos_por_minuto WHERE votacao_id = ?"
+
+	totalsByParticipanteQuery = `
+		SELECT r.participante_id, SUM(r.total) as total, SUM(r.pontos) as pontos
+		FROM votos_por_minuto r
+		JOIN votacoes vt ON vt.id = r.votacao_id
+		WHERE r.votacao_id = ? AND vt.tenant_id = ?
+		GROUP BY r.participante_id
+	`
+)
+
+func totalsByHourQuery() string {
+	return `
+		SELECT ` + dbDriver.Hour("minuto") + ` as hour, SUM(total) as total
+		FROM votos_por_minuto
+		WHERE votacao_id = ?
+		GROUP BY ` + dbDriver.Hour("minuto") + `
+		ORDER BY hour
+	`
+}
+
+func countsByMinuteQuery() string {
+	return `
+		SELECT participante_id, ` + dbDriver.FormatMinute("minuto") + `, total, pontos
+		FROM votos_por_minuto
+		WHERE votacao_id = ?
+		ORDER BY minuto
+	`
+}
 
-	err := DB.QueryRow(query, votacaoID).Scan(&total)
+// GetTotalVotesForVotacao retorna o número de votos válidos e a soma dos seus pesos
+func GetTotalVotesForVotacao(votacaoID int64) (total, pontos int, err error) {
+	err = preparedQueryRow(readDB("GetTotalVotesForVotacao"), totalVotesQuery, votacaoID).Scan(&total, &pontos)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
-	return total, nil
+	return total, pontos, nil
+}
+
+func GetTotalVotesByParticipante(tenantID, votacaoID int64) ([]entities.ParticipanteTotalResponse, error) {
+	return totalsByParticipante(
+		readDB("GetTotalVotesByParticipante"), tenantID, votacaoID, totalsByParticipanteQuery, votacaoID, tenantID,
+	)
 }
 
-func GetTotalVotesByParticipante(votacaoID int64) ([]entities.ParticipanteTotalResponse, error) {
+// GetFinalTotalsByParticipante conta os votos válidos direto da tabela de votos, sem
+// esperar o agregador. Usada para decidir o resultado no encerramento da votação. Os
+// votos de uma votação arquivada só restam nas agregações, completas desde antes do
+// arquivamento; a consulta única lê sempre um dos dois lados, mesmo durante a remoção.
+func GetFinalTotalsByParticipante(tenantID, votacaoID int64) ([]entities.ParticipanteTotalResponse, error) {
+	return totalsByParticipante(readDB("GetFinalTotalsByParticipante"), tenantID, votacaoID, `
+		SELECT v.participante_id, COUNT(*) as total, SUM(v.peso) as pontos
+		FROM votos v
+		JOIN votacoes vt ON vt.id = v.votacao_id AND vt.arquivada_em IS NULL
+		WHERE v.votacao_id = ? AND vt.tenant_id = ? AND NOT v.invalidado
+		GROUP BY v.participante_id
+		UNION ALL
+		SELECT r.participante_id, SUM(r.total), SUM(r.pontos)
+		FROM votos_por_minuto r
+		JOIN votacoes vt ON vt.id = r.votacao_id AND vt.arquivada_em IS NOT NULL
+		WHERE r.votacao_id = ? AND vt.tenant_id = ?
+		GROUP BY r.participante_id
+	`, votacaoID, tenantID, votacaoID, tenantID)
+}
+
+// totalsByParticipante executa a consulta de totais e inclui os participantes da votação sem votos
+func totalsByParticipante(
+	db *Database,
+	tenantID int64,
+	votacaoID int64,
+	query string,
+	args ...interface{},
+) ([]entities.ParticipanteTotalResponse, error) {
 	// Primeiro, obtém todos os participantes para esta votação
-	participants := GetParticipantesByVotacaoID(votacaoID)
+	participants := GetParticipantesByVotacaoID(tenantID, votacaoID)
 
-	// Cria um mapa para armazenar os totais de votos para cada participante
+	// Cria mapas para armazenar os totais de votos e de pontos para cada participante
 	participantTotals := make(map[int64]int)
+	participantPontos := make(map[int64]int)
 
-	// Consulta para obter contagens de votos para participantes que têm votos
-	query := `
-		SELECT p.id, COUNT(*) as total
-		FROM votos v
-		JOIN participantes p ON v.participante_id = p.id
-		WHERE v.votacao_id = ?
-		GROUP BY p.id
-	`
-
-	rows, err := DB.Query(query, votacaoID)
+	rows, err := preparedQuery(db, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -41,11 +98,12 @@ func GetTotalVotesByParticipante(votacaoID int64) ([]entities.ParticipanteTotalR
 	// Preenche o mapa com contagens de votos
 	for rows.Next() {
 		var participanteID int64
-		var total int
-		if err := rows.Scan(&participanteID, &total); err != nil {
+		var total, pontos int
+		if err := rows.Scan(&participanteID, &total, &pontos); err != nil {
 			return nil, err
 		}
 		participantTotals[participanteID] = total
+		participantPontos[participanteID] = pontos
 	}
 
 	if err := rows.Err(); err != nil {
@@ -59,7 +117,9 @@ func GetTotalVotesByParticipante(votacaoID int64) ([]entities.ParticipanteTotalR
 		totals = append(totals, entities.ParticipanteTotalResponse{
 			ParticipanteID: p.ID,
 			Nome:           p.Nome,
+			URLFoto:        p.URLFoto,
 			Total:          total,
+			Pontos:         participantPontos[p.ID],
 		})
 	}
 
@@ -67,15 +127,7 @@ func GetTotalVotesByParticipante(votacaoID int64) ([]entities.ParticipanteTotalR
 }
 
 func GetTotalVotesByHour(votacaoID int64) ([]entities.HourlyTotalResponse, error) {
-	query := `
-		SELECT HOUR(data_hora) as hour, COUNT(*) as total
-		FROM votos
-		WHERE votacao_id = ?
-		GROUP BY HOUR(data_hora)
-		ORDER BY hour
-	`
-
-	rows, err := DB.Query(query, votacaoID)
+	rows, err := preparedQuery(readDB("GetTotalVotesByHour"), totalsByHourQuery(), votacaoID)
 	if err != nil {
 		return nil, err
 	}
@@ -107,3 +159,36 @@ func GetTotalVotesByHour(votacaoID int64) ([]entities.HourlyTotalResponse, error
 
 	return hourlyTotals, nil
 }
+
+// GetVoteCountsByMinute retorna os totais de votos e de pontos por participante e por minuto,
+// ordenados pelo minuto, base das séries temporais em qualquer granularidade.
+// As estatísticas são lidas da tabela votos_por_minuto, mantida por AggregateNewVotos.
+func GetVoteCountsByMinute(votacaoID int64) ([]entities.MinuteCount, error) {
+	rows, err := preparedQuery(readDB("GetVoteCountsByMinute"), countsByMinuteQuery(), votacaoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []entities.MinuteCount{}
+	for rows.Next() {
+		var count entities.MinuteCount
+		var minuto string
+		if err := rows.Scan(&count.ParticipanteID, &minuto, &count.Total, &count.Pontos); err != nil {
+			return nil, err
+		}
+
+		// Os horários são gravados em UTC
+		count.Minuto, err = time.Parse(time.DateTime, minuto)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Por quanto tempo uma resposta fica associada à sua Idempotency-Key, configurável
+// por IDEMPOTENCY_TTL
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// Prefixo das chaves de idempotência, por rota e por cliente
+const idempotencyKeyFormat = "idem:%s:%s:%s"
+
+// Enquanto a primeira requisição não termina, a chave fica reservada por no máximo este tempo
+const idempotencyPendingTTL = 30 * time.Second
+
+var (
+	// ErrIdempotencyInProgress indica que outra requisição com a mesma chave ainda está em andamento
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+	// ErrIdempotencyMismatch indica que a chave já foi usada com um corpo de requisição diferente
+	ErrIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+)
+
+// IdempotencyRecord é a resposta original gravada para uma Idempotency-Key
+type IdempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Pending     bool   `json:"pending"`
+	Status      int    `json:"status"`
+	Body        []byte `json:"body"`
+}
+
+var idempotencyTTL = defaultIdempotencyTTL
+
+// InitIdempotency configura a janela de idempotência a partir das variáveis de ambiente
+func InitIdempotency() {
+	ttl, err := time.ParseDuration(getEnv("IDEMPOTENCY_TTL", defaultIdempotencyTTL.String()))
+	if err != nil || ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	idempotencyTTL = ttl
+}
+
+// ReserveIdempotencyKey reserva a chave do cliente para a requisição atual. Se a chave já
+// tiver uma resposta gravada para o mesmo corpo de requisição (fingerprint), ela é retornada
+// e a requisição não deve ser processada de novo. As chaves são separadas por tenant e por
+// cliente, para que a mesma chave enviada por outro cliente nunca receba a resposta alheia.
+// Sem Redis, as chaves ficam na memória do processo.
+func ReserveIdempotencyKey(ctx context.Context, scope, client, key, fingerprint string) (*IdempotencyRecord, error) {
+	redisKey := scopedKey(ctx, fmt.Sprintf(idempotencyKeyFormat, scope, client, key))
+	pending, err := json.Marshal(IdempotencyRecord{Fingerprint: fingerprint, Pending: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	if RedisClient == nil {
+		if memStore.SetNX(redisKey, pending, idempotencyPendingTTL) {
+			return nil, nil
+		}
+		var found bool
+		if raw, found = memStore.Get(redisKey); !found {
+			// A reserva anterior expirou entre as duas chamadas; tenta de novo
+			return ReserveIdempotencyKey(ctx, scope, client, key, fingerprint)
+		}
+	} else {
+		reserved, err := RedisClient.SetNX(ctx, redisKey, pending, idempotencyPendingTTL).Result()
+		if err != nil {
+			log.Printf("Redis idempotency error: %v", err)
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+
+		raw, err = RedisClient.Get(ctx, redisKey).Bytes()
+		if err == redis.Nil {
+			// A reserva anterior expirou entre as duas chamadas; tenta de novo
+			return ReserveIdempotencyKey(ctx, scope, client, key, fingerprint)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	record := &IdempotencyRecord{}
+	if err := json.Unmarshal(raw, record); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case record.Fingerprint != fingerprint:
+		return nil, ErrIdempotencyMismatch
+	case record.Pending:
+		return nil, ErrIdempotencyInProgress
+	default:
+		return record, nil
+	}
+}
+
+// CompleteIdempotencyKey grava a resposta da requisição para ser devolvida nas repetições
+func CompleteIdempotencyKey(ctx context.Context, scope, client, key, fingerprint string, status int, body []byte) {
+	raw, err := json.Marshal(IdempotencyRecord{Fingerprint: fingerprint, Status: status, Body: body})
+	if err != nil {
+		log.Printf("Error encoding idempotency record: %v", err)
+		return
+	}
+
+	redisKey := scopedKey(ctx, fmt.Sprintf(idempotencyKeyFormat, scope, client, key))
+	if RedisClient == nil {
+		memStore.Set(redisKey, raw, idempotencyTTL)
+		return
+	}
+	if err := RedisClient.Set(ctx, redisKey, raw, idempotencyTTL).Err(); err != nil {
+		log.Printf("Redis idempotency error: %v", err)
+	}
+}
+
+// ReleaseIdempotencyKey libera a chave quando a requisição falha, permitindo que a
+// repetição seja processada normalmente
+func ReleaseIdempotencyKey(ctx context.Context, scope, client, key string) {
+	redisKey := scopedKey(ctx, fmt.Sprintf(idempotencyKeyFormat, scope, client, key))
+	if RedisClient == nil {
+		memStore.Del(redisKey)
+		return
+	}
+
+	if err := RedisClient.Del(ctx, redisKey).Err(); err != nil {
+		log.Printf("Redis idempotency error: %v", err)
+	}
+}
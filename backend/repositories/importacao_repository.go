@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Importacao reúne o que uma importação grava. Registros sem ID são criados; as
+// escalações referenciam os mesmos ponteiros, então usam os IDs gerados na gravação.
+type Importacao struct {
+	Participantes []*entities.Participante
+	Votacoes      []*entities.Votacao
+	Escalacoes    []Escalacao
+}
+
+// Escalacao inclui participantes em uma votação e, se informada, na temporada dela
+type Escalacao struct {
+	Votacao       *entities.Votacao
+	Participantes []*entities.Participante
+	// Participantes que ainda não fazem parte da temporada da votação
+	NovosNaTemporada []*entities.Participante
+}
+
+// ApplyImportacao grava toda a importação em uma única transação: ou tudo é gravado,
+// ou nada é
+func ApplyImportacao(tenantID int64, imp *Importacao) error {
+	// Uma nova tentativa da transação precisa inserir de novo os registros criados na anterior
+	var novosParticipantes []*entities.Participante
+	for _, p := range imp.Participantes {
+		if p.ID == 0 {
+			novosParticipantes = append(novosParticipantes, p)
+		}
+	}
+	var novasVotacoes []*entities.Votacao
+	for _, v := range imp.Votacoes {
+		if v.ID == 0 {
+			novasVotacoes = append(novasVotacoes, v)
+		}
+	}
+
+	err := RunInTx(func(tx *Tx) error {
+		for _, p := range novosParticipantes {
+			p.ID = 0
+		}
+		for _, v := range novasVotacoes {
+			v.ID = 0
+		}
+
+		for _, p := range imp.Participantes {
+			if err := saveParticipante(tx, tenantID, p); err != nil {
+				return err
+			}
+		}
+
+		for _, v := range imp.Votacoes {
+			if err := saveVotacao(tx, tenantID, v); err != nil {
+				return err
+			}
+		}
+
+		for _, e := range imp.Escalacoes {
+			for _, p := range e.NovosNaTemporada {
+				_, err := tx.Exec(`
+					INSERT INTO temporada_participante (temporada_id, participante_id, status) VALUES (?, ?, ?)
+				`+dbDriver.OnConflictIgnore("temporada_id", "participante_id"), *e.Votacao.TemporadaID, p.ID, entities.StatusAtivo)
+				if err != nil {
+					return err
+				}
+			}
+			for _, p := range e.Participantes {
+				_, err := tx.Exec(`
+					INSERT INTO votacao_participante (participante_id, votacao_id) VALUES (?, ?)
+				`+dbDriver.OnConflictIgnore("participante_id", "votacao_id"), p.ID, e.Votacao.ID)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Sem a gravação, os registros novos continuam sem ID
+		for _, p := range novosParticipantes {
+			p.ID = 0
+		}
+		for _, v := range novasVotacoes {
+			v.ID = 0
+		}
+	}
+	return err
+}
@@ -0,0 +1,228 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// ErrInvalidacaoRevertida indica que a invalidação já foi revertida
+var ErrInvalidacaoRevertida = errors.New("invalidacao already reverted")
+
+// VotoFilter seleciona votos de uma votação. Campos com valor zero não restringem a seleção.
+type VotoFilter struct {
+	VotacaoID      int64     `json:"votacaoId"`
+	ParticipanteID int64     `json:"participanteId"`
+	IPHash         string    `json:"ipHash"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+}
+
+func (f VotoFilter) where() (string, []interface{}) {
+	conditions := []string{"votacao_id = ?"}
+	args := []interface{}{f.VotacaoID}
+
+	if f.ParticipanteID != 0 {
+		conditions = append(conditions, "participante_id = ?")
+		args = append(args, f.ParticipanteID)
+	}
+	if f.IPHash != "" {
+		conditions = append(conditions, "ip_hash = ?")
+		args = append(args, f.IPHash)
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, "data_hora >= ?")
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, "data_hora < ?")
+		args = append(args, f.To)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+const invalidacaoColumns = `id, votacao_id, COALESCE(participante_id, 0), COALESCE(ip_hash, ''),
+	inicio, fim, motivo, votos, criado_em, revertida_em`
+
+func scanInvalidacao(row interface{ Scan(...interface{}) error }, i *entities.Invalidacao) error {
+	return row.Scan(
+		&i.ID, &i.VotacaoID, &i.ParticipanteID, &i.IPHash,
+		&i.Inicio, &i.Fim, &i.Motivo, &i.Votos, &i.CriadoEm, &i.RevertidaEm,
+	)
+}
+
+func GetInvalidacoesByVotacaoID(tenantID, votacaoID int64) []*entities.Invalidacao {
+	rows, err := readDB("GetInvalidacoesByVotacaoID").Query(
+		"SELECT "+invalidacaoColumns+" FROM invalidacoes WHERE votacao_id = ? AND "+inTenantVotacao("invalidacoes")+
+			" ORDER BY id DESC",
+		votacaoID, tenantID,
+	)
+	if err != nil {
+		log.Printf("Error querying invalidacoes by votacao ID: %v", err)
+		return []*entities.Invalidacao{}
+	}
+	defer rows.Close()
+
+	invalidacoes := []*entities.Invalidacao{}
+	for rows.Next() {
+		i := &entities.Invalidacao{}
+		if err := scanInvalidacao(rows, i); err != nil {
+			log.Printf("Error scanning invalidacao row: %v", err)
+			continue
+		}
+		invalidacoes = append(invalidacoes, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating invalidacao rows: %v", err)
+	}
+
+	return invalidacoes
+}
+
+func GetInvalidacaoByID(tenantID, id int64) (*entities.Invalidacao, bool) {
+	i := &entities.Invalidacao{}
+	err := scanInvalidacao(DB.QueryRow(
+		"SELECT "+invalidacaoColumns+" FROM invalidacoes WHERE id = ? AND "+inTenantVotacao("invalidacoes"),
+		id, tenantID,
+	), i)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false
+		}
+		log.Printf("Error querying invalidacao by ID: %v", err)
+		return nil, false
+	}
+
+	return i, true
+}
+
+// InvalidateVotos marca como invalidados os votos válidos selecionados pelo filtro,
+// desconta das agregações os que já tinham sido contados e registra a operação com
+// o motivo informado, para que possa ser revertida. Retorna ErrNotFound se a votação não
+// for do tenant e ErrVotacaoArquivada se os votos da votação já tiverem sido arquivados.
+func InvalidateVotos(tenantID int64, filter VotoFilter, motivo string) (*entities.Invalidacao, error) {
+	var invalidacao *entities.Invalidacao
+	err := RunInTx(func(tx *Tx) error {
+		if _, err := lockRollups(tx); err != nil {
+			return err
+		}
+		if err := lockVotacaoVotos(tx, tenantID, filter.VotacaoID); err != nil {
+			return err
+		}
+
+		invalidacao = &entities.Invalidacao{
+			VotacaoID:      filter.VotacaoID,
+			ParticipanteID: filter.ParticipanteID,
+			IPHash:         filter.IPHash,
+			Motivo:         motivo,
+			CriadoEm:       time.Now().UTC(),
+		}
+		if !filter.From.IsZero() {
+			invalidacao.Inicio = &filter.From
+		}
+		if !filter.To.IsZero() {
+			invalidacao.Fim = &filter.To
+		}
+
+		var err error
+		invalidacao.ID, err = dbDriver.InsertID(tx, `
+			INSERT INTO invalidacoes (votacao_id, participante_id, ip_hash, inicio, fim, motivo, votos, criado_em)
+			VALUES (?, NULLIF(?, 0), NULLIF(?, ''), ?, ?, ?, 0, ?)
+		`, invalidacao.VotacaoID, invalidacao.ParticipanteID, invalidacao.IPHash,
+			invalidacao.Inicio, invalidacao.Fim, invalidacao.Motivo, invalidacao.CriadoEm)
+		if err != nil {
+			return err
+		}
+
+		where, args := filter.where()
+
+		// Os votos ainda sem lote não foram agregados e, invalidados, serão ignorados pelo agregador
+		err = addVotosToRollups(tx, true, "lote_agregacao IS NOT NULL AND NOT invalidado AND "+where, args...)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			"UPDATE votos SET invalidado = TRUE, invalidacao_id = ? WHERE NOT invalidado AND "+where,
+			append([]interface{}{invalidacao.ID}, args...)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		invalidacao.Votos, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE invalidacoes SET votos = ? WHERE id = ?", invalidacao.Votos, invalidacao.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invalidacao, nil
+}
+
+// RevertInvalidacao volta a considerar válidos os votos invalidados por uma operação
+// e devolve às agregações os que já tinham sido contados. Retorna ErrNotFound se a
+// invalidação não for de uma votação do tenant e ErrVotacaoArquivada se os votos da
+// votação já tiverem sido arquivados.
+func RevertInvalidacao(tenantID, id int64) (*entities.Invalidacao, error) {
+	invalidacao := &entities.Invalidacao{}
+	err := RunInTx(func(tx *Tx) error {
+		if _, err := lockRollups(tx); err != nil {
+			return err
+		}
+
+		err := scanInvalidacao(tx.QueryRow(
+			"SELECT "+invalidacaoColumns+" FROM invalidacoes WHERE id = ? AND "+inTenantVotacao("invalidacoes")+
+				" "+dbDriver.ForUpdate(),
+			id, tenantID,
+		), invalidacao)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if invalidacao.RevertidaEm != nil {
+			return ErrInvalidacaoRevertida
+		}
+		if err := lockVotacaoVotos(tx, tenantID, invalidacao.VotacaoID); err != nil {
+			return err
+		}
+
+		// Os votos ainda sem lote serão contados pelo agregador assim que voltarem a ser válidos
+		err = addVotosToRollups(tx, false, "lote_agregacao IS NOT NULL AND invalidacao_id = ?", id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE votos SET invalidado = FALSE, invalidacao_id = NULL WHERE invalidacao_id = ?", id)
+		if err != nil {
+			return err
+		}
+
+		revertidaEm := time.Now().UTC()
+		_, err = tx.Exec("UPDATE invalidacoes SET revertida_em = ? WHERE id = ?", revertidaEm, id)
+		if err != nil {
+			return err
+		}
+
+		invalidacao.RevertidaEm = &revertidaEm
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invalidacao, nil
+}
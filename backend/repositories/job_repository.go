@@ -0,0 +1,45 @@
+package repositories
+
+import "time"
+
+// ClaimJobRun reserva a próxima execução do job entre todas as réplicas: trava a linha do
+// job e só a reserva se a última execução, em qualquer réplica, tiver começado há pelo menos
+// interval. Retorna false quando outra réplica já executou o job no intervalo, e o job não
+// deve ser executado.
+func ClaimJobRun(nome string, interval time.Duration) (bool, error) {
+	var claimed bool
+	err := RunInTx(func(tx *Tx) error {
+		claimed = false
+
+		// A linha de um job novo é criada na primeira execução
+		_, err := tx.Exec(
+			"INSERT INTO execucoes_job (nome) VALUES (?) "+dbDriver.OnConflictIgnore("nome"), nome,
+		)
+		if err != nil {
+			return err
+		}
+
+		var executadoEm *time.Time
+		err = tx.QueryRow(
+			"SELECT executado_em FROM execucoes_job WHERE nome = ? "+dbDriver.ForUpdate(), nome,
+		).Scan(&executadoEm)
+		if err != nil {
+			return err
+		}
+
+		// Uma folga de um décimo do intervalo absorve a diferença entre os tiques e os relógios
+		// das réplicas
+		now := time.Now().UTC()
+		if executadoEm != nil && now.Sub(*executadoEm) < interval-interval/10 {
+			return nil
+		}
+
+		_, err = tx.Exec("UPDATE execucoes_job SET executado_em = ? WHERE nome = ?", now, nome)
+		if err != nil {
+			return err
+		}
+		claimed = true
+		return nil
+	})
+	return claimed, err
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClaimJobRunOnlyOnceAcrossInstances(t *testing.T) {
+	nome := fmt.Sprintf("teste-%d", time.Now().UnixNano())
+	t.Cleanup(func() { DB.Exec("DELETE FROM execucoes_job WHERE nome = ?", nome) })
+
+	// Várias réplicas disputam a mesma execução
+	const instances = 4
+	var wg sync.WaitGroup
+	claims := make(chan bool, instances)
+	for range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := ClaimJobRun(nome, time.Hour)
+			if err != nil {
+				t.Errorf("ClaimJobRun: %v", err)
+			}
+			claims <- claimed
+		}()
+	}
+	wg.Wait()
+	close(claims)
+
+	var claimed int
+	for c := range claims {
+		if c {
+			claimed++
+		}
+	}
+	if claimed != 1 {
+		t.Fatalf("%d instances claimed the run, want 1", claimed)
+	}
+}
@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Valores padrão do cache em memória, configuráveis por LOCAL_CACHE_TTL e LOCAL_CACHE_MAX_ENTRIES
+const (
+	defaultLocalCacheTTL        = 500 * time.Millisecond
+	defaultLocalCacheMaxEntries = 1000
+)
+
+type localEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// localCache é um cache LRU limitado por número de entradas, com TTL próprio,
+// que fica na frente do Redis para evitar uma ida à rede a cada requisição
+type localCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+
+	hits          uint64
+	misses        uint64
+	evictions     uint64
+	expirations   uint64
+	invalidations uint64
+}
+
+// LocalCache é o cache em memória compartilhado pelo processo
+var LocalCache = newLocalCache(defaultLocalCacheTTL, defaultLocalCacheMaxEntries)
+
+func newLocalCache(ttl time.Duration, maxEntries int) *localCache {
+	return &localCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// InitLocalCache reconfigura o cache em memória a partir das variáveis de ambiente
+func InitLocalCache() {
+	ttl, err := time.ParseDuration(getEnv("LOCAL_CACHE_TTL", defaultLocalCacheTTL.String()))
+	if err != nil {
+		ttl = defaultLocalCacheTTL
+	}
+
+	maxEntries := getEnvInt("LOCAL_CACHE_MAX_ENTRIES", defaultLocalCacheMaxEntries)
+
+	LocalCache = newLocalCache(ttl, maxEntries)
+}
+
+func (c *localCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*localEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		c.expirations++
+		c.misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits++
+	return entry.value, true
+}
+
+func (c *localCache) Set(key string, value []byte) {
+	if c.ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*localEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&localEntry{key: key, value: value, expiresAt: expiresAt})
+
+	// Remove as entradas menos usadas recentemente quando o limite é ultrapassado
+	for c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+		c.evictions++
+	}
+}
+
+// Delete remove as chaves informadas, retornando quantas existiam
+func (c *localCache) Delete(keys ...string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.removeElement(elem)
+			removed++
+		}
+	}
+	c.invalidations += uint64(removed)
+	return removed
+}
+
+func (c *localCache) Stats() entities.LocalCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return entities.LocalCacheStats{
+		Size:          c.order.Len(),
+		MaxEntries:    c.maxEntries,
+		TTLMillis:     c.ttl.Milliseconds(),
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Evictions:     c.evictions,
+		Expirations:   c.expirations,
+		Invalidations: c.invalidations,
+	}
+}
+
+func (c *localCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*localEntry).key)
+}
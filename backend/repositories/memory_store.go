@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"sync"
+	"time"
+)
+
+// Intervalo mínimo entre as remoções das chaves vencidas do armazenamento em memória
+const memoryStoreSweepInterval = time.Minute
+
+type memoryEntry struct {
+	value     []byte
+	count     int64
+	expiresAt time.Time
+}
+
+// memoryStore guarda na memória do processo as chaves que iriam para o Redis quando ele
+// não é usado, como no modo de desenvolvimento. Sem Redis há uma única réplica, então o
+// processo é o único a ler e gravar as chaves.
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// memStore é o armazenamento em memória compartilhado pelo processo
+var memStore = newMemoryStore()
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: make(map[string]memoryEntry), lastSweep: time.Now()}
+}
+
+// Get retorna o valor da chave, se ela existir e não tiver vencido
+func (s *memoryStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.live(key, time.Now())
+	return entry.value, ok
+}
+
+// Set grava o valor da chave, que vence depois de ttl
+func (s *memoryStore) Set(key string, value []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	s.entries[key] = memoryEntry{value: value, expiresAt: now.Add(ttl)}
+}
+
+// SetNX grava o valor apenas se a chave não existir, indicando se gravou
+func (s *memoryStore) SetNX(key string, value []byte, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := s.live(key, now); ok {
+		return false
+	}
+	s.sweep(now)
+	s.entries[key] = memoryEntry{value: value, expiresAt: now.Add(ttl)}
+	return true
+}
+
+// IncrBelow incrementa os contadores das chaves, criando-os com validade ttl, se nenhum
+// tiver chegado a limit. Caso contrário não altera nenhum e retorna quanto falta para o
+// contador no limite vencer.
+func (s *memoryStore) IncrBelow(keys []string, limit int64, ttl time.Duration) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		if entry, ok := s.live(key, now); ok && entry.count >= limit {
+			wait = max(wait, entry.expiresAt.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+
+	s.sweep(now)
+	for _, key := range keys {
+		entry, ok := s.live(key, now)
+		if !ok {
+			entry = memoryEntry{expiresAt: now.Add(ttl)}
+		}
+		entry.count++
+		s.entries[key] = entry
+	}
+	return true, 0
+}
+
+// Decr decrementa o contador da chave, se ele existir e for positivo
+func (s *memoryStore) Decr(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.live(key, time.Now()); ok && entry.count > 0 {
+		entry.count--
+		s.entries[key] = entry
+	}
+}
+
+// Del remove as chaves informadas
+func (s *memoryStore) Del(keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+}
+
+// live retorna a entrada da chave, removendo-a se tiver vencido
+func (s *memoryStore) live(key string, now time.Time) (memoryEntry, bool) {
+	entry, ok := s.entries[key]
+	if ok && !now.Before(entry.expiresAt) {
+		delete(s.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, ok
+}
+
+// sweep remove de tempos em tempos as chaves vencidas que não foram mais lidas
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memoryStoreSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
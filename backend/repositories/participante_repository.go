@@ -2,13 +2,73 @@ package repositories
 
 import (
 	"database/sql"
+	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/danielfs/paredao/backend/entities"
 )
 
-func GetAllParticipantes() []*entities.Participante {
-	rows, err := DB.Query("SELECT id, nome, url_foto FROM participantes")
+// Formato das datas do perfil, como AAAA-MM-DD
+const dateLayout = time.DateOnly
+
+const participanteColumns = `p.id, p.nome, p.url_foto, COALESCE(p.apelido, ''), COALESCE(p.bio, ''),
+	COALESCE(p.cidade, ''), p.data_nascimento, p.redes_sociais, COALESCE(p.cor, '')`
+
+func scanParticipante(row interface{ Scan(...interface{}) error }, p *entities.Participante) error {
+	var dataNascimento sql.NullTime
+	var redesSociais []byte
+	err := row.Scan(
+		&p.ID, &p.Nome, &p.URLFoto, &p.Apelido, &p.Bio, &p.Cidade, &dataNascimento, &redesSociais, &p.Cor,
+	)
+	if err != nil {
+		return err
+	}
+	if dataNascimento.Valid {
+		p.DataNascimento = dataNascimento.Time.Format(dateLayout)
+	}
+	if redesSociais == nil {
+		return nil
+	}
+	return json.Unmarshal(redesSociais, &p.RedesSociais)
+}
+
+// perfilArgs converte os campos opcionais do perfil para as colunas, que ficam nulas quando vazias
+func perfilArgs(p *entities.Participante) ([]interface{}, error) {
+	var redesSociais interface{}
+	if len(p.RedesSociais) > 0 {
+		data, err := json.Marshal(p.RedesSociais)
+		if err != nil {
+			return nil, err
+		}
+		redesSociais = string(data)
+	}
+
+	var dataNascimento interface{}
+	if p.DataNascimento != "" {
+		date, err := time.Parse(dateLayout, p.DataNascimento)
+		if err != nil {
+			return nil, err
+		}
+		dataNascimento = date
+	}
+
+	return []interface{}{
+		nullIfEmpty(p.Apelido), nullIfEmpty(p.Bio), nullIfEmpty(p.Cidade), dataNascimento, redesSociais, nullIfEmpty(p.Cor),
+	}, nil
+}
+
+func nullIfEmpty(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// SQLParticipanteStore guarda os participantes no banco
+type SQLParticipanteStore struct{}
+
+func (SQLParticipanteStore) GetAll(tenantID int64) []*entities.Participante {
+	rows, err := readDB("GetAllParticipantes").Query(
+		"SELECT "+participanteColumns+" FROM participantes p WHERE p.tenant_id = ?", tenantID,
+	)
 	if err != nil {
 		log.Printf("Error querying participantes: %v", err)
 		return []*entities.Participante{}
@@ -18,7 +78,7 @@ func GetAllParticipantes() []*entities.Participante {
 	participantes := []*entities.Participante{}
 	for rows.Next() {
 		p := &entities.Participante{}
-		if err := rows.Scan(&p.ID, &p.Nome, &p.URLFoto); err != nil {
+		if err := scanParticipante(rows, p); err != nil {
 			log.Printf("Error scanning participante row: %v", err)
 			continue
 		}
@@ -32,59 +92,69 @@ func GetAllParticipantes() []*entities.Participante {
 	return participantes
 }
 
-func GetParticipanteByID(id int64) (*entities.Participante, bool) {
+const participanteByIDQuery = "SELECT " + participanteColumns +
+	" FROM participantes p WHERE p.id = ? AND p.tenant_id = ?"
+
+func (SQLParticipanteStore) GetByID(tenantID, id int64) (*entities.Participante, error) {
 	p := &entities.Participante{}
-	err := DB.QueryRow("SELECT id, nome, url_foto FROM participantes WHERE id = ?", id).
-		Scan(&p.ID, &p.Nome, &p.URLFoto)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, false
-		}
-		log.Printf("Error querying participante by ID: %v", err)
-		return nil, false
+	err := scanParticipante(preparedQueryRow(DB, participanteByIDQuery, id, tenantID), p)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
 	}
 
-	return p, true
+	return p, nil
 }
 
-func SaveParticipante(p *entities.Participante) *entities.Participante {
-	if p.ID == 0 {
-		// Insere novo participante
-		result, err := DB.Exec(
-			"INSERT INTO participantes (nome, url_foto) VALUES (?, ?)",
-			p.Nome, p.URLFoto,
-		)
-		if err != nil {
-			log.Printf("Error inserting participante: %v", err)
-			return nil
-		}
+func (SQLParticipanteStore) Save(tenantID int64, p *entities.Participante) *entities.Participante {
+	if err := saveParticipante(DB, tenantID, p); err != nil {
+		log.Printf("Error saving participante: %v", err)
+		return nil
+	}
 
-		id, err := result.LastInsertId()
-		if err != nil {
-			log.Printf("Error getting last insert ID: %v", err)
-			return nil
-		}
+	return p
+}
 
-		p.ID = id
-	} else {
+// saveParticipante insere o participante sem ID ou atualiza o existente, no banco ou
+// em uma transação
+func saveParticipante(db execQueryer, tenantID int64, p *entities.Participante) error {
+	perfil, err := perfilArgs(p)
+	if err != nil {
+		return err
+	}
+
+	if p.ID != 0 {
 		// Atualiza participante existente
-		_, err := DB.Exec(
-			"UPDATE participantes SET nome = ?, url_foto = ? WHERE id = ?",
-			p.Nome, p.URLFoto, p.ID,
+		_, err := db.Exec(
+			`UPDATE participantes SET nome = ?, url_foto = ?, apelido = ?, bio = ?, cidade = ?,
+			data_nascimento = ?, redes_sociais = ?, cor = ?
+			WHERE id = ? AND tenant_id = ?`,
+			append(append([]interface{}{p.Nome, p.URLFoto}, perfil...), p.ID, tenantID)...,
 		)
-		if err != nil {
-			log.Printf("Error updating participante: %v", err)
-			return nil
-		}
+		return err
 	}
 
-	return p
+	// Insere novo participante
+	p.ID, err = dbDriver.InsertID(db,
+		`INSERT INTO participantes
+		(tenant_id, nome, url_foto, apelido, bio, cidade, data_nascimento, redes_sociais, cor)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		append([]interface{}{tenantID, p.Nome, p.URLFoto}, perfil...)...,
+	)
+	return err
 }
 
-func DeleteParticipanteByID(id int64) bool {
-	result, err := DB.Exec("DELETE FROM participantes WHERE id = ?", id)
+// UpdateFoto grava o endereço da foto do participante
+// UpdateFoto retorna false se o participante não existir no tenant. Cada envio gera uma
+// URL nova, de modo que a atualização sempre altera a linha.
+func (SQLParticipanteStore) UpdateFoto(tenantID, id int64, urlFoto string) bool {
+	result, err := DB.Exec(
+		"UPDATE participantes SET url_foto = ? WHERE id = ? AND tenant_id = ?",
+		urlFoto, id, tenantID,
+	)
 	if err != nil {
-		log.Printf("Error deleting participante: %v", err)
+		log.Printf("Error updating participante foto: %v", err)
 		return false
 	}
 
@@ -97,15 +167,113 @@ func DeleteParticipanteByID(id int64) bool {
 	return rowsAffected > 0
 }
 
-func GetParticipantesByVotacaoID(votacaoID int64) []*entities.Participante {
-	query := `
-		SELECT p.id, p.nome, p.url_foto
-		FROM participantes p
-		JOIN votacao_participante vp ON p.id = vp.participante_id
-		WHERE vp.votacao_id = ?
-	`
+// Delete remove o participante e os seus votos. A tabela de votos
+// particionada do MySQL não tem chaves estrangeiras; os votos são removidos depois, para
+// que um voto gravado durante a remoção não fique para trás.
+func (SQLParticipanteStore) Delete(tenantID, id int64) bool {
+	var rowsAffected int64
+	err := RunInTx(func(tx *Tx) error {
+		result, err := tx.Exec("DELETE FROM participantes WHERE id = ? AND tenant_id = ?", id, tenantID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err = result.RowsAffected()
+		if err != nil || rowsAffected == 0 {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM votos WHERE participante_id = ?", id)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error deleting participante: %v", err)
+		return false
+	}
+
+	return rowsAffected > 0
+}
+
+// Update grava a nova versão do participante. Quando o nome muda, a verificação de que ele
+// é único em cada temporada do participante e a gravação acontecem na mesma transação,
+// com as linhas das temporadas travadas, para que inclusões e renomeações simultâneas
+// não passem as duas pela verificação.
+func (SQLParticipanteStore) Update(tenantID int64, p *entities.Participante) error {
+	return RunInTx(func(tx *Tx) error {
+		var nome string
+		err := tx.QueryRow(
+			"SELECT nome FROM participantes WHERE id = ? AND tenant_id = ? "+dbDriver.ForUpdate(), p.ID, tenantID,
+		).Scan(&nome)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if p.Nome != nome {
+			if err := lockTemporadasOfParticipante(tx, p.ID); err != nil {
+				return err
+			}
+			taken, err := isNomeTakenInTemporadas(tx, p.ID, p.Nome)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return ErrNomeEmUso
+			}
+		}
+
+		return saveParticipante(tx, tenantID, p)
+	})
+}
+
+// lockTemporadasOfParticipante trava as linhas de temporada_participante de todas as
+// temporadas do participante, as mesmas que AddParticipanteToTemporadaInDB trava
+func lockTemporadasOfParticipante(tx *Tx, participanteID int64) error {
+	rows, err := tx.Query(`
+		SELECT outro.participante_id
+		FROM temporada_participante atual
+		JOIN temporada_participante outro ON outro.temporada_id = atual.temporada_id
+		WHERE atual.participante_id = ?
+		ORDER BY outro.temporada_id, outro.participante_id
+	`+dbDriver.ForUpdate("outro"), participanteID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// isNomeTakenInTemporadas indica se outro participante de alguma temporada da qual o
+// participante faz parte já usa o nome. A comparação segue a collation da coluna, que
+// ignora maiúsculas e acentos.
+func isNomeTakenInTemporadas(db queryer, participanteID int64, nome string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM temporada_participante atual
+			JOIN temporada_participante outro ON outro.temporada_id = atual.temporada_id
+			JOIN participantes p ON p.id = outro.participante_id
+			WHERE atual.participante_id = ? AND outro.participante_id <> ? AND p.nome = ?
+		)
+	`, participanteID, participanteID, nome).Scan(&exists)
+	return exists, err
+}
+
+const participantesByVotacaoQuery = `
+	SELECT ` + participanteColumns + `
+	FROM participantes p
+	JOIN votacao_participante vp ON p.id = vp.participante_id
+	WHERE vp.votacao_id = ? AND p.tenant_id = ?
+`
 
-	rows, err := DB.Query(query, votacaoID)
+func GetParticipantesByVotacaoID(tenantID, votacaoID int64) []*entities.Participante {
+	rows, err := preparedQuery(readDB("GetParticipantesByVotacaoID"), participantesByVotacaoQuery, votacaoID, tenantID)
 	if err != nil {
 		log.Printf("Error querying participantes by votacao ID: %v", err)
 		return []*entities.Participante{}
@@ -115,7 +283,7 @@ func GetParticipantesByVotacaoID(votacaoID int64) []*entities.Participante {
 	participantes := []*entities.Participante{}
 	for rows.Next() {
 		p := &entities.Participante{}
-		if err := rows.Scan(&p.ID, &p.Nome, &p.URLFoto); err != nil {
+		if err := scanParticipante(rows, p); err != nil {
 			log.Printf("Error scanning participante row: %v", err)
 			continue
 		}
@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Destinos de uma leitura
+const (
+	routePrimary = "primary"
+	routeReplica = "replica"
+)
+
+// Valores padrão do acompanhamento das réplicas, configuráveis por DB_REPLICA_MAX_LAG e
+// DB_REPLICA_CHECK_INTERVAL
+const (
+	defaultReplicaMaxLag        = 2 * time.Second
+	defaultReplicaCheckInterval = time.Second
+)
+
+// readRoutes é o destino das leituras de listas e estatísticas, por método do repositório.
+// Os valores abaixo são os padrões, sobrescritos por DB_READ_ROUTES. Os demais métodos,
+// como as buscas por ID usadas antes das alterações, leem sempre do primário.
+var readRoutes = map[string]string{
+	// Agregações das estatísticas e da análise de fraude
+	"GetTotalVotesForVotacao":     routeReplica,
+	"GetTotalVotesByParticipante": routeReplica,
+	"GetTotalVotesByHour":         routeReplica,
+	"GetVoteCountsByMinute":       routeReplica,
+	"GetSourceCounts":             routeReplica,
+	// O resultado de uma votação encerrada não pode deixar de fora os últimos votos
+	"GetFinalTotalsByParticipante": routePrimary,
+
+	// Listas
+	"GetAllParticipantes":        routeReplica,
+	"GetAllVotacoes":             routeReplica,
+	"GetAllTemporadas":           routeReplica,
+	"GetAllVotos":                routeReplica,
+	"GetAllTenants":              routeReplica,
+	"GetTemporadaParticipantes":  routeReplica,
+	"GetVotacoesByTemporadaID":   routeReplica,
+	"GetAlertasByVotacaoID":      routeReplica,
+	"GetInvalidacoesByVotacaoID": routeReplica,
+	// Lida a cada voto para validar o participante, logo depois de ele entrar na votação
+	"GetParticipantesByVotacaoID": routePrimary,
+}
+
+// replica é um servidor somente leitura com o atraso medido por último, ou -1 se a
+// medição falhou
+type replica struct {
+	addr string
+	db   *Database
+	lag  atomic.Int64
+}
+
+var (
+	replicas              []*replica
+	replicaNext           atomic.Uint64
+	replicaMaxLag         = defaultReplicaMaxLag
+	replicaMonitorStop    chan struct{}
+	lastPrimaryWriteNanos atomic.Int64
+)
+
+// connectReplicas abre as réplicas de DB_REPLICA_HOSTS (host:porta separados por
+// vírgula), com as mesmas credenciais do primário, e começa a acompanhar o atraso delas.
+// Uma réplica fora do ar não impede a inicialização: as leituras vão ao primário até
+// que ela volte.
+func connectReplicas() {
+	configureReadRoutes(getEnv("DB_READ_ROUTES", ""))
+
+	hosts := getEnv("DB_REPLICA_HOSTS", "")
+	if hosts == "" {
+		return
+	}
+	replicaMaxLag = getEnvDuration("DB_REPLICA_MAX_LAG", defaultReplicaMaxLag)
+
+	for _, addr := range strings.Split(hosts, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+
+		db, err := dbDriver.Open(addr)
+		if err != nil {
+			log.Fatalf("Failed to open replica %s: %v", addr, err)
+		}
+		r := &replica{addr: addr, db: &Database{db}}
+		configurePool(r.db.DB)
+		r.lag.Store(-1)
+		replicas = append(replicas, r)
+	}
+
+	for _, r := range replicas {
+		if err := checkReplicaLag(r); err != nil {
+			log.Printf("Replica %s unavailable, reading from primary: %v", r.addr, err)
+		} else if !r.usable() {
+			log.Printf("Replica %s is %s behind, reading from primary", r.addr, time.Duration(r.lag.Load()))
+		}
+	}
+	log.Printf("Routing reads to %d replicas with max lag %s", len(replicas), replicaMaxLag)
+
+	replicaMonitorStop = make(chan struct{})
+	go monitorReplicas(getEnvDuration("DB_REPLICA_CHECK_INTERVAL", defaultReplicaCheckInterval), replicaMonitorStop)
+}
+
+// configureReadRoutes aplica as rotas no formato Metodo=primary,Metodo=replica
+func configureReadRoutes(routes string) {
+	for _, entry := range strings.Split(routes, ",") {
+		method, route, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			if strings.TrimSpace(entry) != "" {
+				log.Printf("Ignoring malformed DB_READ_ROUTES entry %q", entry)
+			}
+			continue
+		}
+
+		method, route = strings.TrimSpace(method), strings.TrimSpace(route)
+		if _, known := readRoutes[method]; !known {
+			log.Printf("Ignoring DB_READ_ROUTES entry for unknown method %q", method)
+			continue
+		}
+		if route != routePrimary && route != routeReplica {
+			log.Printf("Ignoring DB_READ_ROUTES entry with unknown route %q", route)
+			continue
+		}
+		readRoutes[method] = route
+	}
+}
+
+// closeReplicas encerra o acompanhamento e as conexões das réplicas
+func closeReplicas() {
+	if replicaMonitorStop != nil {
+		close(replicaMonitorStop)
+		replicaMonitorStop = nil
+	}
+	for _, r := range replicas {
+		r.db.Close()
+	}
+	replicas = nil
+}
+
+func monitorReplicas(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, r := range replicas {
+				checkReplicaLag(r)
+			}
+		}
+	}
+}
+
+// checkReplicaLag mede o atraso da réplica, registrando no log quando ela passa a ser
+// usada ou deixa de ser
+func checkReplicaLag(r *replica) error {
+	wasUsable := r.usable()
+
+	lag, err := dbDriver.ReplicaLag(r.db)
+	if err != nil {
+		r.lag.Store(-1)
+		if wasUsable {
+			log.Printf("Replica %s unavailable, reading from primary: %v", r.addr, err)
+		}
+		return err
+	}
+
+	r.lag.Store(int64(lag))
+	switch usable := r.usable(); {
+	case wasUsable && !usable:
+		log.Printf("Replica %s is %s behind, reading from primary", r.addr, lag)
+	case !wasUsable && usable:
+		log.Printf("Replica %s is available (%s behind)", r.addr, lag)
+	}
+	return nil
+}
+
+// usable indica se o último atraso medido está dentro do limite
+func (r *replica) usable() bool {
+	lag := r.lag.Load()
+	return lag >= 0 && time.Duration(lag) <= replicaMaxLag
+}
+
+// notePrimaryWrite registra uma alteração no primário. Até o atraso máximo das réplicas
+// passar, as leituras vão ao primário, para que o cache recarregado depois de uma
+// invalidação não guarde dados que as réplicas ainda não receberam.
+func notePrimaryWrite() {
+	lastPrimaryWriteNanos.Store(time.Now().UnixNano())
+}
+
+// readDB retorna o banco em que o método do repositório deve ler: uma réplica dentro do
+// atraso máximo, escolhida em rodízio, se o método for roteado para as réplicas, ou o
+// primário
+func readDB(method string) *Database {
+	if len(replicas) == 0 || readRoutes[method] != routeReplica {
+		return DB
+	}
+	if time.Since(time.Unix(0, lastPrimaryWriteNanos.Load())) < replicaMaxLag {
+		return DB
+	}
+
+	start := replicaNext.Add(1)
+	for i := range replicas {
+		r := replicas[(start+uint64(i))%uint64(len(replicas))]
+		if r.usable() {
+			return r.db
+		}
+	}
+	return DB
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestReplicas troca as réplicas por servidores falsos com os atrasos informados
+func useTestReplicas(t *testing.T, lags ...time.Duration) []*replica {
+	t.Helper()
+	previous, previousMaxLag := replicas, replicaMaxLag
+	previousWrite := lastPrimaryWriteNanos.Load()
+	t.Cleanup(func() {
+		replicas, replicaMaxLag = previous, previousMaxLag
+		lastPrimaryWriteNanos.Store(previousWrite)
+	})
+
+	replicas = nil
+	for _, lag := range lags {
+		r := &replica{addr: "replica-teste", db: &Database{}}
+		r.lag.Store(int64(lag))
+		replicas = append(replicas, r)
+	}
+	replicaMaxLag = 2 * time.Second
+	lastPrimaryWriteNanos.Store(0)
+	return replicas
+}
+
+func TestReadDB(t *testing.T) {
+	const lista = "GetAllVotacoes"
+
+	tests := []struct {
+		name   string
+		lags   []time.Duration
+		method string
+		// Índice da réplica esperada, ou -1 para o primário
+		want int
+	}{
+		{"no replicas", nil, lista, -1},
+		{"replica within the max lag", []time.Duration{time.Second}, lista, 0},
+		{"replica at the max lag", []time.Duration{2 * time.Second}, lista, 0},
+		{"lagging replica", []time.Duration{3 * time.Second}, lista, -1},
+		{"unavailable replica", []time.Duration{-1}, lista, -1},
+		{"skips the lagging replica", []time.Duration{time.Minute, 0, -1}, lista, 1},
+		{"method routed to the primary", []time.Duration{0}, "GetParticipantesByVotacaoID", -1},
+		{"final results read the primary", []time.Duration{0}, "GetFinalTotalsByParticipante", -1},
+		{"method not in the table", []time.Duration{0}, "GetVotacaoByID", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servers := useTestReplicas(t, tt.lags...)
+			want := DB
+			if tt.want >= 0 {
+				want = servers[tt.want].db
+			}
+			if got := readDB(tt.method); got != want {
+				t.Errorf("readDB(%q) read from the wrong server", tt.method)
+			}
+		})
+	}
+}
+
+func TestReadDBAfterPrimaryWrite(t *testing.T) {
+	servers := useTestReplicas(t, 0)
+
+	// Logo depois de uma alteração, as réplicas podem não tê-la recebido
+	notePrimaryWrite()
+	if readDB("GetAllVotacoes") != DB {
+		t.Error("read from a replica right after a write to the primary")
+	}
+
+	lastPrimaryWriteNanos.Store(time.Now().Add(-replicaMaxLag).UnixNano())
+	if readDB("GetAllVotacoes") != servers[0].db {
+		t.Error("kept reading from the primary after the max lag")
+	}
+}
+
+func TestReadDBRoundRobin(t *testing.T) {
+	servers := useTestReplicas(t, 0, 0, 0)
+	reads := map[*Database]int{}
+	for range 9 {
+		reads[readDB("GetAllParticipantes")]++
+	}
+	for i, r := range servers {
+		if reads[r.db] != 3 {
+			t.Errorf("replica %d got %d of 9 reads, want 3", i, reads[r.db])
+		}
+	}
+
+	// A réplica atrasada é pulada e as demais continuam recebendo leituras
+	servers[1].lag.Store(int64(time.Minute))
+	reads = map[*Database]int{}
+	for range 9 {
+		reads[readDB("GetAllParticipantes")]++
+	}
+	if reads[servers[1].db] != 0 || reads[servers[0].db] == 0 || reads[servers[2].db] == 0 {
+		t.Errorf("reads = %v, want none on the lagging replica and some on each of the others", reads)
+	}
+}
+
+func TestCheckReplicaLagFailureFallsBack(t *testing.T) {
+	servers := useTestReplicas(t, 0)
+
+	// O banco de teste não é uma réplica: a medição falha e as leituras voltam ao primário
+	servers[0].db = DB
+	if err := checkReplicaLag(servers[0]); err == nil {
+		t.Fatal("measuring the lag of a server that is not a replica succeeded")
+	}
+	if servers[0].usable() {
+		t.Error("replica is usable after the lag check failed")
+	}
+	servers[0].db = &Database{}
+	if readDB("GetAllVotacoes") != DB {
+		t.Error("read from a replica whose lag check failed")
+	}
+}
+
+func TestConfigureReadRoutes(t *testing.T) {
+	previous := maps.Clone(readRoutes)
+	t.Cleanup(func() { readRoutes = previous })
+
+	configureReadRoutes(
+		" GetAllVotacoes = primary ,GetParticipantesByVotacaoID=replica," +
+			"GetAllVotos,GetVotacaoByID=replica,GetAllTenants=secundario,,",
+	)
+
+	want := maps.Clone(previous)
+	want["GetAllVotacoes"] = routePrimary
+	want["GetParticipantesByVotacaoID"] = routeReplica
+	if !maps.Equal(readRoutes, want) {
+		t.Errorf("routes = %v, want %v", readRoutes, want)
+	}
+}
+
+// Um método lido com readDB fora da tabela iria sempre ao primário, e DB_READ_ROUTES não
+// conseguiria mudá-lo
+func TestReadRoutesCoverEveryReadDBCall(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				return true
+			}
+			if fn, ok := call.Fun.(*ast.Ident); !ok || fn.Name != "readDB" {
+				return true
+			}
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				method, _ := strconv.Unquote(lit.Value)
+				if _, routed := readRoutes[method]; !routed {
+					t.Errorf("%s: readDB(%q) is not in readRoutes", fset.Position(call.Pos()), method)
+				}
+			}
+			return true
+		})
+	}
+}
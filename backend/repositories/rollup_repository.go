@@ -0,0 +1,226 @@
+package repositories
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Nome da linha de rollup_watermark travada pelas alterações nas agregações, que guarda o
+// último lote de agregação
+const rollupWatermark = "votos_por_minuto"
+
+// Número máximo de votos agregados por execução
+const RollupBatchSize = 50000
+
+type rollupKey struct {
+	votacaoID      int64
+	participanteID int64
+	minuto         string
+}
+
+// rollupTotal é a contagem de votos e a soma dos seus pesos
+type rollupTotal struct {
+	votos  int
+	pontos int
+}
+
+// AggregateNewVotos soma em votos_por_minuto os votos válidos ainda sem lote de agregação
+// e os marca com um novo lote, tudo na mesma transação. Um voto é agregado uma única vez,
+// quando quer que a sua gravação seja confirmada, sem depender da ordem dos IDs nem do
+// relógio das réplicas. A trava na linha da marca d'água serializa as execuções de réplicas
+// diferentes. Retorna quantos votos foram marcados.
+//
+// Cada execução marca no máximo RollupBatchSize votos, em um intervalo fechado de IDs. A
+// transação roda em READ COMMITTED: no InnoDB, a marcação só trava os votos marcados, sem
+// as travas de intervalo que bloqueariam a gravação dos votos novos na tabela.
+func AggregateNewVotos() (int64, error) {
+	var processed int64
+	opts := &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	err := RunInTxWithOptions(opts, func(tx *Tx) error {
+		processed = 0
+
+		lote, err := lockRollups(tx)
+		if err != nil {
+			return err
+		}
+		lote++
+
+		var lower, upper sql.NullInt64
+		err = tx.QueryRow(`
+			SELECT MIN(id), MAX(id) FROM (
+				SELECT id FROM votos WHERE lote_agregacao IS NULL ORDER BY id LIMIT ?
+			) pendentes
+		`, RollupBatchSize).Scan(&lower, &upper)
+		if err != nil {
+			return err
+		}
+		if !upper.Valid {
+			// Nenhum voto novo
+			return nil
+		}
+
+		// A marcação escolhe os votos do lote; a agregação soma exatamente os marcados. Um
+		// voto confirmado depois da leitura acima, dentro do intervalo, entra no mesmo lote.
+		result, err := tx.Exec(
+			"UPDATE votos SET lote_agregacao = ? WHERE lote_agregacao IS NULL AND id BETWEEN ? AND ?",
+			lote, lower.Int64, upper.Int64,
+		)
+		if err != nil {
+			return err
+		}
+		processed, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if err := addVotosToRollups(tx, false, "lote_agregacao = ? AND NOT invalidado", lote); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE rollup_watermark SET ultimo_lote = ? WHERE nome = ?", lote, rollupWatermark)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return processed, nil
+}
+
+// RebuildRollups compara votos_por_minuto com a contagem real dos votos já agregados
+// (com lote de agregação) e retorna as divergências. Com apply, substitui os totais
+// agregados pelos recalculados. Com votacaoID diferente de zero, considera apenas
+// aquela votação. As votações arquivadas ficam de fora: os seus votos não estão mais no
+// banco, e as agregações são o que resta deles.
+func RebuildRollups(votacaoID int64, apply bool) ([]entities.RollupDiscrepancy, error) {
+	var discrepancies []entities.RollupDiscrepancy
+	err := RunInTx(func(tx *Tx) error {
+		// Trava a marca para que o agregador não execute durante a reconstrução
+		if _, err := lockRollups(tx); err != nil {
+			return err
+		}
+
+		filter, args := " AND votacao_id NOT IN (SELECT id FROM votacoes WHERE arquivada_em IS NOT NULL)", []interface{}{}
+		if votacaoID != 0 {
+			filter, args = filter+" AND votacao_id = ?", []interface{}{votacaoID}
+		}
+
+		votos, err := queryRollupTotals(tx, `
+			SELECT votacao_id, participante_id, `+dbDriver.FormatMinute("data_hora")+` as minuto, COUNT(*), SUM(peso)
+			FROM votos
+			WHERE lote_agregacao IS NOT NULL AND NOT invalidado`+filter+`
+			GROUP BY votacao_id, participante_id, minuto
+		`, args...)
+		if err != nil {
+			return err
+		}
+
+		rollups, err := queryRollupTotals(tx, `
+			SELECT votacao_id, participante_id, `+dbDriver.FormatMinute("minuto")+`, total, pontos
+			FROM votos_por_minuto
+			WHERE 1 = 1`+filter, args...)
+		if err != nil {
+			return err
+		}
+
+		discrepancies = []entities.RollupDiscrepancy{}
+		for key, total := range votos {
+			if rollups[key] != total {
+				discrepancies = append(discrepancies, newDiscrepancy(key, rollups[key], total))
+			}
+		}
+		for key, total := range rollups {
+			if _, ok := votos[key]; !ok {
+				discrepancies = append(discrepancies, newDiscrepancy(key, total, rollupTotal{}))
+			}
+		}
+
+		if !apply || len(discrepancies) == 0 {
+			return nil
+		}
+
+		if _, err := tx.Exec("DELETE FROM votos_por_minuto WHERE 1 = 1"+filter, args...); err != nil {
+			return err
+		}
+
+		return addVotosToRollups(tx, false, "lote_agregacao IS NOT NULL AND NOT invalidado"+filter, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if apply && len(discrepancies) > 0 {
+		log.Printf("Rollups rebuilt with %d discrepancies fixed", len(discrepancies))
+	}
+	return discrepancies, nil
+}
+
+// addVotosToRollups soma aos totais de votos_por_minuto os votos que atendem a where,
+// agrupados por minuto, ou os desconta com subtract
+func addVotosToRollups(tx *Tx, subtract bool, where string, args ...interface{}) error {
+	sign := ""
+	if subtract {
+		sign = "-"
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO votos_por_minuto (votacao_id, participante_id, minuto, total, pontos)
+		SELECT votacao_id, participante_id, `+dbDriver.TruncMinute("data_hora")+` as minuto,
+			`+sign+`COUNT(*), `+sign+`SUM(peso)
+		FROM votos
+		WHERE `+where+`
+		GROUP BY votacao_id, participante_id, minuto
+	`+dbDriver.OnConflictUpdate([]string{"votacao_id", "participante_id", "minuto"},
+		"total = votos_por_minuto.total + "+dbDriver.Excluded("total")+
+			", pontos = votos_por_minuto.pontos + "+dbDriver.Excluded("pontos")),
+		args...)
+	return err
+}
+
+// lockRollups trava a linha da marca d'água até o fim da transação, o que impede o
+// agregador e outras alterações nas agregações de executarem ao mesmo tempo. Retorna o
+// último lote de agregação.
+func lockRollups(tx *Tx) (int64, error) {
+	var lote int64
+	err := tx.QueryRow(
+		"SELECT ultimo_lote FROM rollup_watermark WHERE nome = ? "+dbDriver.ForUpdate(), rollupWatermark,
+	).Scan(&lote)
+	return lote, err
+}
+
+func queryRollupTotals(tx *Tx, query string, args ...interface{}) (map[rollupKey]rollupTotal, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[rollupKey]rollupTotal)
+	for rows.Next() {
+		var key rollupKey
+		var total rollupTotal
+		if err := rows.Scan(&key.votacaoID, &key.participanteID, &key.minuto, &total.votos, &total.pontos); err != nil {
+			return nil, err
+		}
+		totals[key] = total
+	}
+
+	return totals, rows.Err()
+}
+
+func newDiscrepancy(key rollupKey, rollup, votos rollupTotal) entities.RollupDiscrepancy {
+	// Os minutos são gravados em UTC; um valor inválido fica com o horário zero
+	minuto, _ := time.Parse(time.DateTime, key.minuto)
+	return entities.RollupDiscrepancy{
+		VotacaoID:      key.votacaoID,
+		ParticipanteID: key.participanteID,
+		Minuto:         minuto,
+		Rollup:         rollup.votos,
+		Votos:          votos.votos,
+		RollupPontos:   rollup.pontos,
+		Pontos:         votos.pontos,
+	}
+}
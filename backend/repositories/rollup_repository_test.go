@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+func TestAggregateNewVotosDuringInserts(t *testing.T) {
+	votacao, participante := newTestVotacao(t)
+	const writers, votosPerWriter = 4, 25
+
+	// Os votos são gravados enquanto o agregador executa sem parar
+	var saved atomic.Int64
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range votosPerWriter {
+				voto := &entities.Voto{Participante: participante, Votacao: votacao, Peso: 2}
+				if _, err := SaveVoto(entities.DefaultTenantID, voto); err != nil {
+					t.Errorf("SaveVoto: %v", err)
+					return
+				}
+				saved.Add(1)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	aggregatorErr := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				aggregatorErr <- nil
+				return
+			default:
+			}
+			if _, err := AggregateNewVotos(); err != nil {
+				aggregatorErr <- err
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	if err := <-aggregatorErr; err != nil {
+		t.Fatalf("AggregateNewVotos during inserts: %v", err)
+	}
+
+	// Uma última execução pega o que foi gravado depois da última rodada concorrente
+	for {
+		processed, err := AggregateNewVotos()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if processed == 0 {
+			break
+		}
+	}
+
+	var total, pontos int64
+	err := DB.QueryRow(
+		"SELECT COALESCE(SUM(total), 0), COALESCE(SUM(pontos), 0) FROM votos_por_minuto WHERE votacao_id = ?",
+		votacao.ID,
+	).Scan(&total, &pontos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := saved.Load(); total != want || pontos != 2*want {
+		t.Errorf("votos_por_minuto has %d votos and %d pontos, want %d and %d", total, pontos, want, 2*want)
+	}
+
+	discrepancies, err := RebuildRollups(votacao.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("RebuildRollups found discrepancies: %+v", discrepancies)
+	}
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"database/sql"
+	"log"
+	"sync"
+)
+
+// hotQueries retorna as consultas dos caminhos mais usados: o voto, as buscas de
+// participante e votação feitas a cada voto e as estatísticas. São preparadas na
+// inicialização para que o banco não precise interpretá-las de novo a cada chamada.
+func hotQueries() []string {
+	return []string{
+		insertVotoQuery(),
+		participanteByIDQuery,
+		votacaoByIDQuery,
+		participantesByVotacaoQuery,
+		totalVotesQuery,
+		totalsByParticipanteQuery,
+		totalsByHourQuery(),
+		countsByMinuteQuery(),
+	}
+}
+
+// stmtKey identifica uma consulta preparada em um dos bancos, o primário ou uma réplica
+type stmtKey struct {
+	db    *Database
+	query string
+}
+
+// preparedStmts guarda as consultas preparadas pelo banco e pelo texto do SQL. Cada
+// *sql.Stmt vale para todo o pool: o database/sql prepara a consulta de novo, sob demanda,
+// em cada conexão que ainda não a conhece.
+var preparedStmts = struct {
+	sync.RWMutex
+	enabled bool
+	stmts   map[stmtKey]*sql.Stmt
+}{stmts: make(map[stmtKey]*sql.Stmt)}
+
+// prepareStatements prepara no primário as consultas mais usadas, a menos que
+// DB_PREPARE_STATEMENTS seja false. Uma consulta que não puder ser preparada é executada
+// sem preparo. Nas réplicas, as consultas são preparadas no primeiro uso.
+func prepareStatements() {
+	preparedStmts.Lock()
+	preparedStmts.enabled = getEnv("DB_PREPARE_STATEMENTS", "true") != "false"
+	preparedStmts.Unlock()
+
+	if !preparedStmts.enabled {
+		log.Println("Prepared statements disabled")
+		return
+	}
+
+	for _, query := range hotQueries() {
+		if _, err := prepared(DB, query); err != nil {
+			log.Printf("Error preparing statement, it will run unprepared: %v", err)
+		}
+	}
+}
+
+// closeStatements libera as consultas preparadas
+func closeStatements() {
+	preparedStmts.Lock()
+	defer preparedStmts.Unlock()
+
+	for key, stmt := range preparedStmts.stmts {
+		stmt.Close()
+		delete(preparedStmts.stmts, key)
+	}
+}
+
+// prepared retorna a consulta preparada no banco, preparando-a no primeiro uso. Retorna
+// nil sem erro quando as consultas preparadas estão desativadas.
+func prepared(db *Database, query string) (*sql.Stmt, error) {
+	key := stmtKey{db, query}
+	preparedStmts.RLock()
+	stmt, ok := preparedStmts.stmts[key]
+	enabled := preparedStmts.enabled
+	preparedStmts.RUnlock()
+	if ok || !enabled {
+		return stmt, nil
+	}
+
+	// O preparo vai ao banco, então é feito fora da trava para não atrasar as outras
+	// consultas. Se outra goroutine preparou a mesma consulta antes, vale a dela.
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	preparedStmts.Lock()
+	defer preparedStmts.Unlock()
+	if existing, ok := preparedStmts.stmts[key]; ok {
+		stmt.Close()
+		return existing, nil
+	}
+	preparedStmts.stmts[key] = stmt
+	return stmt, nil
+}
+
+// preparedQueryRow executa a consulta preparada no banco; sem preparo, executa direto
+func preparedQueryRow(db *Database, query string, args ...interface{}) *sql.Row {
+	if stmt, err := prepared(db, query); err == nil && stmt != nil {
+		return stmt.QueryRow(dbDriver.Args(args)...)
+	}
+	return db.QueryRow(query, args...)
+}
+
+// preparedQuery executa a consulta preparada no banco; sem preparo, executa direto
+func preparedQuery(db *Database, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt, err := prepared(db, query); err == nil && stmt != nil {
+		return stmt.Query(dbDriver.Args(args)...)
+	}
+	return db.Query(query, args...)
+}
+
+// preparedExec executa o comando preparado no primário; sem preparo, executa direto
+func preparedExec(query string, args ...interface{}) (sql.Result, error) {
+	if stmt, err := prepared(DB, query); err == nil && stmt != nil {
+		return stmt.Exec(dbDriver.Args(args)...)
+	}
+	return DB.Exec(query, args...)
+}
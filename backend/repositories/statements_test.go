@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+)
+
+func TestPreparedSharesOneStatementPerQuery(t *testing.T) {
+	preparedStmts.Lock()
+	enabled := preparedStmts.enabled
+	preparedStmts.enabled = true
+	preparedStmts.Unlock()
+	t.Cleanup(func() {
+		preparedStmts.Lock()
+		preparedStmts.enabled = enabled
+		preparedStmts.Unlock()
+	})
+
+	// Uma consulta que nenhum outro teste prepara, para começar sem cache
+	query := "SELECT COUNT(*) FROM votacoes WHERE id > ? AND id < ?"
+	const goroutines = 8
+	stmts := make([]*sql.Stmt, goroutines)
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stmt, err := prepared(DB, query)
+			if err != nil {
+				t.Errorf("prepared: %v", err)
+			}
+			stmts[i] = stmt
+		}()
+	}
+	wg.Wait()
+
+	for _, stmt := range stmts {
+		if stmt == nil || stmt != stmts[0] {
+			t.Fatalf("concurrent calls got different statements: %v", stmts)
+		}
+	}
+
+	// A consulta guardada continua utilizável: as preparações que perderam a corrida foram
+	// fechadas, não a que ficou no cache
+	var count int
+	if err := preparedQueryRow(DB, query, 0, 0).Scan(&count); err != nil {
+		t.Errorf("querying the cached statement: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Canal pelo qual as réplicas distribuem as recontagens aos clientes conectados a cada uma
+const statsUpdatesChannel = "stats:updates"
+
+// Número de atualizações guardadas para cada cliente lento antes de descartar as novas
+const statsUpdatesBuffer = 4
+
+var statsUpdatesSub *redis.PubSub
+
+// Clientes conectados a esta réplica, por votação
+var statsSubscribers = struct {
+	sync.Mutex
+	channels map[int64]map[chan entities.Recontagem]struct{}
+}{channels: make(map[int64]map[chan entities.Recontagem]struct{})}
+
+// SubscribeStatsUpdates retorna um canal com as recontagens de uma votação e a função
+// que encerra a inscrição
+func SubscribeStatsUpdates(votacaoID int64) (<-chan entities.Recontagem, func()) {
+	ch := make(chan entities.Recontagem, statsUpdatesBuffer)
+
+	statsSubscribers.Lock()
+	if statsSubscribers.channels[votacaoID] == nil {
+		statsSubscribers.channels[votacaoID] = make(map[chan entities.Recontagem]struct{})
+	}
+	statsSubscribers.channels[votacaoID][ch] = struct{}{}
+	statsSubscribers.Unlock()
+
+	return ch, func() {
+		statsSubscribers.Lock()
+		defer statsSubscribers.Unlock()
+
+		delete(statsSubscribers.channels[votacaoID], ch)
+		if len(statsSubscribers.channels[votacaoID]) == 0 {
+			delete(statsSubscribers.channels, votacaoID)
+		}
+	}
+}
+
+// PublishStatsUpdate envia uma recontagem aos clientes de todas as réplicas
+func PublishStatsUpdate(ctx context.Context, recontagem entities.Recontagem) error {
+	if RedisClient == nil {
+		deliverStatsUpdate(recontagem)
+		return nil
+	}
+
+	message, err := json.Marshal(recontagem)
+	if err != nil {
+		return err
+	}
+
+	// A própria réplica também recebe a mensagem pela inscrição no canal
+	if err := RedisClient.Publish(ctx, statsUpdatesChannel, message).Err(); err != nil {
+		log.Printf("Redis publish error: %v", err)
+		// Ao menos os clientes desta réplica recebem a recontagem
+		deliverStatsUpdate(recontagem)
+		return err
+	}
+
+	return nil
+}
+
+func deliverStatsUpdate(recontagem entities.Recontagem) {
+	statsSubscribers.Lock()
+	defer statsSubscribers.Unlock()
+
+	for ch := range statsSubscribers.channels[recontagem.VotacaoID] {
+		select {
+		case ch <- recontagem:
+		default:
+			// Um cliente lento perde a atualização, mas recebe a próxima
+		}
+	}
+}
+
+// subscribeStatsUpdates escuta o canal de recontagens e repassa cada uma aos clientes
+// conectados a esta réplica
+func subscribeStatsUpdates() {
+	statsUpdatesSub = RedisClient.Subscribe(context.Background(), statsUpdatesChannel)
+
+	go func() {
+		for msg := range statsUpdatesSub.Channel() {
+			var recontagem entities.Recontagem
+			if err := json.Unmarshal([]byte(msg.Payload), &recontagem); err != nil {
+				log.Printf("Error decoding stats update message: %v", err)
+				continue
+			}
+			deliverStatsUpdate(recontagem)
+		}
+	}()
+}
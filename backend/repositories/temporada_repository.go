@@ -0,0 +1,276 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// ErrNomeEmUso indica que outro participante da temporada já usa o nome
+var ErrNomeEmUso = errors.New("nome already used in the temporada")
+
+// SQLTemporadaStore guarda as temporadas no banco
+type SQLTemporadaStore struct{}
+
+func (SQLTemporadaStore) GetAll(tenantID int64) []*entities.Temporada {
+	rows, err := readDB("GetAllTemporadas").Query("SELECT id, nome FROM temporadas WHERE tenant_id = ?", tenantID)
+	if err != nil {
+		log.Printf("Error querying temporadas: %v", err)
+		return []*entities.Temporada{}
+	}
+	defer rows.Close()
+
+	temporadas := []*entities.Temporada{}
+	for rows.Next() {
+		t := &entities.Temporada{}
+		if err := rows.Scan(&t.ID, &t.Nome); err != nil {
+			log.Printf("Error scanning temporada row: %v", err)
+			continue
+		}
+		temporadas = append(temporadas, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating temporada rows: %v", err)
+	}
+
+	return temporadas
+}
+
+func (SQLTemporadaStore) GetByID(tenantID, id int64) (*entities.Temporada, error) {
+	t := &entities.Temporada{}
+	err := DB.QueryRow("SELECT id, nome FROM temporadas WHERE id = ? AND tenant_id = ?", id, tenantID).
+		Scan(&t.ID, &t.Nome)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (SQLTemporadaStore) Save(tenantID int64, t *entities.Temporada) *entities.Temporada {
+	if t.ID == 0 {
+		// Insere nova temporada
+		id, err := dbDriver.InsertID(DB, "INSERT INTO temporadas (tenant_id, nome) VALUES (?, ?)", tenantID, t.Nome)
+		if err != nil {
+			log.Printf("Error inserting temporada: %v", err)
+			return nil
+		}
+
+		t.ID = id
+	} else {
+		// Atualiza temporada existente
+		_, err := DB.Exec("UPDATE temporadas SET nome = ? WHERE id = ? AND tenant_id = ?", t.Nome, t.ID, tenantID)
+		if err != nil {
+			log.Printf("Error updating temporada: %v", err)
+			return nil
+		}
+	}
+
+	return t
+}
+
+func (SQLTemporadaStore) Delete(tenantID, id int64) bool {
+	result, err := DB.Exec("DELETE FROM temporadas WHERE id = ? AND tenant_id = ?", id, tenantID)
+	if err != nil {
+		log.Printf("Error deleting temporada: %v", err)
+		return false
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected: %v", err)
+		return false
+	}
+
+	return rowsAffected > 0
+}
+
+func GetTemporadaParticipantes(temporadaID int64) []*entities.TemporadaParticipante {
+	query := `
+		SELECT p.id, p.nome, p.url_foto, tp.status, tp.votacao_eliminacao_id
+		FROM participantes p
+		JOIN temporada_participante tp ON p.id = tp.participante_id
+		WHERE tp.temporada_id = ?
+		ORDER BY p.nome
+	`
+
+	rows, err := readDB("GetTemporadaParticipantes").Query(query, temporadaID)
+	if err != nil {
+		log.Printf("Error querying participantes by temporada ID: %v", err)
+		return []*entities.TemporadaParticipante{}
+	}
+	defer rows.Close()
+
+	participantes := []*entities.TemporadaParticipante{}
+	for rows.Next() {
+		p := &entities.TemporadaParticipante{}
+		if err := rows.Scan(&p.ParticipanteID, &p.Nome, &p.URLFoto, &p.Status, &p.VotacaoEliminacaoID); err != nil {
+			log.Printf("Error scanning temporada participante row: %v", err)
+			continue
+		}
+		participantes = append(participantes, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating temporada participante rows: %v", err)
+	}
+
+	return participantes
+}
+
+// IsNomeTakenInTemporada indica se outro participante da temporada já usa o nome
+func IsNomeTakenInTemporada(temporadaID, participanteID int64, nome string) (bool, error) {
+	return isNomeTakenInTemporada(DB, temporadaID, participanteID, nome)
+}
+
+func isNomeTakenInTemporada(db queryer, temporadaID, participanteID int64, nome string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM temporada_participante tp
+			JOIN participantes p ON p.id = tp.participante_id
+			WHERE tp.temporada_id = ? AND tp.participante_id <> ? AND p.nome = ?
+		)
+	`, temporadaID, participanteID, nome).Scan(&exists)
+	return exists, err
+}
+
+// AddParticipanteToTemporadaInDB inclui o participante na temporada como ativo. Incluir
+// um participante que já faz parte dela não altera sua situação. Retorna ErrNomeEmUso
+// se outro participante da temporada já usar o nome dele.
+func AddParticipanteToTemporadaInDB(participanteID, temporadaID int64) error {
+	return RunInTx(func(tx *Tx) error {
+		// Trava a temporada para que inclusões simultâneas verifiquem os nomes uma de cada vez
+		var nome string
+		err := tx.QueryRow(`
+			SELECT p.nome
+			FROM temporadas t
+			JOIN participantes p ON p.tenant_id = t.tenant_id
+			WHERE t.id = ? AND p.id = ?
+		`+dbDriver.ForUpdate("t"), temporadaID, participanteID).Scan(&nome)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// Trava também os participantes da temporada, que as renomeações travam antes de
+		// verificar o nome
+		if err := lockTemporadaParticipantes(tx, temporadaID); err != nil {
+			return err
+		}
+
+		taken, err := isNomeTakenInTemporada(tx, temporadaID, participanteID, nome)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrNomeEmUso
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO temporada_participante (temporada_id, participante_id, status) VALUES (?, ?, ?)
+		`+dbDriver.OnConflictIgnore("temporada_id", "participante_id"), temporadaID, participanteID, entities.StatusAtivo)
+		return err
+	})
+}
+
+// lockTemporadaParticipantes trava as linhas dos participantes da temporada, em ordem
+func lockTemporadaParticipantes(tx *Tx, temporadaID int64) error {
+	rows, err := tx.Query(
+		"SELECT participante_id FROM temporada_participante WHERE temporada_id = ? ORDER BY participante_id "+
+			dbDriver.ForUpdate(),
+		temporadaID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// UpdateParticipanteStatus altera manualmente a situação do participante na temporada
+func UpdateParticipanteStatus(temporadaID, participanteID int64, status string) bool {
+	result, err := DB.Exec(`
+		UPDATE temporada_participante SET status = ?, votacao_eliminacao_id = NULL
+		WHERE temporada_id = ? AND participante_id = ?
+	`, status, temporadaID, participanteID)
+	if err != nil {
+		log.Printf("Error updating participante status: %v", err)
+		return false
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected: %v", err)
+		return false
+	}
+
+	return rowsAffected > 0
+}
+
+// SetEliminadoByVotacao registra o eliminado de uma votação da temporada. Quem tinha
+// sido eliminado antes pela mesma votação volta a ficar ativo, então a função pode ser
+// chamada de novo após uma recontagem. Com participanteID zero, apenas desfaz a marcação.
+func SetEliminadoByVotacao(temporadaID, votacaoID, participanteID int64) error {
+	return RunInTx(func(tx *Tx) error {
+		if err := clearEliminadoByVotacao(tx, votacaoID); err != nil {
+			return err
+		}
+
+		if participanteID == 0 {
+			return nil
+		}
+
+		_, err := tx.Exec(`
+			UPDATE temporada_participante SET status = ?, votacao_eliminacao_id = ?
+			WHERE temporada_id = ? AND participante_id = ? AND status = ?
+		`, entities.StatusEliminado, votacaoID, temporadaID, participanteID, entities.StatusAtivo)
+		return err
+	})
+}
+
+func clearEliminadoByVotacao(db execer, votacaoID int64) error {
+	_, err := db.Exec(`
+		UPDATE temporada_participante SET status = ?, votacao_eliminacao_id = NULL
+		WHERE votacao_eliminacao_id = ?
+	`, entities.StatusAtivo, votacaoID)
+	return err
+}
+
+func GetVotacoesByTemporadaID(temporadaID int64) []*entities.Votacao {
+	rows, err := readDB("GetVotacoesByTemporadaID").Query(
+		"SELECT "+votacaoColumns+" FROM votacoes WHERE temporada_id = ? ORDER BY id", temporadaID,
+	)
+	if err != nil {
+		log.Printf("Error querying votacoes by temporada ID: %v", err)
+		return []*entities.Votacao{}
+	}
+	defer rows.Close()
+
+	votacoes := []*entities.Votacao{}
+	for rows.Next() {
+		v := &entities.Votacao{}
+		if err := scanVotacao(rows, v); err != nil {
+			log.Printf("Error scanning votacao row: %v", err)
+			continue
+		}
+		votacoes = append(votacoes, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating votacao rows: %v", err)
+	}
+
+	return votacoes
+}
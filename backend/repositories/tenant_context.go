@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+type tenantContextKey struct{}
+
+// Prefixo das chaves de cache e das tags de um tenant
+const tenantKeyPrefix = "tenant:%d:"
+
+// WithTenant associa à requisição o tenant que a atende
+func WithTenant(ctx context.Context, tenant *entities.Tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext retorna o tenant da requisição, ou false fora de uma requisição
+func TenantFromContext(ctx context.Context) (*entities.Tenant, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(*entities.Tenant)
+	return tenant, ok && tenant != nil
+}
+
+// TenantID retorna o ID do tenant da requisição ou, fora de uma requisição, o tenant padrão
+func TenantID(ctx context.Context) int64 {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		return tenant.ID
+	}
+	return entities.DefaultTenantID
+}
+
+// scopedKey prefixa a chave com o tenant da requisição, para que programas diferentes
+// nunca compartilhem entradas de cache. Fora de uma requisição a chave é global.
+func scopedKey(ctx context.Context, key string) string {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return key
+	}
+	return fmt.Sprintf(tenantKeyPrefix, tenant.ID) + key
+}
+
+func scopedTags(ctx context.Context, tags []string) []string {
+	scoped := make([]string, len(tags))
+	for i, tag := range tags {
+		scoped[i] = scopedKey(ctx, tag)
+	}
+	return scoped
+}
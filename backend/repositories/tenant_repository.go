@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// ErrTenantDuplicado indica que o slug ou o host já pertencem a outro tenant
+var ErrTenantDuplicado = errors.New("tenant slug or host already in use")
+
+const tenantColumns = "id, slug, nome, COALESCE(host, ''), config"
+
+func scanTenant(row interface{ Scan(...interface{}) error }, t *entities.Tenant) error {
+	var config []byte
+	if err := row.Scan(&t.ID, &t.Slug, &t.Nome, &t.Host, &config); err != nil {
+		return err
+	}
+	if config == nil {
+		return nil
+	}
+	return json.Unmarshal(config, &t.Config)
+}
+
+// HashAPIKey calcula o hash com o qual a chave de API de um tenant é gravada e buscada
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// SQLTenantStore guarda os tenants no banco
+type SQLTenantStore struct{}
+
+func (SQLTenantStore) GetAll() []*entities.Tenant {
+	rows, err := readDB("GetAllTenants").Query("SELECT " + tenantColumns + " FROM tenants ORDER BY id")
+	if err != nil {
+		log.Printf("Error querying tenants: %v", err)
+		return []*entities.Tenant{}
+	}
+	defer rows.Close()
+
+	tenants := []*entities.Tenant{}
+	for rows.Next() {
+		t := &entities.Tenant{}
+		if err := scanTenant(rows, t); err != nil {
+			log.Printf("Error scanning tenant row: %v", err)
+			continue
+		}
+		tenants = append(tenants, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating tenant rows: %v", err)
+	}
+
+	return tenants
+}
+
+func (SQLTenantStore) GetByID(id int64) (*entities.Tenant, bool) {
+	return getTenantBy("id", id)
+}
+
+func (SQLTenantStore) GetBySlug(slug string) (*entities.Tenant, bool) {
+	return getTenantBy("slug", slug)
+}
+
+func (SQLTenantStore) GetByHost(host string) (*entities.Tenant, bool) {
+	return getTenantBy("host", host)
+}
+
+func (SQLTenantStore) GetByAPIKey(apiKey string) (*entities.Tenant, bool) {
+	return getTenantBy("api_key_hash", HashAPIKey(apiKey))
+}
+
+// getTenantBy busca um tenant por uma das colunas únicas; column nunca vem da requisição
+func getTenantBy(column string, value interface{}) (*entities.Tenant, bool) {
+	t := &entities.Tenant{}
+	err := scanTenant(DB.QueryRow("SELECT "+tenantColumns+" FROM tenants WHERE "+column+" = ?", value), t)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error querying tenant by %s: %v", column, err)
+		}
+		return nil, false
+	}
+
+	return t, true
+}
+
+// Save grava na inclusão o hash da chave de API, que não é alterado nas atualizações
+func (SQLTenantStore) Save(t *entities.Tenant, apiKey string) (*entities.Tenant, error) {
+	config, err := json.Marshal(t.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Um host vazio fica nulo, já que a coluna é única
+	host := sql.NullString{String: t.Host, Valid: t.Host != ""}
+
+	if t.ID == 0 {
+		t.ID, err = dbDriver.InsertID(DB,
+			"INSERT INTO tenants (slug, nome, host, api_key_hash, config) VALUES (?, ?, ?, ?, ?)",
+			t.Slug, t.Nome, host, HashAPIKey(apiKey), config,
+		)
+		if err != nil {
+			return nil, tenantError(err)
+		}
+	} else {
+		_, err := DB.Exec(
+			"UPDATE tenants SET slug = ?, nome = ?, host = ?, config = ? WHERE id = ?",
+			t.Slug, t.Nome, host, config, t.ID,
+		)
+		if err != nil {
+			return nil, tenantError(err)
+		}
+	}
+
+	return t, nil
+}
+
+func (SQLTenantStore) RotateAPIKey(id int64, apiKey string) bool {
+	result, err := DB.Exec("UPDATE tenants SET api_key_hash = ? WHERE id = ?", HashAPIKey(apiKey), id)
+	if err != nil {
+		log.Printf("Error rotating tenant API key: %v", err)
+		return false
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected: %v", err)
+		return false
+	}
+
+	return rowsAffected > 0
+}
+
+func tenantError(err error) error {
+	if dbDriver.IsDuplicateKey(err) {
+		return ErrTenantDuplicado
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import "github.com/danielfs/paredao/backend/entities"
+
+// ParticipanteStore guarda os participantes de cada tenant. Toda operação recebe o tenant,
+// e um registro de outro tenant se comporta como inexistente.
+type ParticipanteStore interface {
+	GetAll(tenantID int64) []*entities.Participante
+	// GetByID retorna ErrNotFound se o participante não existir no tenant
+	GetByID(tenantID, id int64) (*entities.Participante, error)
+	// Save insere o participante sem ID ou atualiza o existente, retornando nil em caso de falha
+	Save(tenantID int64, p *entities.Participante) *entities.Participante
+	// Update grava a nova versão do participante existente, retornando ErrNotFound se ele
+	// não existir no tenant e ErrNomeEmUso se o novo nome já for usado por outro
+	// participante de uma das suas temporadas
+	Update(tenantID int64, p *entities.Participante) error
+	UpdateFoto(tenantID, id int64, urlFoto string) bool
+	Delete(tenantID, id int64) bool
+}
+
+// VotacaoStore guarda as votações de cada tenant
+type VotacaoStore interface {
+	GetAll(tenantID int64) []*entities.Votacao
+	// GetByID retorna ErrNotFound se a votação não existir no tenant
+	GetByID(tenantID, id int64) (*entities.Votacao, error)
+	// Save insere a votação sem ID ou atualiza a existente. Na atualização, retorna
+	// ErrNotFound se ela não existir no tenant e ErrVotacaoComVotos se o modo ou os pesos
+	// mudarem depois do primeiro voto.
+	Save(tenantID int64, v *entities.Votacao) error
+	// Encerrar retorna false se a votação não existir no tenant ou já estiver encerrada
+	Encerrar(tenantID, id int64) bool
+	Delete(tenantID, id int64) bool
+}
+
+// TemporadaStore guarda as temporadas de cada tenant
+type TemporadaStore interface {
+	GetAll(tenantID int64) []*entities.Temporada
+	// GetByID retorna ErrNotFound se a temporada não existir no tenant
+	GetByID(tenantID, id int64) (*entities.Temporada, error)
+	// Save insere a temporada sem ID ou atualiza a existente, retornando nil em caso de falha
+	Save(tenantID int64, t *entities.Temporada) *entities.Temporada
+	Delete(tenantID, id int64) bool
+}
+
+// TenantStore guarda os tenants e resolve o tenant de cada requisição
+type TenantStore interface {
+	GetAll() []*entities.Tenant
+	GetByID(id int64) (*entities.Tenant, bool)
+	GetBySlug(slug string) (*entities.Tenant, bool)
+	GetByHost(host string) (*entities.Tenant, bool)
+	// GetByAPIKey busca o tenant dono da chave de API
+	GetByAPIKey(apiKey string) (*entities.Tenant, bool)
+	// Save insere ou atualiza o tenant. A chave de API só é gravada na inclusão. Retorna
+	// ErrTenantDuplicado se o slug ou o host já pertencerem a outro tenant.
+	Save(t *entities.Tenant, apiKey string) (*entities.Tenant, error)
+	// RotateAPIKey substitui a chave de API, retornando false se o tenant não existir
+	RotateAPIKey(id int64, apiKey string) bool
+}
+
+// Armazenamentos em uso, no banco por padrão. Os testes podem trocá-los pelos em memória.
+var (
+	Participantes ParticipanteStore = SQLParticipanteStore{}
+	Votacoes      VotacaoStore      = SQLVotacaoStore{}
+	Temporadas    TemporadaStore    = SQLTemporadaStore{}
+	Tenants       TenantStore       = SQLTenantStore{}
+)
@@ -0,0 +1,338 @@
+package repositories
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// memoryRow é um registro de um tenant guardado na memória
+type memoryRow[T any] struct {
+	tenantID int64
+	value    T
+}
+
+// memoryTable guarda registros de vários tenants, com IDs sequenciais como os do banco.
+// Os registros são copiados na entrada e na saída, para que alterar um valor retornado não
+// altere o armazenado.
+type memoryTable[T any] struct {
+	mu     sync.Mutex
+	rows   map[int64]memoryRow[T]
+	nextID int64
+	clone  func(T) T
+}
+
+func newMemoryTable[T any](clone func(T) T) *memoryTable[T] {
+	return &memoryTable[T]{rows: make(map[int64]memoryRow[T]), clone: clone}
+}
+
+// all retorna os registros do tenant em ordem de ID
+func (t *memoryTable[T]) all(tenantID int64) []*T {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ids := slices.Sorted(maps.Keys(t.rows))
+	values := []*T{}
+	for _, id := range ids {
+		if row := t.rows[id]; row.tenantID == tenantID {
+			value := t.clone(row.value)
+			values = append(values, &value)
+		}
+	}
+	return values
+}
+
+func (t *memoryTable[T]) get(tenantID, id int64) (*T, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	row, ok := t.rows[id]
+	if !ok || row.tenantID != tenantID {
+		return nil, ErrNotFound
+	}
+	value := t.clone(row.value)
+	return &value, nil
+}
+
+// insert grava o registro com o próximo ID, passado a setID antes da cópia
+func (t *memoryTable[T]) insert(tenantID int64, value *T, setID func(*T, int64)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nextID++
+	setID(value, t.nextID)
+	t.rows[t.nextID] = memoryRow[T]{tenantID: tenantID, value: t.clone(*value)}
+}
+
+// update aplica fn ao registro do tenant, retornando ErrNotFound se ele não existir ou o
+// erro de fn, caso em que o registro não é alterado
+func (t *memoryTable[T]) update(tenantID, id int64, fn func(*T) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	row, ok := t.rows[id]
+	if !ok || row.tenantID != tenantID {
+		return ErrNotFound
+	}
+	value := t.clone(row.value)
+	if err := fn(&value); err != nil {
+		return err
+	}
+	t.rows[id] = memoryRow[T]{tenantID: tenantID, value: value}
+	return nil
+}
+
+func (t *memoryTable[T]) delete(tenantID, id int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	row, ok := t.rows[id]
+	if !ok || row.tenantID != tenantID {
+		return false
+	}
+	delete(t.rows, id)
+	return true
+}
+
+// MemoryParticipanteStore guarda os participantes na memória do processo, para os testes.
+// Como não guarda temporadas, o nome sempre pode mudar.
+type MemoryParticipanteStore struct {
+	table *memoryTable[entities.Participante]
+}
+
+func NewMemoryParticipanteStore() *MemoryParticipanteStore {
+	return &MemoryParticipanteStore{table: newMemoryTable(func(p entities.Participante) entities.Participante {
+		p.RedesSociais = maps.Clone(p.RedesSociais)
+		return p
+	})}
+}
+
+func (s *MemoryParticipanteStore) GetAll(tenantID int64) []*entities.Participante {
+	return s.table.all(tenantID)
+}
+
+func (s *MemoryParticipanteStore) GetByID(tenantID, id int64) (*entities.Participante, error) {
+	return s.table.get(tenantID, id)
+}
+
+func (s *MemoryParticipanteStore) Save(tenantID int64, p *entities.Participante) *entities.Participante {
+	if p.ID == 0 {
+		s.table.insert(tenantID, p, func(p *entities.Participante, id int64) { p.ID = id })
+		return p
+	}
+
+	if err := s.Update(tenantID, p); err != nil {
+		return nil
+	}
+	return p
+}
+
+func (s *MemoryParticipanteStore) Update(tenantID int64, p *entities.Participante) error {
+	return s.table.update(tenantID, p.ID, func(current *entities.Participante) error {
+		*current = *p
+		current.RedesSociais = maps.Clone(p.RedesSociais)
+		return nil
+	})
+}
+
+func (s *MemoryParticipanteStore) UpdateFoto(tenantID, id int64, urlFoto string) bool {
+	err := s.table.update(tenantID, id, func(p *entities.Participante) error {
+		p.URLFoto = urlFoto
+		return nil
+	})
+	return err == nil
+}
+
+func (s *MemoryParticipanteStore) Delete(tenantID, id int64) bool {
+	return s.table.delete(tenantID, id)
+}
+
+// MemoryVotacaoStore guarda as votações na memória do processo, para os testes. Como não
+// guarda votos, o modo e os pesos sempre podem mudar.
+type MemoryVotacaoStore struct {
+	table *memoryTable[entities.Votacao]
+}
+
+func NewMemoryVotacaoStore() *MemoryVotacaoStore {
+	return &MemoryVotacaoStore{table: newMemoryTable(func(v entities.Votacao) entities.Votacao {
+		v.Pesos = maps.Clone(v.Pesos)
+		return v
+	})}
+}
+
+func (s *MemoryVotacaoStore) GetAll(tenantID int64) []*entities.Votacao {
+	return s.table.all(tenantID)
+}
+
+func (s *MemoryVotacaoStore) GetByID(tenantID, id int64) (*entities.Votacao, error) {
+	return s.table.get(tenantID, id)
+}
+
+func (s *MemoryVotacaoStore) Save(tenantID int64, v *entities.Votacao) error {
+	if v.Modo == "" {
+		v.Modo = entities.ModoUnica
+	}
+	if v.ID == 0 {
+		s.table.insert(tenantID, v, func(v *entities.Votacao, id int64) { v.ID = id })
+		return nil
+	}
+
+	// A atualização mantém os campos que o banco também não altera
+	return s.table.update(tenantID, v.ID, func(current *entities.Votacao) error {
+		current.Descricao = v.Descricao
+		current.Modo = v.Modo
+		current.Pesos = maps.Clone(v.Pesos)
+		current.TemporadaID = v.TemporadaID
+		return nil
+	})
+}
+
+func (s *MemoryVotacaoStore) Encerrar(tenantID, id int64) bool {
+	err := s.table.update(tenantID, id, func(v *entities.Votacao) error {
+		if v.Encerrada() {
+			return ErrVotacaoEncerrada
+		}
+		now := time.Now()
+		v.EncerradaEm = &now
+		return nil
+	})
+	return err == nil
+}
+
+func (s *MemoryVotacaoStore) Delete(tenantID, id int64) bool {
+	return s.table.delete(tenantID, id)
+}
+
+// MemoryTemporadaStore guarda as temporadas na memória do processo, para os testes
+type MemoryTemporadaStore struct {
+	table *memoryTable[entities.Temporada]
+}
+
+func NewMemoryTemporadaStore() *MemoryTemporadaStore {
+	return &MemoryTemporadaStore{table: newMemoryTable(func(t entities.Temporada) entities.Temporada { return t })}
+}
+
+func (s *MemoryTemporadaStore) GetAll(tenantID int64) []*entities.Temporada {
+	return s.table.all(tenantID)
+}
+
+func (s *MemoryTemporadaStore) GetByID(tenantID, id int64) (*entities.Temporada, error) {
+	return s.table.get(tenantID, id)
+}
+
+func (s *MemoryTemporadaStore) Save(tenantID int64, t *entities.Temporada) *entities.Temporada {
+	if t.ID == 0 {
+		s.table.insert(tenantID, t, func(t *entities.Temporada, id int64) { t.ID = id })
+		return t
+	}
+
+	err := s.table.update(tenantID, t.ID, func(current *entities.Temporada) error {
+		current.Nome = t.Nome
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+func (s *MemoryTemporadaStore) Delete(tenantID, id int64) bool {
+	return s.table.delete(tenantID, id)
+}
+
+// memoryTenant é um tenant guardado na memória com o hash da sua chave de API
+type memoryTenant struct {
+	tenant     entities.Tenant
+	apiKeyHash string
+}
+
+// MemoryTenantStore guarda os tenants na memória do processo, para os testes
+type MemoryTenantStore struct {
+	table *memoryTable[memoryTenant]
+}
+
+func NewMemoryTenantStore() *MemoryTenantStore {
+	return &MemoryTenantStore{table: newMemoryTable(func(t memoryTenant) memoryTenant {
+		t.tenant.Config.Modos = slices.Clone(t.tenant.Config.Modos)
+		return t
+	})}
+}
+
+// Os tenants não pertencem a um tenant; todos ficam na tabela com o tenant zero
+const memoryTenantOwner = 0
+
+func (s *MemoryTenantStore) GetAll() []*entities.Tenant {
+	tenants := []*entities.Tenant{}
+	for _, t := range s.table.all(memoryTenantOwner) {
+		tenants = append(tenants, &t.tenant)
+	}
+	return tenants
+}
+
+func (s *MemoryTenantStore) GetByID(id int64) (*entities.Tenant, bool) {
+	t, err := s.table.get(memoryTenantOwner, id)
+	if err != nil {
+		return nil, false
+	}
+	return &t.tenant, true
+}
+
+func (s *MemoryTenantStore) GetBySlug(slug string) (*entities.Tenant, bool) {
+	return s.find(func(t memoryTenant) bool { return t.tenant.Slug == slug })
+}
+
+func (s *MemoryTenantStore) GetByHost(host string) (*entities.Tenant, bool) {
+	return s.find(func(t memoryTenant) bool { return host != "" && t.tenant.Host == host })
+}
+
+func (s *MemoryTenantStore) GetByAPIKey(apiKey string) (*entities.Tenant, bool) {
+	hash := HashAPIKey(apiKey)
+	return s.find(func(t memoryTenant) bool { return t.apiKeyHash == hash })
+}
+
+func (s *MemoryTenantStore) find(match func(memoryTenant) bool) (*entities.Tenant, bool) {
+	for _, t := range s.table.all(memoryTenantOwner) {
+		if match(*t) {
+			return &t.tenant, true
+		}
+	}
+	return nil, false
+}
+
+func (s *MemoryTenantStore) Save(t *entities.Tenant, apiKey string) (*entities.Tenant, error) {
+	// O slug e o host são únicos, como as colunas do banco
+	for _, other := range s.table.all(memoryTenantOwner) {
+		sameHost := t.Host != "" && strings.EqualFold(other.tenant.Host, t.Host)
+		if other.tenant.ID != t.ID && (other.tenant.Slug == t.Slug || sameHost) {
+			return nil, ErrTenantDuplicado
+		}
+	}
+
+	if t.ID == 0 {
+		row := &memoryTenant{tenant: *t, apiKeyHash: HashAPIKey(apiKey)}
+		s.table.insert(memoryTenantOwner, row, func(row *memoryTenant, id int64) { row.tenant.ID = id })
+		t.ID = row.tenant.ID
+		return t, nil
+	}
+
+	err := s.table.update(memoryTenantOwner, t.ID, func(current *memoryTenant) error {
+		current.tenant = *t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *MemoryTenantStore) RotateAPIKey(id int64, apiKey string) bool {
+	err := s.table.update(memoryTenantOwner, id, func(t *memoryTenant) error {
+		t.apiKeyHash = HashAPIKey(apiKey)
+		return nil
+	})
+	return err == nil
+}
@@ -0,0 +1,322 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// tenantStores reúne os armazenamentos de uma implementação e os dois tenants usados nos
+// testes de isolamento
+type tenantStores struct {
+	participantes ParticipanteStore
+	votacoes      VotacaoStore
+	temporadas    TemporadaStore
+	tenantA       int64
+	tenantB       int64
+}
+
+// storeImplementations retorna os armazenamentos no banco de teste e os em memória
+func storeImplementations(t *testing.T) map[string]tenantStores {
+	t.Helper()
+	return map[string]tenantStores{
+		"sql": {
+			participantes: SQLParticipanteStore{},
+			votacoes:      SQLVotacaoStore{},
+			temporadas:    SQLTemporadaStore{},
+			tenantA:       createTestTenant(t, SQLTenantStore{}).ID,
+			tenantB:       createTestTenant(t, SQLTenantStore{}).ID,
+		},
+		"memory": {
+			participantes: NewMemoryParticipanteStore(),
+			votacoes:      NewMemoryVotacaoStore(),
+			temporadas:    NewMemoryTemporadaStore(),
+			tenantA:       1,
+			tenantB:       2,
+		},
+	}
+}
+
+// createTestTenant cria um tenant com slug e host únicos
+func createTestTenant(t *testing.T, store TenantStore) *entities.Tenant {
+	t.Helper()
+	slug := fmt.Sprintf("teste-%d", time.Now().UnixNano())
+	tenant, err := store.Save(&entities.Tenant{Slug: slug, Nome: slug, Host: slug + ".example.com"}, "chave-"+slug)
+	if err != nil {
+		t.Fatalf("creating tenant %s: %v", slug, err)
+	}
+	return tenant
+}
+
+func TestParticipanteStoreIsolatesTenants(t *testing.T) {
+	for name, s := range storeImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			p := s.participantes.Save(s.tenantA, &entities.Participante{Nome: "Ana", URLFoto: "/a.jpg"})
+			if p == nil {
+				t.Fatal("Save returned nil")
+			}
+
+			for _, other := range s.participantes.GetAll(s.tenantB) {
+				if other.ID == p.ID {
+					t.Error("GetAll of tenant B lists the participante of tenant A")
+				}
+			}
+			if _, err := s.participantes.GetByID(s.tenantB, p.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetByID from tenant B: got %v, want ErrNotFound", err)
+			}
+
+			s.participantes.Save(s.tenantB, &entities.Participante{ID: p.ID, Nome: "Invasor", URLFoto: "/b.jpg"})
+			if s.participantes.UpdateFoto(s.tenantB, p.ID, "/invasor.jpg") {
+				t.Error("UpdateFoto from tenant B succeeded")
+			}
+			if s.participantes.Delete(s.tenantB, p.ID) {
+				t.Error("Delete from tenant B succeeded")
+			}
+
+			got, err := s.participantes.GetByID(s.tenantA, p.ID)
+			if err != nil {
+				t.Fatalf("GetByID from tenant A: %v", err)
+			}
+			if got.Nome != "Ana" || got.URLFoto != "/a.jpg" {
+				t.Errorf("tenant B changed the participante: got %q %q", got.Nome, got.URLFoto)
+			}
+
+			if !s.participantes.Delete(s.tenantA, p.ID) {
+				t.Error("Delete from tenant A failed")
+			}
+		})
+	}
+}
+
+func TestVotacaoStoreIsolatesTenants(t *testing.T) {
+	for name, s := range storeImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			v := &entities.Votacao{Descricao: "Paredão A"}
+			if err := s.votacoes.Save(s.tenantA, v); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			for _, other := range s.votacoes.GetAll(s.tenantB) {
+				if other.ID == v.ID {
+					t.Error("GetAll of tenant B lists the votação of tenant A")
+				}
+			}
+			if _, err := s.votacoes.GetByID(s.tenantB, v.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetByID from tenant B: got %v, want ErrNotFound", err)
+			}
+
+			update := &entities.Votacao{ID: v.ID, Descricao: "Invasor"}
+			if err := s.votacoes.Save(s.tenantB, update); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Save from tenant B: got %v, want ErrNotFound", err)
+			}
+			if s.votacoes.Encerrar(s.tenantB, v.ID) {
+				t.Error("Encerrar from tenant B succeeded")
+			}
+			if s.votacoes.Delete(s.tenantB, v.ID) {
+				t.Error("Delete from tenant B succeeded")
+			}
+
+			got, err := s.votacoes.GetByID(s.tenantA, v.ID)
+			if err != nil {
+				t.Fatalf("GetByID from tenant A: %v", err)
+			}
+			if got.Descricao != "Paredão A" || got.Encerrada() {
+				t.Errorf("tenant B changed the votação: got %q, encerrada %v", got.Descricao, got.Encerrada())
+			}
+
+			if !s.votacoes.Delete(s.tenantA, v.ID) {
+				t.Error("Delete from tenant A failed")
+			}
+		})
+	}
+}
+
+func TestTemporadaStoreIsolatesTenants(t *testing.T) {
+	for name, s := range storeImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			temporada := s.temporadas.Save(s.tenantA, &entities.Temporada{Nome: "Temporada A"})
+			if temporada == nil {
+				t.Fatal("Save returned nil")
+			}
+
+			for _, other := range s.temporadas.GetAll(s.tenantB) {
+				if other.ID == temporada.ID {
+					t.Error("GetAll of tenant B lists the temporada of tenant A")
+				}
+			}
+			if _, err := s.temporadas.GetByID(s.tenantB, temporada.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetByID from tenant B: got %v, want ErrNotFound", err)
+			}
+
+			s.temporadas.Save(s.tenantB, &entities.Temporada{ID: temporada.ID, Nome: "Invasor"})
+			if s.temporadas.Delete(s.tenantB, temporada.ID) {
+				t.Error("Delete from tenant B succeeded")
+			}
+
+			got, err := s.temporadas.GetByID(s.tenantA, temporada.ID)
+			if err != nil {
+				t.Fatalf("GetByID from tenant A: %v", err)
+			}
+			if got.Nome != "Temporada A" {
+				t.Errorf("tenant B changed the temporada: got %q", got.Nome)
+			}
+
+			if !s.temporadas.Delete(s.tenantA, temporada.ID) {
+				t.Error("Delete from tenant A failed")
+			}
+		})
+	}
+}
+
+func TestTenantStoreAPIKeys(t *testing.T) {
+	stores := map[string]TenantStore{"sql": SQLTenantStore{}, "memory": NewMemoryTenantStore()}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			a := createTestTenant(t, store)
+			b := createTestTenant(t, store)
+
+			if got, ok := store.GetByAPIKey("chave-" + a.Slug); !ok || got.ID != a.ID {
+				t.Errorf("GetByAPIKey of tenant A: got %v %v, want tenant %d", got, ok, a.ID)
+			}
+			if _, ok := store.GetByAPIKey("inexistente"); ok {
+				t.Error("GetByAPIKey accepted an unknown key")
+			}
+			if got, ok := store.GetByHost(b.Host); !ok || got.ID != b.ID {
+				t.Errorf("GetByHost of tenant B: got %v %v, want tenant %d", got, ok, b.ID)
+			}
+
+			duplicado := &entities.Tenant{Slug: a.Slug, Nome: "Duplicado"}
+			if _, err := store.Save(duplicado, "outra"); !errors.Is(err, ErrTenantDuplicado) {
+				t.Errorf("Save with a taken slug: got %v, want ErrTenantDuplicado", err)
+			}
+
+			if !store.RotateAPIKey(a.ID, "nova-"+a.Slug) {
+				t.Fatal("RotateAPIKey failed")
+			}
+			if _, ok := store.GetByAPIKey("chave-" + a.Slug); ok {
+				t.Error("the old key still resolves after RotateAPIKey")
+			}
+			if got, ok := store.GetByAPIKey("nova-" + a.Slug); !ok || got.ID != a.ID {
+				t.Errorf("GetByAPIKey with the new key: got %v %v, want tenant %d", got, ok, a.ID)
+			}
+		})
+	}
+}
+
+func TestCacheKeysIsolateTenants(t *testing.T) {
+	key := testCacheKey(t)
+	tag := key + ":tag"
+	ctxA := WithTenant(context.Background(), &entities.Tenant{ID: 1001})
+	ctxB := WithTenant(context.Background(), &entities.Tenant{ID: 1002})
+	opts := CacheOptions{TTL: time.Hour, Tags: []string{tag}}
+
+	load := func(ctx context.Context, value string) string {
+		got, err := GetOrLoadCache(ctx, key, opts, func() (string, error) { return value, nil })
+		if err != nil {
+			t.Fatalf("GetOrLoadCache: %v", err)
+		}
+		return got
+	}
+
+	if got := load(ctxA, "A"); got != "A" {
+		t.Fatalf("tenant A: got %q, want A", got)
+	}
+	// A mesma chave em outro tenant não reaproveita o valor do primeiro
+	if got := load(ctxB, "B"); got != "B" {
+		t.Fatalf("tenant B read %q from the cache of tenant A", got)
+	}
+	if got := load(ctxA, "recarregado"); got != "A" {
+		t.Errorf("tenant A: got %q, want the cached A", got)
+	}
+
+	// Invalidar a tag num tenant não afeta o outro
+	if err := InvalidateCacheTags(ctxB, tag); err != nil {
+		t.Fatalf("InvalidateCacheTags: %v", err)
+	}
+	if got := load(ctxB, "B2"); got != "B2" {
+		t.Errorf("tenant B after invalidation: got %q, want B2", got)
+	}
+	if got := load(ctxA, "recarregado"); got != "A" {
+		t.Errorf("tenant A after invalidating tenant B: got %q, want A", got)
+	}
+}
+
+func TestVotacaoQueriesIsolateTenants(t *testing.T) {
+	votacao, participante := newTestVotacao(t)
+	other := createTestTenant(t, SQLTenantStore{}).ID
+	if _, err := SaveVoto(entities.DefaultTenantID, &entities.Voto{
+		Participante: participante, Votacao: votacao, IPHash: "origem",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AggregateNewVotos(); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	if err := SaveAlerta(&entities.Alerta{
+		VotacaoID: votacao.ID, ParticipanteID: participante.ID, Tipo: entities.AlertaTaxaOrigem,
+		Origem: "origem", JanelaInicio: now.Add(-time.Minute), JanelaFim: now,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	alertas := GetAlertasByVotacaoID(entities.DefaultTenantID, votacao.ID)
+	if len(alertas) != 1 {
+		t.Fatalf("got %d alertas in the votacao's tenant, want 1", len(alertas))
+	}
+	filter := VotoFilter{VotacaoID: votacao.ID, ParticipanteID: participante.ID}
+	invalidacao, err := InvalidateVotos(entities.DefaultTenantID, filter, "teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nenhuma leitura de outro tenant alcança a votação, mesmo pelo ID certo
+	if got := GetParticipantesByVotacaoID(other, votacao.ID); len(got) != 0 {
+		t.Errorf("GetParticipantesByVotacaoID from another tenant returned %d participantes", len(got))
+	}
+	if got, err := GetTotalVotesByParticipante(other, votacao.ID); err != nil || len(got) != 0 {
+		t.Errorf("GetTotalVotesByParticipante from another tenant: got %v, %v", got, err)
+	}
+	if got, err := GetFinalTotalsByParticipante(other, votacao.ID); err != nil || len(got) != 0 {
+		t.Errorf("GetFinalTotalsByParticipante from another tenant: got %v, %v", got, err)
+	}
+	if got := GetAlertasByVotacaoID(other, votacao.ID); len(got) != 0 {
+		t.Errorf("GetAlertasByVotacaoID from another tenant returned %d alertas", len(got))
+	}
+	if _, exists := GetAlertaByID(other, alertas[0].ID); exists {
+		t.Error("GetAlertaByID from another tenant found the alerta")
+	}
+	if got := GetInvalidacoesByVotacaoID(other, votacao.ID); len(got) != 0 {
+		t.Errorf("GetInvalidacoesByVotacaoID from another tenant returned %d invalidacoes", len(got))
+	}
+	if _, exists := GetInvalidacaoByID(other, invalidacao.ID); exists {
+		t.Error("GetInvalidacaoByID from another tenant found the invalidacao")
+	}
+	if _, err := InvalidateVotos(other, filter, "invasor"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("InvalidateVotos from another tenant: got %v, want ErrNotFound", err)
+	}
+	if _, err := RevertInvalidacao(other, invalidacao.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RevertInvalidacao from another tenant: got %v, want ErrNotFound", err)
+	}
+	if got, err := GetVotacaoChain(other, votacao.ID); err != nil || len(got) != 0 {
+		t.Errorf("GetVotacaoChain from another tenant: got %v, %v", got, err)
+	}
+
+	// O tenant da votação continua vendo tudo
+	if _, exists := GetInvalidacaoByID(entities.DefaultTenantID, invalidacao.ID); !exists {
+		t.Error("GetInvalidacaoByID from the votacao's tenant did not find the invalidacao")
+	}
+	if _, err := RevertInvalidacao(entities.DefaultTenantID, invalidacao.ID); err != nil {
+		t.Errorf("RevertInvalidacao from the votacao's tenant: %v", err)
+	}
+	totals, err := GetFinalTotalsByParticipante(entities.DefaultTenantID, votacao.ID)
+	if err != nil || len(totals) != 1 || totals[0].Total != 1 {
+		t.Errorf("GetFinalTotalsByParticipante after the revert: got %v, %v; want one voto", totals, err)
+	}
+	if got, err := GetVotacaoChain(entities.DefaultTenantID, votacao.ID); err != nil || len(got) != 1 {
+		t.Errorf("GetVotacaoChain from the votacao's tenant: got %v, %v", got, err)
+	}
+}
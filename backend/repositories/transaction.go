@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"math/rand/v2"
+	"time"
+)
+
+// Número máximo de tentativas de uma transação desfeita pelo banco por conflito de travas
+const maxTxAttempts = 3
+
+// Espera antes de repetir uma transação, multiplicada pela tentativa e com variação
+// aleatória para que as transações em conflito não colidam de novo
+const txRetryDelay = 20 * time.Millisecond
+
+// RunInTx executa fn como uma unidade de trabalho: os comandos feitos em tx são
+// confirmados juntos se fn retornar nil e desfeitos caso contrário. Se o banco desfizer
+// a transação por deadlock ou por tempo de espera de trava esgotado, fn é executada de
+// novo desde o início, então não deve ter efeitos fora de tx além dos seus resultados.
+func RunInTx(fn func(tx *Tx) error) error {
+	return RunInTxWithOptions(nil, fn)
+}
+
+// RunInTxWithOptions é RunInTx com nível de isolamento ou modo somente leitura próprios
+func RunInTxWithOptions(opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	return retryOnLockConflict(func() error {
+		tx, err := DB.BeginTx(context.Background(), opts)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// retryOnLockConflict repete a operação enquanto ela falhar por deadlock ou espera de
+// trava, até maxTxAttempts vezes
+func retryOnLockConflict(op func() error) error {
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		if err = op(); err == nil || !dbDriver.IsLockConflict(err) {
+			return err
+		}
+		if attempt < maxTxAttempts {
+			log.Printf("Retrying transaction after lock conflict (attempt %d of %d): %v", attempt, maxTxAttempts, err)
+			time.Sleep(time.Duration(attempt)*txRetryDelay + rand.N(txRetryDelay))
+		}
+	}
+	return err
+}
@@ -2,13 +2,66 @@ package repositories
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"log"
+	"maps"
+	"time"
 
 	"github.com/danielfs/paredao/backend/entities"
 )
 
-func GetAllVotacoes() []*entities.Votacao {
-	rows, err := DB.Query("SELECT id, descricao FROM votacoes")
+// ErrParticipanteInativo indica que o participante não está ativo na temporada da votação
+var ErrParticipanteInativo = errors.New("participante is not active in the temporada")
+
+// ErrRodadaExistente indica que a votação de origem já tem uma rodada derivada com o
+// mesmo critério
+var ErrRodadaExistente = errors.New("votacao already has a derived round with this criterio")
+
+// ErrVotacaoComVotos indica que o modo e os pesos da votação não podem mais mudar, porque
+// ela já recebeu votos
+var ErrVotacaoComVotos = errors.New("votacao already has votos")
+
+const votacaoColumns = "id, descricao, encerrada_em, modo, pesos, votacao_origem_id, COALESCE(criterio, ''), " +
+	"temporada_id, arquivada_em"
+
+// inTenantVotacao restringe as linhas de uma tabela com votacao_id às votações do tenant
+// passado como argumento
+func inTenantVotacao(table string) string {
+	return "EXISTS (SELECT 1 FROM votacoes vt WHERE vt.id = " + table + ".votacao_id AND vt.tenant_id = ?)"
+}
+
+func scanVotacao(row interface{ Scan(...interface{}) error }, v *entities.Votacao) error {
+	var pesos []byte
+	err := row.Scan(
+		&v.ID, &v.Descricao, &v.EncerradaEm, &v.Modo, &pesos, &v.VotacaoOrigemID, &v.Criterio, &v.TemporadaID, &v.ArquivadaEm,
+	)
+	if err != nil {
+		return err
+	}
+	if pesos == nil {
+		return nil
+	}
+	return json.Unmarshal(pesos, &v.Pesos)
+}
+
+// pesosJSON converte os pesos dos canais para a coluna JSON, que fica nula quando não há pesos
+func pesosJSON(pesos map[string]int) (interface{}, error) {
+	if len(pesos) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(pesos)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// SQLVotacaoStore guarda as votações no banco
+type SQLVotacaoStore struct{}
+
+func (SQLVotacaoStore) GetAll(tenantID int64) []*entities.Votacao {
+	rows, err := readDB("GetAllVotacoes").Query("SELECT "+votacaoColumns+" FROM votacoes WHERE tenant_id = ?", tenantID)
 	if err != nil {
 		log.Printf("Error querying votacoes: %v", err)
 		return []*entities.Votacao{}
@@ -18,7 +71,7 @@ func GetAllVotacoes() []*entities.Votacao {
 	votacoes := []*entities.Votacao{}
 	for rows.Next() {
 		v := &entities.Votacao{}
-		if err := rows.Scan(&v.ID, &v.Descricao); err != nil {
+		if err := scanVotacao(rows, v); err != nil {
 			log.Printf("Error scanning votacao row: %v", err)
 			continue
 		}
@@ -32,59 +85,115 @@ func GetAllVotacoes() []*entities.Votacao {
 	return votacoes
 }
 
-func GetVotacaoByID(id int64) (*entities.Votacao, bool) {
+const votacaoByIDQuery = "SELECT " + votacaoColumns + " FROM votacoes WHERE id = ? AND tenant_id = ?"
+
+func (SQLVotacaoStore) GetByID(tenantID, id int64) (*entities.Votacao, error) {
 	v := &entities.Votacao{}
-	err := DB.QueryRow("SELECT id, descricao FROM votacoes WHERE id = ?", id).
-		Scan(&v.ID, &v.Descricao)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, false
-		}
-		log.Printf("Error querying votacao by ID: %v", err)
-		return nil, false
+	err := scanVotacao(preparedQueryRow(DB, votacaoByIDQuery, id, tenantID), v)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
 	}
 
-	return v, true
+	return v, nil
 }
 
-func SaveVotacao(v *entities.Votacao) *entities.Votacao {
+// Save grava a votação. A atualização confere, na mesma transação, se o modo ou os pesos
+// podem mudar.
+func (SQLVotacaoStore) Save(tenantID int64, v *entities.Votacao) error {
 	if v.ID == 0 {
-		// Insere nova votação
-		result, err := DB.Exec(
-			"INSERT INTO votacoes (descricao) VALUES (?)",
-			v.Descricao,
-		)
-		if err != nil {
-			log.Printf("Error inserting votacao: %v", err)
-			return nil
-		}
+		return saveVotacao(DB, tenantID, v)
+	}
+	return RunInTx(func(tx *Tx) error {
+		return saveVotacao(tx, tenantID, v)
+	})
+}
 
-		id, err := result.LastInsertId()
-		if err != nil {
-			log.Printf("Error getting last insert ID: %v", err)
-			return nil
+// saveVotacao insere a votação sem ID ou atualiza a existente, no banco ou em uma transação
+func saveVotacao(db execQueryer, tenantID int64, v *entities.Votacao) error {
+	if v.Modo == "" {
+		v.Modo = entities.ModoUnica
+	}
+
+	pesos, err := pesosJSON(v.Pesos)
+	if err != nil {
+		return err
+	}
+
+	if v.ID != 0 {
+		if err := checkModoChange(db, tenantID, v); err != nil {
+			return err
 		}
 
-		v.ID = id
-	} else {
 		// Atualiza votação existente
-		_, err := DB.Exec(
-			"UPDATE votacoes SET descricao = ? WHERE id = ?",
-			v.Descricao, v.ID,
+		_, err := db.Exec(
+			"UPDATE votacoes SET descricao = ?, modo = ?, pesos = ?, temporada_id = ? WHERE id = ? AND tenant_id = ?",
+			v.Descricao, v.Modo, pesos, v.TemporadaID, v.ID, tenantID,
 		)
-		if err != nil {
-			log.Printf("Error updating votacao: %v", err)
-			return nil
-		}
+		return err
 	}
 
-	return v
+	// Insere nova votação
+	v.ID, err = dbDriver.InsertID(db,
+		"INSERT INTO votacoes (tenant_id, descricao, modo, pesos, temporada_id) VALUES (?, ?, ?, ?, ?)",
+		tenantID, v.Descricao, v.Modo, pesos, v.TemporadaID,
+	)
+	return err
 }
 
-func DeleteVotacaoByID(id int64) bool {
-	result, err := DB.Exec("DELETE FROM votacoes WHERE id = ?", id)
+// checkModoChange trava a votação e retorna ErrVotacaoComVotos se o modo ou os pesos
+// mudarem depois que ela recebeu votos, inclusive arquivados: cada voto guarda o peso com
+// que foi contado, e os totais misturariam regras diferentes
+func checkModoChange(db execQueryer, tenantID int64, v *entities.Votacao) error {
+	current := &entities.Votacao{}
+	err := scanVotacao(db.QueryRow(votacaoByIDQuery+" "+dbDriver.ForUpdate(), v.ID, tenantID), current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
 	if err != nil {
-		log.Printf("Error deleting votacao: %v", err)
+		return err
+	}
+	if current.Modo == v.Modo && maps.Equal(current.Pesos, v.Pesos) {
+		return nil
+	}
+
+	hasVotos, err := votacaoHasVotos(db, v.ID)
+	if err != nil {
+		return err
+	}
+	if hasVotos {
+		return ErrVotacaoComVotos
+	}
+	return nil
+}
+
+// VotacaoHasVotos indica se a votação já recebeu votos, inclusive os já arquivados
+func VotacaoHasVotos(votacaoID int64) bool {
+	hasVotos, err := votacaoHasVotos(DB, votacaoID)
+	if err != nil {
+		log.Printf("Error checking votos of votacao: %v", err)
+	}
+	return hasVotos
+}
+
+func votacaoHasVotos(db queryer, votacaoID int64) (bool, error) {
+	var hasVotos bool
+	err := db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM votos WHERE votacao_id = ?)
+			OR EXISTS(SELECT 1 FROM votos_por_minuto WHERE votacao_id = ?)
+	`, votacaoID, votacaoID).Scan(&hasVotos)
+	return hasVotos, err
+}
+
+// Encerrar encerra a votação, que deixa de aceitar votos
+func (SQLVotacaoStore) Encerrar(tenantID, id int64) bool {
+	result, err := DB.Exec(
+		"UPDATE votacoes SET encerrada_em = ? WHERE id = ? AND tenant_id = ? AND encerrada_em IS NULL",
+		time.Now(), id, tenantID,
+	)
+	if err != nil {
+		log.Printf("Error closing votacao: %v", err)
 		return false
 	}
 
@@ -97,42 +206,203 @@ func DeleteVotacaoByID(id int64) bool {
 	return rowsAffected > 0
 }
 
-func AddParticipanteToVotacaoInDB(participanteID, votacaoID int64) bool {
-	// Verifica se o participante e a votação existem
-	_, participanteExists := GetParticipanteByID(participanteID)
-	_, votacaoExists := GetVotacaoByID(votacaoID)
+// Delete remove a votação e os seus votos. A tabela de votos
+// particionada do MySQL não tem chaves estrangeiras; os votos são removidos depois, para
+// que um voto gravado durante a remoção não fique para trás.
+func (SQLVotacaoStore) Delete(tenantID, id int64) bool {
+	var rowsAffected int64
+	err := RunInTx(func(tx *Tx) error {
+		result, err := tx.Exec("DELETE FROM votacoes WHERE id = ? AND tenant_id = ?", id, tenantID)
+		if err != nil {
+			return err
+		}
 
-	if !participanteExists || !votacaoExists {
-		log.Printf("Cannot add participante to votacao: participante or votacao does not exist")
+		rowsAffected, err = result.RowsAffected()
+		if err != nil || rowsAffected == 0 {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM votos WHERE votacao_id = ?", id)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error deleting votacao: %v", err)
 		return false
 	}
 
-	// Verifica se o relacionamento já existe
-	var exists bool
-	err := DB.QueryRow(
-		"SELECT 1 FROM votacao_participante WHERE participante_id = ? AND votacao_id = ?",
-		participanteID, votacaoID,
-	).Scan(&exists)
+	return rowsAffected > 0
+}
 
-	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Error checking if participante is already in votacao: %v", err)
-		return false
+func GetVotacaoIDsByParticipanteID(participanteID int64) []int64 {
+	rows, err := DB.Query("SELECT votacao_id FROM votacao_participante WHERE participante_id = ?", participanteID)
+	if err != nil {
+		log.Printf("Error querying votacoes by participante ID: %v", err)
+		return []int64{}
 	}
+	defer rows.Close()
 
-	if err == nil {
-		// Relacionamento já existe
-		return true
+	votacaoIDs := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("Error scanning votacao ID row: %v", err)
+			continue
+		}
+		votacaoIDs = append(votacaoIDs, id)
 	}
 
-	// Insere novo relacionamento
-	_, err = DB.Exec(
-		"INSERT INTO votacao_participante (participante_id, votacao_id) VALUES (?, ?)",
-		participanteID, votacaoID,
-	)
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating votacao ID rows: %v", err)
+	}
+
+	return votacaoIDs
+}
+
+// AddParticipanteToVotacaoInDB inclui o participante na votação; incluir quem já faz parte
+// dela não muda nada. Retorna ErrNotFound se o participante ou a votação não forem do
+// tenant e ErrParticipanteInativo se a votação for de uma temporada em que o participante
+// não está ativo.
+func AddParticipanteToVotacaoInDB(tenantID, participanteID, votacaoID int64) error {
+	return RunInTx(func(tx *Tx) error {
+		// Trava as linhas em modo compartilhado para que não sejam removidas nem tenham a
+		// situação alterada antes da inclusão
+		var temporadaID sql.NullInt64
+		err := tx.QueryRow(`
+			SELECT v.temporada_id
+			FROM votacoes v
+			JOIN participantes p ON p.tenant_id = v.tenant_id
+			WHERE v.id = ? AND p.id = ? AND v.tenant_id = ?
+		`+dbDriver.ForShare(), votacaoID, participanteID, tenantID).Scan(&temporadaID)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if temporadaID.Valid {
+			var status string
+			err := tx.QueryRow(
+				"SELECT status FROM temporada_participante WHERE temporada_id = ? AND participante_id = ? "+dbDriver.ForShare(),
+				temporadaID.Int64, participanteID,
+			).Scan(&status)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if status != entities.StatusAtivo {
+				return ErrParticipanteInativo
+			}
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO votacao_participante (participante_id, votacao_id) VALUES (?, ?)
+		`+dbDriver.OnConflictIgnore("participante_id", "votacao_id"), participanteID, votacaoID)
+		return err
+	})
+}
+
+// CreateDerivedVotacao cria uma rodada derivada de outra votação já com os participantes
+// escolhidos, tudo na mesma transação. A nova rodada passa a decidir o paredão, então
+// quem tinha sido eliminado pela votação de origem volta a ficar ativo na temporada. Cada
+// votação tem no máximo uma rodada derivada por critério; uma segunda retorna
+// ErrRodadaExistente.
+func CreateDerivedVotacao(tenantID int64, v *entities.Votacao, participanteIDs []int64) (*entities.Votacao, error) {
+	pesos, err := pesosJSON(v.Pesos)
 	if err != nil {
-		log.Printf("Error adding participante to votacao: %v", err)
-		return false
+		return nil, err
+	}
+
+	err = RunInTx(func(tx *Tx) error {
+		// Trava a votação de origem para que derivações simultâneas sejam verificadas uma de
+		// cada vez; a chave única da migração 14 cobre o que escapar da verificação
+		var origemID int64
+		err := tx.QueryRow(
+			"SELECT id FROM votacoes WHERE id = ? AND tenant_id = ? "+dbDriver.ForUpdate(), *v.VotacaoOrigemID, tenantID,
+		).Scan(&origemID)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var exists bool
+		err = tx.QueryRow(
+			"SELECT EXISTS (SELECT 1 FROM votacoes WHERE votacao_origem_id = ? AND criterio = ?)",
+			origemID, v.Criterio,
+		).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrRodadaExistente
+		}
+
+		v.ID, err = dbDriver.InsertID(tx,
+			`INSERT INTO votacoes (tenant_id, descricao, modo, pesos, votacao_origem_id, criterio, temporada_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			tenantID, v.Descricao, v.Modo, pesos, v.VotacaoOrigemID, v.Criterio, v.TemporadaID,
+		)
+		if dbDriver.IsDuplicateKey(err) {
+			return ErrRodadaExistente
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := clearEliminadoByVotacao(tx, *v.VotacaoOrigemID); err != nil {
+			return err
+		}
+
+		for _, participanteID := range participanteIDs {
+			_, err := tx.Exec(
+				"INSERT INTO votacao_participante (participante_id, votacao_id) VALUES (?, ?)",
+				participanteID, v.ID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// GetVotacaoChain retorna todas as rodadas ligadas à votação, da votação original às
+// derivadas mais recentes, ordenadas pelo ID
+func GetVotacaoChain(tenantID, id int64) ([]*entities.Votacao, error) {
+	// Sobe até a votação original e desce por todas as rodadas derivadas dela, sem sair do tenant
+	rows, err := DB.Query(`
+		WITH RECURSIVE origem (id, votacao_origem_id) AS (
+			SELECT id, votacao_origem_id FROM votacoes WHERE id = ? AND tenant_id = ?
+			UNION ALL
+			SELECT v.id, v.votacao_origem_id FROM votacoes v JOIN origem o ON v.id = o.votacao_origem_id
+		),
+		cadeia (id) AS (
+			SELECT id FROM origem WHERE votacao_origem_id IS NULL
+			UNION ALL
+			SELECT v.id FROM votacoes v JOIN cadeia c ON v.votacao_origem_id = c.id
+		)
+		SELECT `+votacaoColumns+` FROM votacoes
+		WHERE id IN (SELECT id FROM cadeia) AND tenant_id = ?
+		ORDER BY id
+	`, id, tenantID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votacoes := []*entities.Votacao{}
+	for rows.Next() {
+		v := &entities.Votacao{}
+		if err := scanVotacao(rows, v); err != nil {
+			return nil, err
+		}
+		votacoes = append(votacoes, v)
 	}
 
-	return true
+	return votacoes, rows.Err()
 }
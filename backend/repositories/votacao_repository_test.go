@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// deriveTestVotacao deriva uma rodada da votação com o critério informado
+func deriveTestVotacao(t *testing.T, origem *entities.Votacao, criterio string, participanteIDs []int64) error {
+	t.Helper()
+	v := &entities.Votacao{
+		Descricao: origem.Descricao + " - " + criterio, Modo: entities.ModoUnica,
+		VotacaoOrigemID: &origem.ID, Criterio: criterio,
+	}
+	derived, err := CreateDerivedVotacao(entities.DefaultTenantID, v, participanteIDs)
+	if err == nil {
+		t.Cleanup(func() { Votacoes.Delete(entities.DefaultTenantID, derived.ID) })
+	}
+	return err
+}
+
+func TestCreateDerivedVotacaoOncePerCriterio(t *testing.T) {
+	origem, participante := newTestVotacao(t)
+	participanteIDs := []int64{participante.ID}
+
+	if err := deriveTestVotacao(t, origem, entities.CriterioTop, participanteIDs); err != nil {
+		t.Fatalf("first round: %v", err)
+	}
+	if err := deriveTestVotacao(t, origem, entities.CriterioTop, participanteIDs); !errors.Is(err, ErrRodadaExistente) {
+		t.Errorf("second round with the same criterio: got %v, want ErrRodadaExistente", err)
+	}
+	if err := deriveTestVotacao(t, origem, entities.CriterioEmpate, participanteIDs); err != nil {
+		t.Errorf("round with another criterio: %v", err)
+	}
+
+	tenant := createTestTenant(t, SQLTenantStore{})
+	other := &entities.Votacao{Descricao: "Outro tenant"}
+	if err := Votacoes.Save(tenant.ID, other); err != nil {
+		t.Fatal(err)
+	}
+	defer Votacoes.Delete(tenant.ID, other.ID)
+	if err := deriveTestVotacao(t, other, entities.CriterioTop, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("round from another tenant's votacao: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestConcurrentDerivedVotacoesCreateOneRound(t *testing.T) {
+	const rounds = 4
+	origem, participante := newTestVotacao(t)
+
+	errs := make([]error, rounds)
+	var wg sync.WaitGroup
+	for i := range rounds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = deriveTestVotacao(t, origem, entities.CriterioTop, []int64{participante.ID})
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrRodadaExistente):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("created %d rounds, want 1", created)
+	}
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Chave do contador de votos de um cliente em uma votação
+const voteCapKeyFormat = "votecap:%d:%s"
+
+// Confere todos os contadores do voto e só os incrementa se nenhum tiver chegado ao limite,
+// definindo a expiração de cada um na primeira contagem. A operação é atômica, para que o
+// limite seja respeitado mesmo com várias réplicas recebendo votos do mesmo cliente. Retorna
+// se o voto foi contado e, quando não foi, quanto falta para o contador no limite vencer.
+var voteCapScript = redis.NewScript(`
+local wait = 0
+for _, key in ipairs(KEYS) do
+	if tonumber(redis.call('GET', key) or '0') >= tonumber(ARGV[1]) then
+		wait = math.max(wait, redis.call('PTTL', key), 1)
+	end
+end
+if wait > 0 then
+	return {0, wait}
+end
+for _, key in ipairs(KEYS) do
+	if redis.call('INCR', key) == 1 then
+		redis.call('PEXPIRE', key, ARGV[2])
+	end
+end
+return {1, 0}
+`)
+
+// Devolve aos contadores um voto contado que não chegou a ser gravado
+var voteRefundScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	if tonumber(redis.call('GET', key) or '0') > 0 then
+		redis.call('DECR', key)
+	end
+end
+return 0
+`)
+
+// VoteCap limita quantos votos um cliente pode registrar em uma votação a cada janela.
+// Limit igual a zero desativa o limite.
+type VoteCap struct {
+	Limit  int
+	Window time.Duration
+}
+
+// VoteCapConfig é o limite em vigor, configurável por VOTE_CAP_LIMIT e VOTE_CAP_WINDOW
+var VoteCapConfig = VoteCap{Window: time.Minute}
+
+// InitVoteCap configura o limite de votos por cliente a partir das variáveis de ambiente
+func InitVoteCap() {
+	window, err := time.ParseDuration(getEnv("VOTE_CAP_WINDOW", time.Minute.String()))
+	if err != nil || window <= 0 {
+		window = time.Minute
+	}
+
+	VoteCapConfig = VoteCap{
+		Limit:  getEnvInt("VOTE_CAP_LIMIT", 0),
+		Window: window,
+	}
+}
+
+// voteCapFor retorna o limite do tenant da requisição, que sobrepõe o limite global
+// nos campos que configura
+func voteCapFor(ctx context.Context) VoteCap {
+	voteCap := VoteCapConfig
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return voteCap
+	}
+
+	if tenant.Config.VoteCapLimit != nil {
+		voteCap.Limit = *tenant.Config.VoteCapLimit
+	}
+	if window, ok := tenant.VoteCapWindowDuration(); ok {
+		voteCap.Window = window
+	}
+	return voteCap
+}
+
+// AllowVote conta um voto dos clientes na votação se todos estiverem dentro do limite do
+// tenant; basta um deles no limite para o voto ser recusado, sem contar para nenhum. Quando
+// recusa, retorna quanto tempo falta para a janela terminar. Com o limite desativado, todos
+// os votos são permitidos. Sem Redis, os votos são contados na memória do processo.
+func AllowVote(ctx context.Context, votacaoID int64, clientIDs []string) (bool, time.Duration) {
+	voteCap := voteCapFor(ctx)
+	if voteCap.Limit <= 0 {
+		return true, 0
+	}
+
+	keys := voteCapKeys(ctx, votacaoID, clientIDs)
+	if RedisClient == nil {
+		return memStore.IncrBelow(keys, int64(voteCap.Limit), voteCap.Window)
+	}
+
+	result, err := voteCapScript.Run(ctx, RedisClient, keys, voteCap.Limit, voteCap.Window.Milliseconds()).Int64Slice()
+	if err != nil {
+		// Uma falha do Redis não deve impedir a votação
+		log.Printf("Redis vote cap error: %v", err)
+		return true, 0
+	}
+
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond
+}
+
+// RefundVote desconta dos clientes um voto permitido por AllowVote que não foi gravado,
+// para que falhas na gravação não consumam o limite
+func RefundVote(ctx context.Context, votacaoID int64, clientIDs []string) {
+	if voteCapFor(ctx).Limit <= 0 {
+		return
+	}
+
+	keys := voteCapKeys(ctx, votacaoID, clientIDs)
+	if RedisClient == nil {
+		for _, key := range keys {
+			memStore.Decr(key)
+		}
+		return
+	}
+
+	if err := voteRefundScript.Run(ctx, RedisClient, keys).Err(); err != nil {
+		log.Printf("Redis vote refund error: %v", err)
+	}
+}
+
+func voteCapKeys(ctx context.Context, votacaoID int64, clientIDs []string) []string {
+	keys := make([]string, len(clientIDs))
+	for i, clientID := range clientIDs {
+		keys[i] = scopedKey(ctx, fmt.Sprintf(voteCapKeyFormat, votacaoID, clientID))
+	}
+	return keys
+}
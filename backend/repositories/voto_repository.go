@@ -2,23 +2,38 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"time"
 
 	"github.com/danielfs/paredao/backend/entities"
 )
 
-func GetAllVotos() []*entities.Voto {
+// ErrVotacaoEncerrada indica que a votação não aceita mais votos
+var ErrVotacaoEncerrada = errors.New("votacao is closed")
+
+func GetAllVotos(tenantID int64) []*entities.Voto {
+	return queryVotos("GetAllVotos", "vt.tenant_id = ?", tenantID)
+}
+
+// GetVotosByVotacaoID retorna os votos da votação com a sua origem, em ordem de gravação
+func GetVotosByVotacaoID(tenantID, votacaoID int64) []*entities.Voto {
+	return queryVotos("GetVotosByVotacaoID", "vt.tenant_id = ? AND v.votacao_id = ? ORDER BY v.id", tenantID, votacaoID)
+}
+
+func queryVotos(method, where string, args ...interface{}) []*entities.Voto {
 	query := `
 		SELECT v.participante_id, v.votacao_id, v.data_hora,
+			   COALESCE(v.ip_hash, ''), COALESCE(v.user_agent_hash, ''), COALESCE(v.device_id, ''), v.invalidado,
+			   COALESCE(v.canal, ''), v.peso,
 			   p.id, p.nome, p.url_foto,
-			   vt.id, vt.descricao
+			   vt.id, vt.descricao, vt.encerrada_em
 		FROM votos v
 		JOIN participantes p ON v.participante_id = p.id
 		JOIN votacoes vt ON v.votacao_id = vt.id
-	`
+		WHERE ` + where
 
-	rows, err := DB.Query(query)
+	rows, err := readDB(method).Query(query, args...)
 	if err != nil {
 		log.Printf("Error querying votos: %v", err)
 		return []*entities.Voto{}
@@ -34,8 +49,9 @@ func GetAllVotos() []*entities.Voto {
 
 		if err := rows.Scan(
 			&v.Participante.ID, &v.Votacao.ID, &v.DataHora,
+			&v.IPHash, &v.UserAgentHash, &v.DeviceID, &v.Invalidado, &v.Canal, &v.Peso,
 			&v.Participante.ID, &v.Participante.Nome, &v.Participante.URLFoto,
-			&v.Votacao.ID, &v.Votacao.Descricao,
+			&v.Votacao.ID, &v.Votacao.Descricao, &v.Votacao.EncerradaEm,
 		); err != nil {
 			log.Printf("Error scanning voto row: %v", err)
 			continue
@@ -51,15 +67,17 @@ func GetAllVotos() []*entities.Voto {
 	return votos
 }
 
-func GetVotoByIDs(participanteID, votacaoID int64) (*entities.Voto, bool) {
+func GetVotoByIDs(tenantID, participanteID, votacaoID int64) (*entities.Voto, bool) {
 	query := `
 		SELECT v.participante_id, v.votacao_id, v.data_hora,
+			   COALESCE(v.ip_hash, ''), COALESCE(v.user_agent_hash, ''), COALESCE(v.device_id, ''), v.invalidado,
+			   COALESCE(v.canal, ''), v.peso,
 			   p.id, p.nome, p.url_foto,
-			   vt.id, vt.descricao
+			   vt.id, vt.descricao, vt.encerrada_em
 		FROM votos v
 		JOIN participantes p ON v.participante_id = p.id
 		JOIN votacoes vt ON v.votacao_id = vt.id
-		WHERE v.participante_id = ? AND v.votacao_id = ?
+		WHERE v.participante_id = ? AND v.votacao_id = ? AND vt.tenant_id = ?
 	`
 
 	v := &entities.Voto{
@@ -67,10 +85,11 @@ func GetVotoByIDs(participanteID, votacaoID int64) (*entities.Voto, bool) {
 		Votacao:      &entities.Votacao{},
 	}
 
-	err := DB.QueryRow(query, participanteID, votacaoID).Scan(
+	err := DB.QueryRow(query, participanteID, votacaoID, tenantID).Scan(
 		&v.Participante.ID, &v.Votacao.ID, &v.DataHora,
+		&v.IPHash, &v.UserAgentHash, &v.DeviceID, &v.Invalidado, &v.Canal, &v.Peso,
 		&v.Participante.ID, &v.Participante.Nome, &v.Participante.URLFoto,
-		&v.Votacao.ID, &v.Votacao.Descricao,
+		&v.Votacao.ID, &v.Votacao.Descricao, &v.Votacao.EncerradaEm,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -83,30 +102,52 @@ func GetVotoByIDs(participanteID, votacaoID int64) (*entities.Voto, bool) {
 	return v, true
 }
 
-func SaveVoto(v *entities.Voto) *entities.Voto {
-	// Verifica se o participante e a votação existem
-	_, participanteExists := GetParticipanteByID(v.Participante.ID)
-	_, votacaoExists := GetVotacaoByID(v.Votacao.ID)
-
-	if !participanteExists || !votacaoExists {
-		log.Printf("Cannot save voto: participante or votacao does not exist")
-		return nil
-	}
+func insertVotoQuery() string {
+	return `
+		INSERT INTO votos (participante_id, votacao_id, data_hora, ip_hash, user_agent_hash, device_id, canal, peso)
+		SELECT p.id, vt.id, ` + dbDriver.Placeholder("TIMESTAMP WITH TIME ZONE") + `,
+			NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ` + dbDriver.Placeholder("INTEGER") + `
+		FROM votacoes vt
+		JOIN participantes p ON p.tenant_id = vt.tenant_id
+		WHERE p.id = ? AND vt.id = ? AND vt.tenant_id = ? AND vt.encerrada_em IS NULL
+	`
+}
 
+// SaveVoto grava o voto em um único comando, que só insere se o participante e a votação
+// forem do tenant e a votação estiver aberta, sem janela entre a verificação e a gravação.
+// Retorna ErrVotacaoEncerrada ou ErrNotFound quando o voto não pode ser gravado.
+func SaveVoto(tenantID int64, v *entities.Voto) (*entities.Voto, error) {
 	// Define o timestamp se não fornecido
 	if v.DataHora.IsZero() {
 		v.DataHora = time.Now()
 	}
 
-	// Insere novo voto
-	_, err := DB.Exec(
-		"INSERT INTO votos (participante_id, votacao_id, data_hora) VALUES (?, ?, ?)",
-		v.Participante.ID, v.Votacao.ID, v.DataHora,
-	)
+	// Um voto sem peso definido vale um ponto
+	if v.Peso == 0 {
+		v.Peso = 1
+	}
+
+	var inserted int64
+	err := retryOnLockConflict(func() error {
+		result, err := preparedExec(insertVotoQuery(), v.DataHora, v.IPHash, v.UserAgentHash, v.DeviceID, v.Canal, v.Peso,
+			v.Participante.ID, v.Votacao.ID, tenantID)
+		if err != nil {
+			return err
+		}
+		inserted, err = result.RowsAffected()
+		return err
+	})
 	if err != nil {
-		log.Printf("Error inserting voto: %v", err)
-		return nil
+		return nil, err
+	}
+
+	if inserted == 0 {
+		// Nada foi gravado: descobre o motivo para informar quem votou
+		if votacao, err := Votacoes.GetByID(tenantID, v.Votacao.ID); err == nil && votacao.Encerrada() {
+			return nil, ErrVotacaoEncerrada
+		}
+		return nil, ErrNotFound
 	}
 
-	return v
+	return v, nil
 }
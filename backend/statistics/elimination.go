@@ -0,0 +1,47 @@
+package statistics
+
+import "github.com/danielfs/paredao/backend/entities"
+
+// Eliminate aponta, em um ranking ordenado por Rank, o participante eliminado: o com mais
+// pontos ou, na votação para salvar, o com menos. Se essa posição estiver empatada não há
+// eliminado e os empatados são retornados. Sem nenhum ponto, não há eliminado nem empate.
+func Eliminate(
+	ranked []entities.ParticipanteTotalResponse,
+	modo string,
+) (eliminado *entities.ParticipanteTotalResponse, empatados []entities.ParticipanteTotalResponse) {
+	pontos := 0
+	for _, t := range ranked {
+		pontos += t.Pontos
+	}
+	if len(ranked) == 0 || pontos == 0 {
+		return nil, nil
+	}
+
+	decisive := 0
+	if modo == entities.ModoSalvar {
+		decisive = len(ranked) - 1
+	}
+
+	if !ranked[decisive].Empatado {
+		return &ranked[decisive], nil
+	}
+
+	for _, t := range ranked {
+		if t.Pontos == ranked[decisive].Pontos {
+			empatados = append(empatados, t)
+		}
+	}
+	return nil, empatados
+}
+
+// Top retorna os participantes nas n primeiras posições do ranking. Empatados na
+// posição de corte entram todos, então podem ser retornados mais de n participantes.
+func Top(ranked []entities.ParticipanteTotalResponse, n int) []entities.ParticipanteTotalResponse {
+	top := []entities.ParticipanteTotalResponse{}
+	for _, t := range ranked {
+		if t.Posicao <= n {
+			top = append(top, t)
+		}
+	}
+	return top
+}
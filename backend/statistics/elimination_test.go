@@ -0,0 +1,57 @@
+package statistics
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+func TestEliminate(t *testing.T) {
+	tests := []struct {
+		name      string
+		pontos    []int
+		modo      string
+		eliminado int64
+		empatados []int64
+	}{
+		{"most voted leaves", []int{3, 7, 1}, entities.ModoUnica, 2, nil},
+		{"weighted most voted leaves", []int{40, 25}, entities.ModoPonderada, 1, nil},
+		{"least voted leaves when saving", []int{3, 7, 1}, entities.ModoSalvar, 3, nil},
+		{"tie at the top", []int{5, 5, 1}, entities.ModoUnica, 0, []int64{1, 2}},
+		{"tie at the bottom when saving", []int{5, 1, 1}, entities.ModoSalvar, 0, []int64{2, 3}},
+		{"tie elsewhere does not matter", []int{9, 1, 1}, entities.ModoUnica, 1, nil},
+		{"no votes", []int{0, 0}, entities.ModoUnica, 0, nil},
+		{"no participantes", nil, entities.ModoUnica, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := Rank(totalsWithPontos(tt.pontos...), LargestRemainder, 2)
+			eliminado, empatados := Eliminate(ranked, tt.modo)
+
+			switch {
+			case tt.eliminado == 0 && eliminado != nil:
+				t.Errorf("eliminado = %d, want none", eliminado.ParticipanteID)
+			case tt.eliminado != 0 && (eliminado == nil || eliminado.ParticipanteID != tt.eliminado):
+				t.Errorf("eliminado = %+v, want participante %d", eliminado, tt.eliminado)
+			}
+
+			ids := []int64{}
+			for _, e := range empatados {
+				ids = append(ids, e.ParticipanteID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.empatados) && (len(ids) > 0 || len(tt.empatados) > 0) {
+				t.Errorf("empatados = %v, want %v", ids, tt.empatados)
+			}
+		})
+	}
+}
+
+func TestTopIncludesTiesAtTheCut(t *testing.T) {
+	ranked := Rank(totalsWithPontos(9, 5, 5, 1), LargestRemainder, 0)
+	if top := Top(ranked, 2); len(top) != 3 {
+		t.Errorf("Top(2) returned %d participantes, want 3 with the tie at the cut", len(top))
+	}
+}
@@ -0,0 +1,97 @@
+package statistics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// FraudThresholds são os limites a partir dos quais a análise de fraude gera alertas
+type FraudThresholds struct {
+	// MaxVotesPerSourcePerMinute é a taxa máxima de votos de uma origem em um participante
+	MaxVotesPerSourcePerMinute float64
+	// MinSourceEntropy é a entropia mínima, em bits, das origens dos votos de um participante
+	MinSourceEntropy float64
+	// MinVotes é o número de votos na janela a partir do qual a entropia é avaliada
+	MinVotes int
+}
+
+// AnalyzeSources avalia os votos por origem de uma votação em uma janela de duração
+// window e retorna os alertas encontrados, ainda sem votação e janela preenchidas.
+// Uma origem votando acima da taxa gera um alerta de taxa; um participante com votos
+// suficientes concentrados em poucas origens gera um alerta de baixa entropia,
+// atribuído à origem mais frequente.
+func AnalyzeSources(counts []entities.SourceCount, window time.Duration, thresholds FraudThresholds) []entities.Alerta {
+	minutes := window.Minutes()
+	if minutes <= 0 {
+		return nil
+	}
+
+	byParticipante := map[int64][]entities.SourceCount{}
+	ids := []int64{}
+	for _, c := range counts {
+		if _, ok := byParticipante[c.ParticipanteID]; !ok {
+			ids = append(ids, c.ParticipanteID)
+		}
+		byParticipante[c.ParticipanteID] = append(byParticipante[c.ParticipanteID], c)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	alertas := []entities.Alerta{}
+	for _, id := range ids {
+		sources := byParticipante[id]
+
+		total := 0
+		dominant := sources[0]
+		for _, s := range sources {
+			total += s.Total
+			if s.Total > dominant.Total || (s.Total == dominant.Total && s.Origem < dominant.Origem) {
+				dominant = s
+			}
+
+			rate := float64(s.Total) / minutes
+			if rate > thresholds.MaxVotesPerSourcePerMinute {
+				alertas = append(alertas, entities.Alerta{
+					ParticipanteID: id,
+					Tipo:           entities.AlertaTaxaOrigem,
+					Origem:         s.Origem,
+					Valor:          rate,
+					Limite:         thresholds.MaxVotesPerSourcePerMinute,
+					Votos:          s.Total,
+				})
+			}
+		}
+
+		if total < thresholds.MinVotes {
+			continue
+		}
+
+		if entropy := sourceEntropy(sources, total); entropy < thresholds.MinSourceEntropy {
+			alertas = append(alertas, entities.Alerta{
+				ParticipanteID: id,
+				Tipo:           entities.AlertaBaixaEntropia,
+				Origem:         dominant.Origem,
+				Valor:          entropy,
+				Limite:         thresholds.MinSourceEntropy,
+				Votos:          total,
+			})
+		}
+	}
+
+	return alertas
+}
+
+// sourceEntropy é a entropia de Shannon, em bits, da distribuição dos votos pelas origens
+func sourceEntropy(sources []entities.SourceCount, total int) float64 {
+	entropy := 0.0
+	for _, s := range sources {
+		if s.Total == 0 {
+			continue
+		}
+		p := float64(s.Total) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
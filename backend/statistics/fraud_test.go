@@ -0,0 +1,99 @@
+package statistics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+func TestAnalyzeSources(t *testing.T) {
+	thresholds := FraudThresholds{MaxVotesPerSourcePerMinute: 10, MinSourceEntropy: 2, MinVotes: 20}
+	relaxedRate := FraudThresholds{MaxVotesPerSourcePerMinute: 1000, MinSourceEntropy: 2, MinVotes: 20}
+	source := func(participanteID int64, origem string, total int) entities.SourceCount {
+		return entities.SourceCount{ParticipanteID: participanteID, Origem: origem, Total: total}
+	}
+	type alerta struct {
+		participanteID int64
+		tipo, origem   string
+	}
+
+	tests := []struct {
+		name       string
+		counts     []entities.SourceCount
+		window     time.Duration
+		thresholds FraudThresholds
+		want       []alerta
+	}{
+		{"rate at the limit", []entities.SourceCount{source(1, "a", 10)}, time.Minute, thresholds, nil},
+		{
+			"rate over the limit", []entities.SourceCount{source(1, "a", 11), source(1, "b", 3)},
+			time.Minute, thresholds, []alerta{{1, entities.AlertaTaxaOrigem, "a"}},
+		},
+		{"rate over a longer window", []entities.SourceCount{source(1, "a", 19)}, 2 * time.Minute, thresholds, nil},
+		{
+			"concentrated sources",
+			[]entities.SourceCount{source(1, "a", 17), source(1, "b", 1), source(1, "c", 1), source(1, "d", 1)},
+			time.Minute, relaxedRate, []alerta{{1, entities.AlertaBaixaEntropia, "a"}},
+		},
+		{
+			"spread sources",
+			[]entities.SourceCount{source(1, "a", 5), source(1, "b", 5), source(1, "c", 5), source(1, "d", 5)},
+			time.Minute, relaxedRate, nil,
+		},
+		{
+			"too few votes for the entropy", []entities.SourceCount{source(1, "a", 18), source(1, "b", 1)},
+			time.Minute, relaxedRate, nil,
+		},
+		{
+			"tied dominant source", []entities.SourceCount{source(1, "b", 10), source(1, "a", 10)},
+			time.Minute, relaxedRate, []alerta{{1, entities.AlertaBaixaEntropia, "a"}},
+		},
+		{
+			"participantes in order", []entities.SourceCount{source(2, "x", 12), source(1, "y", 12)},
+			time.Minute, thresholds,
+			[]alerta{{1, entities.AlertaTaxaOrigem, "y"}, {2, entities.AlertaTaxaOrigem, "x"}},
+		},
+		{"empty window", []entities.SourceCount{source(1, "a", 100)}, 0, thresholds, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alertas := AnalyzeSources(tt.counts, tt.window, tt.thresholds)
+			if len(alertas) != len(tt.want) {
+				t.Fatalf("alertas = %+v, want %+v", alertas, tt.want)
+			}
+			for i, want := range tt.want {
+				got := alertas[i]
+				if got.ParticipanteID != want.participanteID || got.Tipo != want.tipo || got.Origem != want.origem {
+					t.Errorf("alerta %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeSourcesValues(t *testing.T) {
+	thresholds := FraudThresholds{MaxVotesPerSourcePerMinute: 5, MinSourceEntropy: 1.5, MinVotes: 10}
+	counts := []entities.SourceCount{
+		{ParticipanteID: 1, Origem: "a", Total: 15},
+		{ParticipanteID: 1, Origem: "b", Total: 5},
+	}
+
+	alertas := AnalyzeSources(counts, 2*time.Minute, thresholds)
+	if len(alertas) != 2 {
+		t.Fatalf("alertas = %+v, want a rate and an entropy alerta", alertas)
+	}
+
+	rate := alertas[0]
+	if rate.Valor != 7.5 || rate.Limite != 5 || rate.Votos != 15 {
+		t.Errorf("rate alerta = %+v, want 7.5 votos per minute over 5 from 15 votos", rate)
+	}
+
+	// 3/4 e 1/4 dos votos: -(0,75·log2 0,75 + 0,25·log2 0,25) ≈ 0,811 bit
+	entropy := alertas[1]
+	if math.Abs(entropy.Valor-0.8113) > 0.0001 || entropy.Limite != 1.5 || entropy.Votos != 20 {
+		t.Errorf("entropy alerta = %+v, want about 0.811 bits under 1.5 from 20 votos", entropy)
+	}
+}
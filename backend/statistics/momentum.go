@@ -0,0 +1,105 @@
+package statistics
+
+import (
+	"math"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Momentum calcula, a partir dos totais por minuto (ordenados pelo minuto), a fatia de
+// cada participante nos pontos em [windowStart, windowEnd) comparada à fatia geral e os
+// minutos em que a liderança mudou de participante. Como no ranking, as fatias e a
+// liderança consideram os pontos, que só diferem dos votos em votações ponderadas.
+// Preenche participantes, trocas de liderança e totais da resposta.
+func Momentum(
+	counts []entities.MinuteCount,
+	participantes []*entities.Participante,
+	windowStart, windowEnd time.Time,
+) entities.MomentumResponse {
+	position := make(map[int64]int, len(participantes))
+	momentum := make([]entities.ParticipanteMomentum, len(participantes))
+	for i, p := range participantes {
+		position[p.ID] = i
+		momentum[i] = entities.ParticipanteMomentum{
+			ParticipanteID: p.ID,
+			Nome:           p.Nome,
+			Acumulado:      []entities.TimeSeriesPoint{},
+		}
+	}
+
+	response := entities.MomentumResponse{TrocasLideranca: []entities.LeadChange{}}
+	leader := -1
+	for i := 0; i < len(counts); {
+		// Aplica todos os votos do minuto antes de verificar a liderança
+		minute := counts[i].Minuto
+		for ; i < len(counts) && counts[i].Minuto.Equal(minute); i++ {
+			p, ok := position[counts[i].ParticipanteID]
+			if !ok {
+				continue
+			}
+
+			momentum[p].Total += counts[i].Total
+			momentum[p].Pontos += counts[i].Pontos
+			response.Total += counts[i].Total
+			response.Pontos += counts[i].Pontos
+			if !minute.Before(windowStart) && minute.Before(windowEnd) {
+				momentum[p].TotalJanela += counts[i].Total
+				momentum[p].PontosJanela += counts[i].Pontos
+				response.TotalJanela += counts[i].Total
+				response.PontosJanela += counts[i].Pontos
+			}
+		}
+
+		// A liderança só muda quando outro participante passa à frente de todos; empate não troca
+		// o líder, e ninguém lidera enquanto os primeiros estiverem empatados desde o início
+		next, tied := -1, false
+		for p := range momentum {
+			switch {
+			case next == -1 || momentum[p].Pontos > momentum[next].Pontos:
+				next, tied = p, false
+			case momentum[p].Pontos == momentum[next].Pontos:
+				tied = true
+			}
+		}
+		if next != -1 && !tied && next != leader && momentum[next].Pontos > 0 {
+			leader = next
+			response.TrocasLideranca = append(response.TrocasLideranca, entities.LeadChange{
+				Momento:        minute,
+				ParticipanteID: momentum[leader].ParticipanteID,
+				Nome:           momentum[leader].Nome,
+				Total:          momentum[leader].Total,
+				Pontos:         momentum[leader].Pontos,
+			})
+		}
+	}
+
+	for i := range momentum {
+		momentum[i].Percentual = share(momentum[i].Pontos, response.Pontos)
+		momentum[i].PercentualJanela = share(momentum[i].PontosJanela, response.PontosJanela)
+		momentum[i].Tendencia = math.Round((momentum[i].PercentualJanela-momentum[i].Percentual)*100) / 100
+	}
+	response.Participantes = momentum
+
+	return response
+}
+
+// Cumulative transforma uma série de totais por intervalo em totais acumulados
+func Cumulative(series []entities.TimeSeriesPoint) []entities.TimeSeriesPoint {
+	cumulative := make([]entities.TimeSeriesPoint, len(series))
+	total, pontos := 0, 0
+	for i, point := range series {
+		total += point.Total
+		pontos += point.Pontos
+		cumulative[i] = entities.TimeSeriesPoint{Inicio: point.Inicio, Total: total, Pontos: pontos}
+	}
+	return cumulative
+}
+
+// share retorna a fatia em percentual, com duas casas decimais
+func share(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*10000/float64(whole)) / 100
+}
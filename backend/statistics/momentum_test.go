@@ -0,0 +1,125 @@
+package statistics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// minuteCounts monta os totais por minuto a partir de (minuto, participante, pontos), com
+// um voto por linha
+func minuteCounts(rows ...[3]int) []entities.MinuteCount {
+	counts := make([]entities.MinuteCount, len(rows))
+	for i, row := range rows {
+		counts[i] = entities.MinuteCount{
+			Minuto:         utc(10, 20, row[0]),
+			ParticipanteID: int64(row[1]),
+			Total:          1,
+			Pontos:         row[2],
+		}
+	}
+	return counts
+}
+
+func TestMomentumLeadChanges(t *testing.T) {
+	participantes := []*entities.Participante{{ID: 1, Nome: "A"}, {ID: 2, Nome: "B"}, {ID: 3, Nome: "C"}}
+
+	tests := []struct {
+		name   string
+		counts []entities.MinuteCount
+		// Minuto e participante de cada troca de liderança
+		want [][2]int
+	}{
+		{"no votes", nil, [][2]int{}},
+		{"single leader", minuteCounts([3]int{0, 1, 1}, [3]int{1, 1, 1}, [3]int{2, 2, 1}), [][2]int{{0, 1}}},
+		{
+			"overtake",
+			minuteCounts([3]int{0, 1, 2}, [3]int{1, 2, 1}, [3]int{2, 2, 2}),
+			[][2]int{{0, 1}, {2, 2}},
+		},
+		{
+			// Quem alcança o líder não o substitui
+			"tie keeps the leader",
+			minuteCounts([3]int{0, 1, 2}, [3]int{1, 2, 2}, [3]int{2, 1, 1}, [3]int{3, 2, 1}),
+			[][2]int{{0, 1}},
+		},
+		{
+			"tie at the start has no leader",
+			minuteCounts([3]int{0, 1, 1}, [3]int{0, 2, 1}, [3]int{1, 2, 1}),
+			[][2]int{{1, 2}},
+		},
+		{
+			// Os votos do mesmo minuto são somados antes de conferir a liderança
+			"votes of the same minute together",
+			minuteCounts([3]int{0, 1, 2}, [3]int{1, 2, 3}, [3]int{1, 1, 2}),
+			[][2]int{{0, 1}},
+		},
+		{
+			"weighted pontos decide",
+			minuteCounts([3]int{0, 1, 1}, [3]int{0, 1, 1}, [3]int{1, 3, 3}),
+			[][2]int{{0, 1}, {1, 3}},
+		},
+		{"unknown participante is ignored", minuteCounts([3]int{0, 9, 5}, [3]int{1, 2, 1}), [][2]int{{1, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := Momentum(tt.counts, participantes, utc(10, 20, 0), utc(10, 21, 0))
+			if len(response.TrocasLideranca) != len(tt.want) {
+				t.Fatalf("trocas de liderança = %+v, want %v", response.TrocasLideranca, tt.want)
+			}
+			for i, want := range tt.want {
+				got := response.TrocasLideranca[i]
+				if !got.Momento.Equal(utc(10, 20, want[0])) || got.ParticipanteID != int64(want[1]) {
+					t.Errorf("troca %d = %v participante %d, want minute %d participante %d",
+						i, got.Momento, got.ParticipanteID, want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestMomentumWindow(t *testing.T) {
+	participantes := []*entities.Participante{{ID: 1, Nome: "A"}, {ID: 2, Nome: "B"}}
+	// A tem três de quatro pontos no total, mas B tem todos os pontos dos últimos minutos
+	counts := minuteCounts([3]int{0, 1, 1}, [3]int{1, 1, 1}, [3]int{2, 1, 1}, [3]int{5, 2, 1})
+
+	response := Momentum(counts, participantes, utc(10, 20, 5), utc(10, 20, 10))
+	if response.Pontos != 4 || response.PontosJanela != 1 {
+		t.Fatalf("pontos = %d and %d in the window, want 4 and 1", response.Pontos, response.PontosJanela)
+	}
+
+	a, b := response.Participantes[0], response.Participantes[1]
+	if a.Percentual != 75 || a.PercentualJanela != 0 || a.Tendencia != -75 {
+		t.Errorf("A = %+v, want 75%% overall, 0%% in the window and a -75 tendencia", a)
+	}
+	if b.Percentual != 25 || b.PercentualJanela != 100 || b.Tendencia != 75 {
+		t.Errorf("B = %+v, want 25%% overall, 100%% in the window and a 75 tendencia", b)
+	}
+}
+
+func TestCumulative(t *testing.T) {
+	start := utc(10, 0, 0)
+	series := []entities.TimeSeriesPoint{
+		{Inicio: start, Total: 2, Pontos: 3},
+		{Inicio: start.Add(time.Hour), Total: 0, Pontos: 0},
+		{Inicio: start.Add(2 * time.Hour), Total: 1, Pontos: 5},
+	}
+
+	cumulative := Cumulative(series)
+	want := []entities.TimeSeriesPoint{
+		{Inicio: start, Total: 2, Pontos: 3},
+		{Inicio: start.Add(time.Hour), Total: 2, Pontos: 3},
+		{Inicio: start.Add(2 * time.Hour), Total: 3, Pontos: 8},
+	}
+	for i := range want {
+		if !cumulative[i].Inicio.Equal(want[i].Inicio) ||
+			cumulative[i].Total != want[i].Total || cumulative[i].Pontos != want[i].Pontos {
+			t.Errorf("cumulative[%d] = %+v, want %+v", i, cumulative[i], want[i])
+		}
+	}
+	if series[1].Total != 0 {
+		t.Error("Cumulative changed the original series")
+	}
+}
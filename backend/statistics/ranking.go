@@ -0,0 +1,125 @@
+package statistics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// RoundingStrategy define como os percentuais são arredondados para que somem 100%
+type RoundingStrategy string
+
+const (
+	// LargestRemainder trunca todos os percentuais e distribui as unidades que
+	// faltam para os participantes com os maiores restos (método de Hamilton)
+	LargestRemainder RoundingStrategy = "largest-remainder"
+	// Nearest arredonda cada percentual para o valor mais próximo e corrige a
+	// diferença uma unidade por vez, nos participantes mais distantes do valor exato
+	Nearest RoundingStrategy = "nearest"
+)
+
+// Número máximo de casas decimais aceito nos percentuais
+const MaxDecimals = 4
+
+// ParseRoundingStrategy converte o nome de uma estratégia, retornando false se for desconhecida
+func ParseRoundingStrategy(name string) (RoundingStrategy, bool) {
+	switch strategy := RoundingStrategy(name); strategy {
+	case LargestRemainder, Nearest:
+		return strategy, true
+	default:
+		return "", false
+	}
+}
+
+// Rank ordena os totais do participante com mais para o com menos pontos e preenche
+// percentual, posição, diferença para o líder e empate, todos calculados sobre os pontos
+// (que só diferem do número de votos em votações ponderadas). Participantes com os mesmos
+// pontos ocupam a mesma posição. Os percentuais somam exatamente 100% sempre que houver
+// ao menos um voto.
+func Rank(
+	totals []entities.ParticipanteTotalResponse,
+	strategy RoundingStrategy,
+	decimals int,
+) []entities.ParticipanteTotalResponse {
+	ranked := make([]entities.ParticipanteTotalResponse, len(totals))
+	copy(ranked, totals)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Pontos > ranked[j].Pontos
+	})
+
+	samePontos := make(map[int]int, len(ranked))
+	for _, t := range ranked {
+		samePontos[t.Pontos]++
+	}
+
+	for i := range ranked {
+		if i > 0 && ranked[i].Pontos == ranked[i-1].Pontos {
+			ranked[i].Posicao = ranked[i-1].Posicao
+		} else {
+			ranked[i].Posicao = i + 1
+		}
+		ranked[i].DiferencaLider = ranked[0].Pontos - ranked[i].Pontos
+		ranked[i].Empatado = samePontos[ranked[i].Pontos] > 1
+	}
+
+	fillPercentages(ranked, strategy, decimals)
+
+	return ranked
+}
+
+// fillPercentages calcula os percentuais em unidades inteiras (centésimos de ponto
+// percentual para duas casas, por exemplo) para que a soma seja exata
+func fillPercentages(ranked []entities.ParticipanteTotalResponse, strategy RoundingStrategy, decimals int) {
+	decimals = max(0, min(decimals, MaxDecimals))
+	scale := int64(math.Pow10(decimals))
+
+	var sum int64
+	for _, t := range ranked {
+		sum += int64(t.Pontos)
+	}
+	if sum == 0 {
+		return
+	}
+
+	target := 100 * scale
+	units := make([]int64, len(ranked))
+	// remainders guarda o quanto falta (ou, se negativo, sobra) em cada participante para
+	// o valor exato, em unidades de 1/sum
+	remainders := make([]int64, len(ranked))
+	var assigned int64
+	for i, t := range ranked {
+		numerator := int64(t.Pontos) * target
+		units[i] = numerator / sum
+		remainders[i] = numerator % sum
+
+		// Arredonda meio para cima
+		if strategy == Nearest && 2*remainders[i] >= sum {
+			units[i]++
+			remainders[i] -= sum
+		}
+		assigned += units[i]
+	}
+
+	// A diferença é distribuída uma unidade por participante: as que faltam vão para os
+	// maiores restos e as que sobram saem dos menores. Empatados têm o mesmo resto, então
+	// diferem em no máximo uma unidade, e quem perde uma unidade foi arredondado para cima
+	order := make([]int, len(ranked))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := int64(0); i < target-assigned; i++ {
+		units[order[i]]++
+	}
+	for i := int64(0); i < assigned-target; i++ {
+		units[order[int64(len(order))-1-i]]--
+	}
+
+	for i := range ranked {
+		ranked[i].Percentual = float64(units[i]) / float64(scale)
+	}
+}
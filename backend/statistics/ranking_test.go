@@ -0,0 +1,153 @@
+package statistics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// totalsWithPontos monta os totais de participantes com os pontos informados, na ordem
+func totalsWithPontos(pontos ...int) []entities.ParticipanteTotalResponse {
+	totals := make([]entities.ParticipanteTotalResponse, len(pontos))
+	for i, p := range pontos {
+		totals[i] = entities.ParticipanteTotalResponse{ParticipanteID: int64(i + 1), Total: p, Pontos: p}
+	}
+	return totals
+}
+
+// percentUnits converte os percentuais de volta para unidades inteiras da última casa decimal
+func percentUnits(ranked []entities.ParticipanteTotalResponse, decimals int) []int64 {
+	scale := math.Pow10(decimals)
+	units := make([]int64, len(ranked))
+	for i, t := range ranked {
+		units[i] = int64(math.Round(t.Percentual * scale))
+	}
+	return units
+}
+
+func TestRankPercentagesSumTo100(t *testing.T) {
+	tests := []struct {
+		name   string
+		pontos []int
+	}{
+		{"eight tied", []int{5, 5, 5, 5, 5, 5, 5, 5}},
+		{"three tied", []int{1, 1, 1}},
+		{"leader and tied", []int{2, 1, 1, 1, 1, 1, 1}},
+		{"large leader", []int{1000, 1, 1}},
+		{"tied with a zero", []int{5, 5, 0}},
+		{"many tied thirds", []int{7, 3, 3, 3}},
+		{"eleven tied", []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		{"single participante", []int{3}},
+	}
+
+	for _, strategy := range []RoundingStrategy{LargestRemainder, Nearest} {
+		for _, decimals := range []int{0, 1, 2} {
+			for _, tt := range tests {
+				t.Run(string(strategy)+"/"+tt.name, func(t *testing.T) {
+					ranked := Rank(totalsWithPontos(tt.pontos...), strategy, decimals)
+					units := percentUnits(ranked, decimals)
+
+					var sum int64
+					for i, u := range units {
+						sum += u
+						if u < 0 {
+							t.Errorf("participante %d has a negative percentual %v",
+								ranked[i].ParticipanteID, ranked[i].Percentual)
+						}
+					}
+					if want := int64(100 * math.Pow10(decimals)); sum != want {
+						t.Errorf("%d decimals: percentuais %v sum to %d units, want %d", decimals, units, sum, want)
+					}
+
+					// Empatados diferem em no máximo uma unidade, e quem tem mais pontos não tem menos
+					for i := 1; i < len(ranked); i++ {
+						if ranked[i].Pontos == ranked[i-1].Pontos && abs(units[i]-units[i-1]) > 1 {
+							t.Errorf("%d decimals: tied participantes got %v", decimals, units)
+						}
+						if ranked[i].Pontos < ranked[i-1].Pontos && units[i] > units[i-1] {
+							t.Errorf("%d decimals: fewer pontos got a larger percentual: %v", decimals, units)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func TestRankTiedPercentagesAreEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		pontos   []int
+		decimals int
+		want     []float64
+	}{
+		{"four tied", []int{9, 9, 9, 9}, 0, []float64{25, 25, 25, 25}},
+		{"eight tied, two decimals", []int{5, 5, 5, 5, 5, 5, 5, 5}, 2,
+			[]float64{12.5, 12.5, 12.5, 12.5, 12.5, 12.5, 12.5, 12.5}},
+		{"leader and two tied", []int{2, 1, 1}, 1, []float64{50, 25, 25}},
+	}
+
+	for _, strategy := range []RoundingStrategy{LargestRemainder, Nearest} {
+		for _, tt := range tests {
+			t.Run(string(strategy)+"/"+tt.name, func(t *testing.T) {
+				ranked := Rank(totalsWithPontos(tt.pontos...), strategy, tt.decimals)
+				for i, want := range tt.want {
+					if ranked[i].Percentual != want {
+						t.Errorf("percentual[%d] = %v, want %v", i, ranked[i].Percentual, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRankNearestSpreadsTheCorrection(t *testing.T) {
+	// Oito empatados arredondam para 13%: os quatro últimos perdem uma unidade, e o
+	// primeiro não absorve a diferença toda
+	ranked := Rank(totalsWithPontos(1, 1, 1, 1, 1, 1, 1, 1), Nearest, 0)
+	want := []int64{13, 13, 13, 13, 12, 12, 12, 12}
+	for i, u := range percentUnits(ranked, 0) {
+		if u != want[i] {
+			t.Fatalf("percentuais = %v, want %v", percentUnits(ranked, 0), want)
+		}
+	}
+}
+
+func TestRankPositions(t *testing.T) {
+	ranked := Rank(totalsWithPontos(3, 7, 3, 1), LargestRemainder, 2)
+
+	want := []struct {
+		id             int64
+		posicao        int
+		diferencaLider int
+		empatado       bool
+	}{
+		{2, 1, 0, false},
+		{1, 2, 4, true},
+		{3, 2, 4, true},
+		{4, 4, 6, false},
+	}
+	for i, w := range want {
+		got := ranked[i]
+		if got.ParticipanteID != w.id || got.Posicao != w.posicao ||
+			got.DiferencaLider != w.diferencaLider || got.Empatado != w.empatado {
+			t.Errorf("ranked[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestRankWithoutVotes(t *testing.T) {
+	for _, total := range Rank(totalsWithPontos(0, 0), Nearest, 2) {
+		if total.Percentual != 0 {
+			t.Errorf("percentual = %v without votes, want 0", total.Percentual)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package statistics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+// Granularity é o tamanho de cada intervalo de uma série temporal
+type Granularity string
+
+const (
+	Minute      Granularity = "minute"
+	FiveMinutes Granularity = "5m"
+	Hour        Granularity = "hour"
+	Day         Granularity = "day"
+)
+
+// Número máximo de intervalos de uma série, para limitar o tamanho da resposta
+const MaxBuckets = 10000
+
+// ErrTooManyBuckets indica que o período pedido gera intervalos demais para a granularidade
+var ErrTooManyBuckets = errors.New("too many buckets")
+
+// ParseGranularity converte o nome de uma granularidade, retornando false se for desconhecida
+func ParseGranularity(name string) (Granularity, bool) {
+	switch granularity := Granularity(name); granularity {
+	case Minute, FiveMinutes, Hour, Day:
+		return granularity, true
+	default:
+		return "", false
+	}
+}
+
+// BucketStart retorna o início do intervalo que contém t, alinhado ao fuso horário loc.
+// Horas e dias seguem o relógio local, inclusive em fusos com deslocamento fracionário.
+// Os intervalos de minutos e horas são recuados a partir de t, e não remontados pelo
+// relógio, para que a hora repetida no fim do horário de verão tenha o seu próprio intervalo.
+func BucketStart(t time.Time, granularity Granularity, loc *time.Location) time.Time {
+	t = t.In(loc)
+	elapsed := time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	switch granularity {
+	case Minute:
+		return t.Add(-elapsed)
+	case FiveMinutes:
+		return t.Add(-elapsed - time.Duration(t.Minute()%5)*time.Minute)
+	case Hour:
+		return t.Add(-elapsed - time.Duration(t.Minute())*time.Minute)
+	default:
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		if start.Day() != t.Day() {
+			// A meia-noite não existe quando o horário de verão começa nela: o dia começa na mudança
+			_, start = start.ZoneBounds()
+		}
+		return start
+	}
+}
+
+// NextBucket retorna o início do intervalo seguinte ao que começa em start
+func NextBucket(start time.Time, granularity Granularity, loc *time.Location) time.Time {
+	switch granularity {
+	case Minute:
+		return start.Add(time.Minute)
+	case FiveMinutes:
+		return start.Add(5 * time.Minute)
+	case Hour:
+		// Soma em tempo absoluto e realinha, para atravessar mudanças de horário de verão
+		return BucketStart(start.Add(time.Hour), granularity, loc)
+	default:
+		// O meio-dia existe em todo dia, mesmo quando a meia-noite não existe
+		start = start.In(loc)
+		return BucketStart(time.Date(start.Year(), start.Month(), start.Day()+1, 12, 0, 0, 0, loc), granularity, loc)
+	}
+}
+
+// BuildTimeSeries agrupa os totais por minuto em intervalos da granularidade pedida,
+// dentro de [from, to), preenchendo com zero os intervalos sem votos. A série total
+// e a de cada participante têm sempre os mesmos intervalos.
+func BuildTimeSeries(
+	counts []entities.MinuteCount,
+	participantes []*entities.Participante,
+	granularity Granularity,
+	loc *time.Location,
+	from, to time.Time,
+) (total []entities.TimeSeriesPoint, porParticipante []entities.ParticipanteTimeSeries, err error) {
+	starts := []time.Time{}
+	index := map[int64]int{}
+	for start := BucketStart(from, granularity, loc); start.Before(to); start = NextBucket(start, granularity, loc) {
+		if len(starts) == MaxBuckets {
+			return nil, nil, ErrTooManyBuckets
+		}
+		index[start.Unix()] = len(starts)
+		starts = append(starts, start)
+	}
+
+	total = newSeries(starts)
+	porParticipante = make([]entities.ParticipanteTimeSeries, len(participantes))
+	seriesByID := make(map[int64][]entities.TimeSeriesPoint, len(participantes))
+	for i, p := range participantes {
+		porParticipante[i] = entities.ParticipanteTimeSeries{
+			ParticipanteID: p.ID,
+			Nome:           p.Nome,
+			Serie:          newSeries(starts),
+		}
+		seriesByID[p.ID] = porParticipante[i].Serie
+	}
+
+	for _, c := range counts {
+		if c.Minuto.Before(from) || !c.Minuto.Before(to) {
+			continue
+		}
+
+		i, ok := index[BucketStart(c.Minuto, granularity, loc).Unix()]
+		if !ok {
+			continue
+		}
+
+		total[i].Total += c.Total
+		total[i].Pontos += c.Pontos
+		if series, ok := seriesByID[c.ParticipanteID]; ok {
+			series[i].Total += c.Total
+			series[i].Pontos += c.Pontos
+		}
+	}
+
+	return total, porParticipante, nil
+}
+
+func newSeries(starts []time.Time) []entities.TimeSeriesPoint {
+	series := make([]entities.TimeSeriesPoint, len(starts))
+	for i, start := range starts {
+		series[i].Inicio = start
+	}
+	return series
+}
@@ -0,0 +1,188 @@
+package statistics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+// utc monta um instante em UTC no dia informado
+func utc(day, hour, minute int) time.Time {
+	return time.Date(2026, time.March, day, hour, minute, 0, 0, time.UTC)
+}
+
+func seriesTotals(series []entities.TimeSeriesPoint) []int {
+	totals := make([]int, len(series))
+	for i, point := range series {
+		totals[i] = point.Total
+	}
+	return totals
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBuildTimeSeries(t *testing.T) {
+	participantes := []*entities.Participante{{ID: 1, Nome: "A"}, {ID: 2, Nome: "B"}}
+	counts := []entities.MinuteCount{
+		{ParticipanteID: 1, Minuto: utc(10, 9, 59), Total: 7, Pontos: 7},
+		{ParticipanteID: 1, Minuto: utc(10, 10, 0), Total: 1, Pontos: 1},
+		{ParticipanteID: 2, Minuto: utc(10, 10, 5), Total: 2, Pontos: 6},
+		{ParticipanteID: 2, Minuto: utc(10, 10, 29), Total: 3, Pontos: 3},
+		{ParticipanteID: 1, Minuto: utc(10, 12, 59), Total: 4, Pontos: 4},
+		{ParticipanteID: 2, Minuto: utc(10, 13, 0), Total: 9, Pontos: 9},
+	}
+
+	tests := []struct {
+		name        string
+		granularity Granularity
+		from, to    time.Time
+		total       []int
+		a, b        []int
+	}{
+		{"empty hours are zero", Hour, utc(10, 10, 0), utc(10, 13, 0),
+			[]int{6, 0, 4}, []int{1, 0, 4}, []int{5, 0, 0}},
+		{"from inside a bucket", Hour, utc(10, 10, 10), utc(10, 13, 0),
+			[]int{3, 0, 4}, []int{0, 0, 4}, []int{3, 0, 0}},
+		{"to is exclusive", Hour, utc(10, 12, 0), utc(10, 13, 0), []int{4}, []int{4}, []int{0}},
+		{"five minutes", FiveMinutes, utc(10, 10, 0), utc(10, 10, 15), []int{1, 2, 0}, []int{1, 0, 0}, []int{0, 2, 0}},
+		{"days", Day, utc(9, 0, 0), utc(12, 0, 0), []int{0, 26, 0}, []int{0, 12, 0}, []int{0, 14, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, porParticipante, err := BuildTimeSeries(
+				counts, participantes, tt.granularity, time.UTC, tt.from, tt.to,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := seriesTotals(total); !equalInts(got, tt.total) {
+				t.Errorf("total = %v, want %v", got, tt.total)
+			}
+			if got := seriesTotals(porParticipante[0].Serie); !equalInts(got, tt.a) {
+				t.Errorf("participante A = %v, want %v", got, tt.a)
+			}
+			if got := seriesTotals(porParticipante[1].Serie); !equalInts(got, tt.b) {
+				t.Errorf("participante B = %v, want %v", got, tt.b)
+			}
+			for i := range total {
+				if !porParticipante[0].Serie[i].Inicio.Equal(total[i].Inicio) {
+					t.Errorf("bucket %d starts at %v for A and %v for the total",
+						i, porParticipante[0].Serie[i].Inicio, total[i].Inicio)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildTimeSeriesAcrossDST(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	saoPaulo := mustLoadLocation(t, "America/Sao_Paulo")
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	at := func(loc *time.Location, s string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed.In(loc)
+	}
+
+	tests := []struct {
+		name        string
+		loc         *time.Location
+		granularity Granularity
+		from, to    string
+		votos       []string
+		starts      []string
+		totals      []int
+	}{
+		{
+			// 01:00 se repete: a primeira vez no horário de verão, a segunda fora dele
+			name: "repeated hour", loc: newYork, granularity: Hour,
+			from: "2025-11-02T00:00:00-04:00", to: "2025-11-02T03:00:00-05:00",
+			votos: []string{"2025-11-02T01:30:00-04:00", "2025-11-02T01:30:00-05:00", "2025-11-02T01:45:00-05:00"},
+			starts: []string{
+				"2025-11-02T00:00:00-04:00", "2025-11-02T01:00:00-04:00",
+				"2025-11-02T01:00:00-05:00", "2025-11-02T02:00:00-05:00",
+			},
+			totals: []int{0, 1, 2, 0},
+		},
+		{
+			name: "skipped hour", loc: newYork, granularity: Hour,
+			from: "2025-03-09T00:00:00-05:00", to: "2025-03-09T04:00:00-04:00",
+			votos:  []string{"2025-03-09T03:10:00-04:00"},
+			starts: []string{"2025-03-09T00:00:00-05:00", "2025-03-09T01:00:00-05:00", "2025-03-09T03:00:00-04:00"},
+			totals: []int{0, 0, 1},
+		},
+		{
+			// O horário de verão começava à meia-noite: o dia 4 começa à 01:00
+			name: "day without midnight", loc: saoPaulo, granularity: Day,
+			from: "2018-11-03T00:00:00-03:00", to: "2018-11-06T00:00:00-02:00",
+			votos:  []string{"2018-11-04T01:05:00-02:00", "2018-11-04T23:59:00-02:00", "2018-11-05T00:00:00-02:00"},
+			starts: []string{"2018-11-03T00:00:00-03:00", "2018-11-04T01:00:00-02:00", "2018-11-05T00:00:00-02:00"},
+			totals: []int{0, 2, 1},
+		},
+		{
+			name: "fractional offset", loc: kolkata, granularity: Hour,
+			from: "2026-03-10T10:00:00+05:30", to: "2026-03-10T12:00:00+05:30",
+			votos:  []string{"2026-03-10T10:59:00+05:30", "2026-03-10T11:00:00+05:30"},
+			starts: []string{"2026-03-10T10:00:00+05:30", "2026-03-10T11:00:00+05:30"},
+			totals: []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := []entities.MinuteCount{}
+			for _, voto := range tt.votos {
+				counts = append(counts, entities.MinuteCount{ParticipanteID: 1, Minuto: at(time.UTC, voto), Total: 1})
+			}
+			from, to := at(tt.loc, tt.from), at(tt.loc, tt.to)
+			total, _, err := BuildTimeSeries(counts, nil, tt.granularity, tt.loc, from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(total) != len(tt.starts) {
+				t.Fatalf("got %d buckets, want %d: %v", len(total), len(tt.starts), total)
+			}
+			for i, start := range tt.starts {
+				if want := at(tt.loc, start); !total[i].Inicio.Equal(want) {
+					t.Errorf("bucket %d starts at %v, want %v", i, total[i].Inicio, want)
+				}
+			}
+			if got := seriesTotals(total); !equalInts(got, tt.totals) {
+				t.Errorf("totals = %v, want %v", got, tt.totals)
+			}
+		})
+	}
+}
+
+func TestBuildTimeSeriesTooManyBuckets(t *testing.T) {
+	from := utc(1, 0, 0)
+	to := from.Add((MaxBuckets + 1) * time.Minute)
+	if _, _, err := BuildTimeSeries(nil, nil, Minute, time.UTC, from, to); !errors.Is(err, ErrTooManyBuckets) {
+		t.Errorf("got %v, want ErrTooManyBuckets", err)
+	}
+}
@@ -0,0 +1,109 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/danielfs/paredao/backend/repositories"
+	"github.com/danielfs/paredao/backend/statistics"
+)
+
+// Intervalo padrão entre as análises, configurável por FRAUD_ANALYSIS_INTERVAL
+const defaultFraudAnalysisInterval = 30 * time.Second
+
+// Nome da análise na tabela execucoes_job
+const fraudAnalyzerJob = "analise_fraude"
+
+// Janela padrão de votos analisada, configurável por FRAUD_WINDOW
+const defaultFraudWindow = 5 * time.Minute
+
+// Limites padrão, configuráveis por FRAUD_MAX_VOTES_PER_SOURCE_PER_MINUTE,
+// FRAUD_MIN_SOURCE_ENTROPY e FRAUD_MIN_VOTES
+const (
+	defaultFraudMaxRate    = 30
+	defaultFraudMinEntropy = 2.0
+	defaultFraudMinVotes   = 100
+)
+
+// StartFraudAnalyzer analisa periodicamente os votos recentes das votações abertas e
+// grava alertas para origens suspeitas, até que ctx seja cancelado
+func StartFraudAnalyzer(ctx context.Context) {
+	interval := durationFromEnv("FRAUD_ANALYSIS_INTERVAL", defaultFraudAnalysisInterval)
+	window := durationFromEnv("FRAUD_WINDOW", defaultFraudWindow)
+	thresholds := statistics.FraudThresholds{
+		MaxVotesPerSourcePerMinute: floatFromEnv("FRAUD_MAX_VOTES_PER_SOURCE_PER_MINUTE", defaultFraudMaxRate),
+		MinSourceEntropy:           floatFromEnv("FRAUD_MIN_SOURCE_ENTROPY", defaultFraudMinEntropy),
+		MinVotes:                   int(floatFromEnv("FRAUD_MIN_VOTES", defaultFraudMinVotes)),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Só uma réplica analisa a cada intervalo, para que cada alerta seja contado uma vez
+				claimed, err := repositories.ClaimJobRun(fraudAnalyzerJob, interval)
+				if err != nil {
+					log.Printf("Error claiming fraud analysis run: %v", err)
+					continue
+				}
+				if claimed {
+					analyzeOpenVotacoes(window, thresholds)
+				}
+			}
+		}
+	}()
+
+	log.Printf("Fraud analyzer started with interval %s and window %s", interval, window)
+}
+
+func analyzeOpenVotacoes(window time.Duration, thresholds statistics.FraudThresholds) {
+	ids, err := repositories.GetOpenVotacaoIDs()
+	if err != nil {
+		log.Printf("Error listing open votacoes: %v", err)
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-window)
+	for _, votacaoID := range ids {
+		counts, err := repositories.GetSourceCounts(votacaoID, from, to)
+		if err != nil {
+			log.Printf("Error getting source counts for votacao %d: %v", votacaoID, err)
+			continue
+		}
+
+		for _, alerta := range statistics.AnalyzeSources(counts, window, thresholds) {
+			alerta.VotacaoID = votacaoID
+			alerta.JanelaInicio = from
+			alerta.JanelaFim = to
+			if err := repositories.SaveAlerta(&alerta); err == nil {
+				log.Printf("Fraud alert %s on votacao %d, participante %d: %.2f (limit %.2f)",
+					alerta.Tipo, votacaoID, alerta.ParticipanteID, alerta.Valor, alerta.Limite)
+			}
+		}
+	}
+}
+
+func durationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func floatFromEnv(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,131 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// Intervalo padrão entre as execuções, configurável por RETENTION_INTERVAL
+const defaultRetentionInterval = time.Hour
+
+// Nome do job de retenção na tabela execucoes_job
+const retentionJob = "retencao"
+
+// Meses completos, depois do atual, que já têm partição de votos
+const votoPartitionsAhead = 2
+
+// StartRetentionJob cria periodicamente as partições mensais de votos dos próximos meses.
+// Com VOTOS_RETENTION_DAYS maior que zero, também arquiva os votos das votações encerradas
+// há mais desses dias e remove do banco os votos arquivados, com as partições que só têm
+// votos arquivados. Roda até que ctx seja cancelado.
+func StartRetentionJob(ctx context.Context) {
+	interval := durationFromEnv("RETENTION_INTERVAL", defaultRetentionInterval)
+	retention := time.Duration(floatFromEnv("VOTOS_RETENTION_DAYS", 0) * float64(24*time.Hour))
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// As partições do mês seguinte não podem esperar o primeiro intervalo
+		for {
+			runRetention(ctx, interval, retention)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	if retention > 0 {
+		log.Printf("Retention job started with interval %s, archiving votos after %s", interval, retention)
+	} else {
+		log.Printf("Retention job started with interval %s, archiving disabled", interval)
+	}
+}
+
+func runRetention(ctx context.Context, interval, retention time.Duration) {
+	// Só uma réplica roda a cada intervalo: o DDL das partições não pode rodar em paralelo
+	claimed, err := repositories.ClaimJobRun(retentionJob, interval)
+	if err != nil {
+		log.Printf("Error claiming retention run: %v", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	now := time.Now().UTC()
+	until := time.Date(now.Year(), now.Month()+1+votoPartitionsAhead, 1, 0, 0, 0, 0, time.UTC)
+	created, err := repositories.EnsureVotoPartitions(until)
+	for _, name := range created {
+		log.Printf("Created votos partition %s", name)
+	}
+	if err != nil {
+		log.Printf("Error creating votos partitions: %v", err)
+	}
+
+	if retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-retention)
+
+	archiveVotacoes(ctx, cutoff)
+	if ctx.Err() != nil {
+		return
+	}
+
+	dropped, err := repositories.DropArchivedVotoPartitions(cutoff)
+	for _, name := range dropped {
+		log.Printf("Dropped votos partition %s", name)
+	}
+	if err != nil {
+		log.Printf("Error dropping votos partitions: %v", err)
+	}
+
+	purged, err := repositories.PurgeArchivedVotos()
+	if err != nil {
+		log.Printf("Error purging archived votos: %v", err)
+	}
+	if purged > 0 {
+		log.Printf("Purged %d archived votos", purged)
+	}
+}
+
+// archiveVotacoes arquiva os votos das votações encerradas antes de cutoff
+func archiveVotacoes(ctx context.Context, cutoff time.Time) {
+	ids, err := repositories.GetArchivableVotacaoIDs(cutoff)
+	if err != nil {
+		log.Printf("Error listing votacoes to archive: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+
+		arquivo, err := repositories.ArchiveVotos(ctx, id)
+		switch {
+		case errors.Is(err, repositories.ErrVotosNaoAgregados), errors.Is(err, repositories.ErrVotacaoArquivada):
+			// Fica para a próxima execução, ou outra réplica já arquivou
+			continue
+		case err != nil:
+			log.Printf("Error archiving votos of votacao %d: %v", id, err)
+			continue
+		}
+		log.Printf("Archived %d votos of votacao %d to %s", arquivo.Votos, id, arquivo.Arquivo)
+
+		// A votação em cache ainda não informa o arquivamento
+		tenantCtx := repositories.WithTenant(ctx, &entities.Tenant{ID: arquivo.TenantID})
+		if err := repositories.InvalidateVotacaoCache(tenantCtx, id); err != nil {
+			log.Printf("Error invalidating cache of votacao %d: %v", id, err)
+		}
+	}
+}
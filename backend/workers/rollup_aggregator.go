@@ -0,0 +1,80 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/danielfs/paredao/backend/entities"
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+// Intervalo padrão entre as agregações, configurável por ROLLUP_INTERVAL
+const defaultRollupInterval = 1 * time.Second
+
+// Número máximo de recontagens esperando o agregador
+const recountQueueSize = 256
+
+// RecountFunc confere as agregações de uma votação cujos votos foram invalidados ou
+// restaurados e publica o novo resultado
+type RecountFunc func(ctx context.Context, tenantID, votacaoID int64, motivo string)
+
+type recountRequest struct {
+	tenantID, votacaoID int64
+	motivo              string
+}
+
+var recountQueue = make(chan recountRequest, recountQueueSize)
+
+// StartRollupAggregator agrega periodicamente os novos votos na tabela votos_por_minuto e,
+// entre as agregações, executa com recount as recontagens agendadas por EnqueueRecount,
+// até que ctx seja cancelado
+func StartRollupAggregator(ctx context.Context, recount RecountFunc) {
+	interval := durationFromEnv("ROLLUP_INTERVAL", defaultRollupInterval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				aggregatePending()
+			case request := <-recountQueue:
+				tenantCtx := repositories.WithTenant(ctx, &entities.Tenant{ID: request.tenantID})
+				recount(tenantCtx, request.tenantID, request.votacaoID, request.motivo)
+			}
+		}
+	}()
+
+	log.Printf("Rollup aggregator started with interval %s", interval)
+}
+
+// EnqueueRecount agenda a recontagem da votação no agregador, fora da requisição que mudou
+// os votos. A invalidação já ajusta as agregações na própria transação, então, com a fila
+// cheia, a recontagem é descartada e retorna false.
+func EnqueueRecount(tenantID, votacaoID int64, motivo string) bool {
+	select {
+	case recountQueue <- recountRequest{tenantID: tenantID, votacaoID: votacaoID, motivo: motivo}:
+		return true
+	default:
+		log.Printf("Recount queue is full, skipping recount of votacao %d", votacaoID)
+		return false
+	}
+}
+
+// aggregatePending processa lotes seguidos enquanto houver votos acumulados
+func aggregatePending() {
+	for {
+		processed, err := repositories.AggregateNewVotos()
+		if err != nil {
+			log.Printf("Error aggregating votos: %v", err)
+			return
+		}
+		if processed < repositories.RollupBatchSize {
+			return
+		}
+	}
+}
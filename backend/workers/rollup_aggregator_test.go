@@ -0,0 +1,45 @@
+package workers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielfs/paredao/backend/repositories"
+)
+
+func TestRecountsRunOnTheAggregator(t *testing.T) {
+	t.Setenv("ROLLUP_INTERVAL", "1h")
+
+	// Sem o agregador a fila enche, e as recontagens seguintes são descartadas
+	for i := range recountQueueSize {
+		if !EnqueueRecount(7, int64(i), "motivo") {
+			t.Fatalf("recount %d was not queued", i)
+		}
+	}
+	if EnqueueRecount(7, recountQueueSize, "motivo") {
+		t.Error("a recount was queued over the queue size")
+	}
+
+	ran := make(chan recountRequest, recountQueueSize)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartRollupAggregator(ctx, func(ctx context.Context, tenantID, votacaoID int64, motivo string) {
+		if tenant, ok := repositories.TenantFromContext(ctx); !ok || tenant.ID != tenantID {
+			t.Errorf("recount of votacao %d runs without tenant %d in the context", votacaoID, tenantID)
+		}
+		ran <- recountRequest{tenantID: tenantID, votacaoID: votacaoID, motivo: motivo}
+	})
+
+	// O agregador executa as recontagens na ordem em que foram agendadas
+	for i := range recountQueueSize {
+		select {
+		case request := <-ran:
+			if request.votacaoID != int64(i) || request.tenantID != 7 || request.motivo != "motivo" {
+				t.Fatalf("recount %d = %+v, want votacao %d of tenant 7", i, request, i)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("recount %d did not run", i)
+		}
+	}
+}
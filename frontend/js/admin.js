@@ -1,6 +1,28 @@
 // Base URL for API requests
 const API_BASE_URL = 'http://localhost:8080';
 
+// Tenant API key, required by the admin routes and kept in localStorage
+const API_KEY_STORAGE = 'paredaoApiKey';
+
+// fetch with the tenant API key; asks for the key when missing or rejected
+function adminFetch(url, options = {}) {
+  let apiKey = localStorage.getItem(API_KEY_STORAGE);
+  if (!apiKey) {
+    apiKey = prompt('Chave de API do programa:') || '';
+    localStorage.setItem(API_KEY_STORAGE, apiKey);
+  }
+
+  const headers = new Headers(options.headers || {});
+  headers.set('X-API-Key', apiKey);
+  return fetch(url, { ...options, headers }).then(response => {
+    if (response.status === 401) {
+      localStorage.removeItem(API_KEY_STORAGE);
+      throw new Error('Chave de API inválida; recarregue a página e informe a chave');
+    }
+    return response;
+  });
+}
+
 // DOM Elements
 let participantsTable;
 let votacoesTable;
@@ -56,6 +78,15 @@ function setupEventListeners() {
     saveVotacao();
   });
 
+  // Import form submission; each button says whether it is a dry run
+  const importForm = document.getElementById('import-form');
+  if (importForm) {
+    importForm.addEventListener('submit', (e) => {
+      e.preventDefault();
+      importDocument(e.submitter.dataset.dryRun === 'true');
+    });
+  }
+
   // Add participante to votacao form submission
   if (addParticipanteToVotacaoForm) {
     addParticipanteToVotacaoForm.addEventListener('submit', (e) => {
@@ -119,7 +150,7 @@ function showAlert(message, type = 'success') {
 // Load all participantes
 async function loadParticipantes() {
   try {
-    const response = await fetch(`${API_BASE_URL}/participantes`);
+    const response = await adminFetch(`${API_BASE_URL}/participantes`);
     if (!response.ok) {
       throw new Error('Failed to load participants');
     }
@@ -204,7 +235,7 @@ function openParticipanteModal(id = null) {
   
   if (id) {
     // Edit mode - load participante data
-    fetch(`${API_BASE_URL}/participantes/${id}`)
+    adminFetch(`${API_BASE_URL}/participantes/${id}`)
       .then(response => {
         if (!response.ok) throw new Error('Failed to load participante');
         return response.json();
@@ -212,6 +243,11 @@ function openParticipanteModal(id = null) {
       .then(participante => {
         document.getElementById('participante-nome').value = participante.nome;
         document.getElementById('participante-url-foto').value = participante.urlFoto;
+        document.getElementById('participante-apelido').value = participante.apelido || '';
+        document.getElementById('participante-cidade').value = participante.cidade || '';
+        document.getElementById('participante-data-nascimento').value = participante.dataNascimento || '';
+        document.getElementById('participante-bio').value = participante.bio || '';
+        document.getElementById('participante-cor').value = participante.cor || '#000000';
         document.getElementById('participante-modal-title').textContent = 'Editar Participante';
       })
       .catch(error => {
@@ -230,24 +266,32 @@ function openParticipanteModal(id = null) {
 function saveParticipante() {
   const nome = document.getElementById('participante-nome').value;
   const urlFoto = document.getElementById('participante-url-foto').value;
+  const foto = document.getElementById('participante-foto').files[0];
   
-  if (!nome || !urlFoto) {
-    showAlert('Nome e URL da foto são obrigatórios', 'danger');
+  if (!nome || (!urlFoto && !foto)) {
+    showAlert('Nome e URL ou arquivo da foto são obrigatórios', 'danger');
     return;
   }
   
+  // Empty optional fields are sent as null so PATCH clears them
+  const optional = id => document.getElementById(id).value.trim() || null;
   const participante = {
     nome: nome,
-    urlFoto: urlFoto
+    urlFoto: urlFoto,
+    apelido: optional('participante-apelido'),
+    cidade: optional('participante-cidade'),
+    dataNascimento: optional('participante-data-nascimento'),
+    bio: optional('participante-bio'),
+    cor: optional('participante-cor')
   };
   
   const url = currentParticipanteId 
     ? `${API_BASE_URL}/participantes/${currentParticipanteId}` 
     : `${API_BASE_URL}/participantes`;
   
-  const method = currentParticipanteId ? 'PUT' : 'POST';
+  const method = currentParticipanteId ? 'PATCH' : 'POST';
   
-  fetch(url, {
+  adminFetch(url, {
     method,
     headers: {
       'Content-Type': 'application/json'
@@ -255,9 +299,15 @@ function saveParticipante() {
     body: JSON.stringify(participante)
   })
     .then(response => {
+      if (response.status === 422) {
+        return response.json().then(body => {
+          throw new Error(body.erros.map(erro => erro.mensagem).join('; '));
+        });
+      }
       if (!response.ok) throw new Error('Failed to save participante');
       return response.json();
     })
+    .then(saved => foto ? uploadFoto(saved.id, foto) : saved)
     .then(() => {
       participanteModal.style.display = 'none';
       showAlert(currentParticipanteId ? 'Participante atualizado com sucesso' : 'Participante criado com sucesso');
@@ -265,7 +315,22 @@ function saveParticipante() {
     })
     .catch(error => {
       console.error('Error saving participante:', error);
-      showAlert('Failed to save participante', 'danger');
+      showAlert(error.message || 'Failed to save participante', 'danger');
+    });
+}
+
+// Upload participante photo; the API stores resized copies and updates urlFoto
+function uploadFoto(id, foto) {
+  const formData = new FormData();
+  formData.append('foto', foto);
+  
+  return adminFetch(`${API_BASE_URL}/participantes/${id}/foto`, {
+    method: 'POST',
+    body: formData
+  })
+    .then(response => {
+      if (!response.ok) throw new Error('Failed to upload foto');
+      return response.json();
     });
 }
 
@@ -275,7 +340,7 @@ function deleteParticipante(id) {
     return;
   }
   
-  fetch(`${API_BASE_URL}/participantes/${id}`, {
+  adminFetch(`${API_BASE_URL}/participantes/${id}`, {
     method: 'DELETE'
   })
     .then(response => {
@@ -292,7 +357,7 @@ function deleteParticipante(id) {
 // Load all votacoes
 async function loadVotacoes() {
   try {
-    const response = await fetch(`${API_BASE_URL}/votacoes`);
+    const response = await adminFetch(`${API_BASE_URL}/votacoes`);
     if (!response.ok) {
       throw new Error('Failed to load votacoes');
     }
@@ -361,13 +426,15 @@ function openVotacaoModal(id = null) {
   
   if (id) {
     // Edit mode - load votacao data
-    fetch(`${API_BASE_URL}/votacoes/${id}`)
+    adminFetch(`${API_BASE_URL}/votacoes/${id}`)
       .then(response => {
         if (!response.ok) throw new Error('Failed to load votacao');
         return response.json();
       })
       .then(votacao => {
         document.getElementById('votacao-descricao').value = votacao.descricao;
+        document.getElementById('votacao-modo').value = votacao.modo || 'unica';
+        document.getElementById('votacao-pesos').value = formatPesos(votacao.pesos);
         document.getElementById('votacao-modal-title').textContent = 'Editar Votação';
       })
       .catch(error => {
@@ -391,9 +458,19 @@ function saveVotacao() {
     return;
   }
   
+  const modo = document.getElementById('votacao-modo').value;
   const votacao = {
-    descricao: descricao
+    descricao: descricao,
+    modo: modo
   };
+
+  if (modo === 'ponderada') {
+    votacao.pesos = parsePesos(document.getElementById('votacao-pesos').value);
+    if (!votacao.pesos) {
+      showAlert('Informe os pesos no formato canal=peso, separados por vírgula', 'danger');
+      return;
+    }
+  }
   
   const url = currentVotacaoId 
     ? `${API_BASE_URL}/votacoes/${currentVotacaoId}` 
@@ -401,7 +478,7 @@ function saveVotacao() {
   
   const method = currentVotacaoId ? 'PUT' : 'POST';
   
-  fetch(url, {
+  adminFetch(url, {
     method,
     headers: {
       'Content-Type': 'application/json'
@@ -423,13 +500,37 @@ function saveVotacao() {
     });
 }
 
+// Parse channel weights typed as "canal=peso, canal=peso"; returns null if invalid
+function parsePesos(text) {
+  const pesos = {};
+  for (const entry of text.split(',')) {
+    if (!entry.trim()) {
+      continue;
+    }
+    const [canal, peso] = entry.split('=').map(part => part.trim());
+    const value = parseInt(peso, 10);
+    if (!canal || !Number.isInteger(value) || value < 1) {
+      return null;
+    }
+    pesos[canal] = value;
+  }
+  return Object.keys(pesos).length > 0 ? pesos : null;
+}
+
+// Format channel weights for editing
+function formatPesos(pesos) {
+  return Object.entries(pesos || {})
+    .map(([canal, peso]) => `${canal}=${peso}`)
+    .join(', ');
+}
+
 // Delete votacao
 function deleteVotacao(id) {
   if (!confirm('Tem certeza que deseja excluir esta votação?')) {
     return;
   }
   
-  fetch(`${API_BASE_URL}/votacoes/${id}`, {
+  adminFetch(`${API_BASE_URL}/votacoes/${id}`, {
     method: 'DELETE'
   })
     .then(response => {
@@ -448,7 +549,7 @@ function manageVotacaoParticipantes(votacaoId) {
   currentVotacaoId = votacaoId;
   
   // Load votacao details
-  fetch(`${API_BASE_URL}/votacoes/${votacaoId}`)
+  adminFetch(`${API_BASE_URL}/votacoes/${votacaoId}`)
     .then(response => {
       if (!response.ok) throw new Error('Failed to load votacao');
       return response.json();
@@ -457,7 +558,7 @@ function manageVotacaoParticipantes(votacaoId) {
       document.getElementById('votacao-participantes-title').textContent = `Participantes da Votação: ${votacao.descricao}`;
       
       // Load participantes for this votacao
-      return fetch(`${API_BASE_URL}/votacoes/${votacaoId}/participantes`);
+      return adminFetch(`${API_BASE_URL}/votacoes/${votacaoId}/participantes`);
     })
     .then(response => {
       if (!response.ok) throw new Error('Failed to load participantes');
@@ -515,7 +616,7 @@ function addParticipanteToVotacao() {
     participanteId: parseInt(participanteId)
   };
   
-  fetch(`${API_BASE_URL}/votacoes/${currentVotacaoId}/participantes`, {
+  adminFetch(`${API_BASE_URL}/votacoes/${currentVotacaoId}/participantes`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json'
@@ -531,7 +632,7 @@ function addParticipanteToVotacao() {
       document.getElementById('participante-id').value = '';
       
       // Refresh participantes list
-      return fetch(`${API_BASE_URL}/votacoes/${currentVotacaoId}/participantes`);
+      return adminFetch(`${API_BASE_URL}/votacoes/${currentVotacaoId}/participantes`);
     })
     .then(response => {
       if (!response.ok) throw new Error('Failed to load participantes');
@@ -554,3 +655,105 @@ window.openVotacaoModal = openVotacaoModal;
 window.editVotacao = openVotacaoModal;
 window.deleteVotacao = deleteVotacao;
 window.manageVotacaoParticipantes = manageVotacaoParticipantes;
+
+// Import participantes and votações from a CSV or JSON file
+function importDocument(dryRun) {
+  const file = document.getElementById('import-file').files[0];
+  if (!file) {
+    showAlert('Selecione um arquivo para importar', 'danger');
+    return;
+  }
+
+  const contentType = file.name.toLowerCase().endsWith('.json') ? 'application/json' : 'text/csv';
+
+  adminFetch(`${API_BASE_URL}/import?dryRun=${dryRun}`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': contentType
+    },
+    body: file
+  })
+    .then(response => {
+      if (!response.ok && response.status !== 422) throw new Error('Failed to import');
+      return response.json();
+    })
+    .then(result => {
+      renderImportResult(result);
+      if (result.erros) {
+        showAlert('O arquivo tem erros; nada foi importado', 'danger');
+        return;
+      }
+      if (!dryRun) {
+        showAlert('Importação concluída com sucesso');
+        loadParticipantes();
+        loadVotacoes();
+      }
+    })
+    .catch(error => {
+      console.error('Error importing:', error);
+      showAlert('Failed to import', 'danger');
+    });
+}
+
+// Build a table with one row per item. Cells are set through textContent, never as markup,
+// because names, keys and messages come from the imported file. An array cell becomes one
+// line per entry.
+function createTextTable(headers, rows) {
+  const table = document.createElement('table');
+  const head = table.createTHead().insertRow();
+  headers.forEach(header => {
+    const th = document.createElement('th');
+    th.textContent = header;
+    head.appendChild(th);
+  });
+
+  const body = table.createTBody();
+  rows.forEach(values => {
+    const row = body.insertRow();
+    values.forEach(value => {
+      const cell = row.insertCell();
+      const lines = Array.isArray(value) ? value : [value];
+      lines.forEach((line, i) => {
+        if (i > 0) {
+          cell.appendChild(document.createElement('br'));
+        }
+        cell.appendChild(document.createTextNode(line ?? ''));
+      });
+    });
+  });
+  return table;
+}
+
+// Render the import diff or its errors
+function renderImportResult(result) {
+  const container = document.getElementById('import-result');
+  container.replaceChildren();
+
+  if (result.erros) {
+    container.appendChild(createTextTable(
+      ['Linha', 'Campo', 'Erro'],
+      result.erros.map(erro => [erro.linha, erro.campo || '', erro.mensagem])
+    ));
+    return;
+  }
+
+  const summary = document.createElement('p');
+  summary.textContent = `${result.dryRun ? 'Simulação' : 'Importação'}: ${result.criados} criados, ` +
+    `${result.atualizados} atualizados, ${result.inalterados} inalterados`;
+  container.appendChild(summary);
+
+  container.appendChild(createTextTable(
+    ['Linha', 'Tipo', 'Registro', 'Ação', 'Alterações'],
+    result.alteracoes.map(alteracao => [
+      alteracao.linha,
+      alteracao.tipo,
+      alteracao.chave,
+      alteracao.acao,
+      [
+        ...Object.entries(alteracao.campos || {}).map(([campo, valor]) =>
+          `${campo}: ${JSON.stringify(valor.de)} → ${JSON.stringify(valor.para)}`),
+        ...(alteracao.participantesAdicionados || []).map(nome => `+ ${nome}`)
+      ]
+    ])
+  ));
+}
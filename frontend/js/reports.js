@@ -7,6 +7,7 @@ let reportContent;
 let totalVotesElement;
 let votesByParticipantElement;
 let votesByHourElement;
+let reportEvents;
 
 // Initialize the reports functionality
 document.addEventListener('DOMContentLoaded', () => {
@@ -45,8 +46,10 @@ function setupReportsEventListeners() {
     const votacaoId = reportVotacaoSelect.value;
     if (votacaoId) {
       loadReportsForVotacao(votacaoId);
+      listenForRecounts(votacaoId);
     } else {
       reportContent.style.display = 'none';
+      listenForRecounts(null);
     }
   });
 }
@@ -104,7 +107,7 @@ async function loadReportsForVotacao(votacaoId) {
     const [totalResponse, participantResponse, hourlyResponse] = await Promise.all([
       fetch(`${REPORTS_API_BASE_URL}/estatisticas/votacoes/${votacaoId}/total`),
       fetch(`${REPORTS_API_BASE_URL}/estatisticas/votacoes/${votacaoId}/participantes`),
-      fetch(`${REPORTS_API_BASE_URL}/estatisticas/votacoes/${votacaoId}/hourly`)
+      fetch(`${REPORTS_API_BASE_URL}/estatisticas/votacoes/${votacaoId}/serie?granularity=hour&tz=${encodeURIComponent(browserTimeZone())}`)
     ]);
     
     // Check responses
@@ -136,6 +139,27 @@ async function loadReportsForVotacao(votacaoId) {
   }
 }
 
+// Listen for recounts of the selected votacao, pushed by the server after votes are
+// invalidated or restored, and refresh the reports with the new numbers
+function listenForRecounts(votacaoId) {
+  if (reportEvents) {
+    reportEvents.close();
+    reportEvents = null;
+  }
+  if (!votacaoId) {
+    return;
+  }
+
+  reportEvents = new EventSource(`${REPORTS_API_BASE_URL}/estatisticas/votacoes/${votacaoId}/eventos`);
+  reportEvents.addEventListener('recontagem', event => {
+    const recontagem = JSON.parse(event.data);
+    console.log('Reports: Recount received', recontagem);
+
+    displayTotalVotes(recontagem);
+    displayVotesByParticipant(recontagem.participantes);
+  });
+}
+
 // Display total votes
 function displayTotalVotes(data) {
   console.log('Reports: Displaying total votes', data);
@@ -169,11 +193,8 @@ function displayVotesByParticipant(data) {
       <tbody>
   `;
   
-  // Calculate total votes for percentage calculation
-  const totalVotes = data.reduce((sum, item) => sum + item.total, 0);
-  
   data.forEach(item => {
-    const percentage = totalVotes > 0 ? ((item.total / totalVotes) * 100).toFixed(2) : '0.00';
+    const percentage = item.percentual.toFixed(2);
     
     html += `
       <tr>
@@ -192,11 +213,11 @@ function displayVotesByParticipant(data) {
   votesByParticipantElement.innerHTML = html;
 }
 
-// Display votes by hour
+// Display votes by hour, one row per hour of each day of the votacao
 function displayVotesByHour(data) {
   console.log('Reports: Displaying votes by hour', data);
   
-  if (data.length === 0) {
+  if (data.serie.length === 0) {
     votesByHourElement.innerHTML = '<p>Nenhum dado disponível</p>';
     return;
   }
@@ -212,8 +233,8 @@ function displayVotesByHour(data) {
       <tbody>
   `;
   
-  data.forEach(item => {
-    const hourLabel = formatHour(item.hour);
+  data.serie.forEach(item => {
+    const hourLabel = formatHour(item.inicio);
     
     html += `
       <tr>
@@ -231,7 +252,15 @@ function displayVotesByHour(data) {
   votesByHourElement.innerHTML = html;
 }
 
-// Format hour for display (24-hour format)
-function formatHour(hour) {
-  return `${hour.toString().padStart(2, '0')}:00`;
+// Format the start of an hour bucket for display (dd/mm HH:00)
+function formatHour(inicio) {
+  // The API returns the bucket start in the requested time zone, e.g. 2025-02-27T21:00:00-03:00
+  const [date, time] = inicio.split('T');
+  const [, month, day] = date.split('-');
+  return `${day}/${month} ${time.substring(0, 5)}`;
+}
+
+// IANA time zone of the browser, used to align the hours with the viewer's clock
+function browserTimeZone() {
+  return Intl.DateTimeFormat().resolvedOptions().timeZone || 'UTC';
 }
@@ -103,7 +103,7 @@ async function fetchVotacaoTotal(id) {
 
 // Fetch votes by participant for a votacao
 async function fetchVotacaoTotalByParticipante(id) {
-  const response = await fetch(`${API_BASE_URL}/estatisticas/votacoes/${id}/participantes`);
+  const response = await fetch(`${API_BASE_URL}/estatisticas/votacoes/${id}/participantes?casas=1`);
   if (!response.ok) {
     throw new Error('Failed to load votes by participant');
   }
@@ -155,11 +155,9 @@ function renderParticipants() {
   
   let html = '<div class="participants-grid">';
   
-  // Sort participants by vote count (descending)
-  participantesData.sort((a, b) => b.total - a.total);
-  
+  // The API returns participants already ranked, with percentages that add up to 100%
   participantesData.forEach((participante, index) => {
-    const percentage = totalVotes > 0 ? ((participante.total / totalVotes) * 100).toFixed(1) : 0;
+    const percentage = participante.percentual.toFixed(1);
     
     // Assign different colors based on position
     const colorClass = index === 0 ? 'participant-first' : 
@@ -1,6 +1,9 @@
 // Base URL for API requests
 const API_BASE_URL = 'http://localhost:8080';
 
+// Channel of this page; in weighted votacoes it defines how much each vote counts
+const VOTING_CHANNEL = 'anonimo';
+
 // DOM Elements
 let votacaoSelector;
 let participantsContainer;
@@ -198,18 +201,28 @@ async function submitVote() {
   
   const voteData = {
     participanteId: selectedParticipanteId,
-    votacaoId: currentVotacaoId
+    votacaoId: currentVotacaoId,
+    canal: VOTING_CHANNEL
   };
   
+  // Same key for every retry of this vote, so the API counts it only once
+  const idempotencyKey = newIdempotencyKey();
+  
   try {
     const response = await fetch(`${API_BASE_URL}/votos`, {
       method: 'POST',
       headers: {
-        'Content-Type': 'application/json'
+        'Content-Type': 'application/json',
+        'Idempotency-Key': idempotencyKey
       },
       body: JSON.stringify(voteData)
     });
     
+    if (response.status === 429) {
+      showAlert('Você já votou recentemente. Aguarde um pouco para votar de novo', 'danger');
+      return;
+    }
+    
     if (!response.ok) {
       throw new Error('Failed to submit vote');
     }
@@ -224,5 +237,13 @@ async function submitVote() {
   }
 }
 
+// Generate a unique key identifying one vote submission
+function newIdempotencyKey() {
+  if (window.crypto && crypto.randomUUID) {
+    return crypto.randomUUID();
+  }
+  return `${Date.now()}-${Math.random().toString(36).slice(2)}`;
+}
+
 // Make functions available globally
 window.selectParticipante = selectParticipante;